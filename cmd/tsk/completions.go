@@ -10,27 +10,69 @@ const bashCompletion = `_tsk() {
     COMPREPLY=()
     cur="${COMP_WORDS[COMP_CWORD]}"
     prev="${COMP_WORDS[COMP_CWORD-1]}"
-    commands="add list ls done rm edit clear export config version completion"
+    commands="add list ls done rm edit tag note block unblock recur start stop timesheet clear undo redo sync log history encrypt decrypt conflicts export project config version completion"
 
     case "$prev" in
         tsk)
-            COMPREPLY=( $(compgen -W "$commands" -- "$cur") )
+            COMPREPLY=( $(compgen -W "$commands --timeout --config --set" -- "$cur") )
             return
             ;;
-        done|rm|edit)
+        --config)
+            COMPREPLY=( $(compgen -f -- "$cur") )
+            return
+            ;;
+        done|rm|edit|tag|note|block|unblock|start|history)
             local ids
             ids=$(tsk list 2>/dev/null | awk '{print $1}')
             COMPREPLY=( $(compgen -W "$ids" -- "$cur") )
             return
             ;;
-        list|ls|export)
-            COMPREPLY=( $(compgen -W "--done --pending" -- "$cur") )
+        list|ls)
+            COMPREPLY=( $(compgen -W "--done --pending --overdue --blocked --ready --due-before -P" -- "$cur") )
+            return
+            ;;
+        export)
+            COMPREPLY=( $(compgen -W "--done --pending -P" -- "$cur") )
             return
             ;;
         -p)
             COMPREPLY=( $(compgen -W "h m l high medium low" -- "$cur") )
             return
             ;;
+        project)
+            COMPREPLY=( $(compgen -W "list rename archive unarchive" -- "$cur") )
+            return
+            ;;
+        config)
+            COMPREPLY=( $(compgen -W "get set unset edit init check" -- "$cur") )
+            return
+            ;;
+        get|set|unset)
+            if [[ "${COMP_WORDS[1]}" == config ]]; then
+                COMPREPLY=( $(compgen -W "color.enabled storage.type storage.path storage.gist_token storage.gist_id storage.gist_timeout storage.git_dir storage.git_remote storage.events_path storage.indexed_path storage.passphrase_cmd" -- "$cur") )
+            fi
+            return
+            ;;
+        recur)
+            COMPREPLY=( $(compgen -W "list stop" -- "$cur") )
+            return
+            ;;
+        undo)
+            COMPREPLY=( $(compgen -W "--list" -- "$cur") )
+            return
+            ;;
+        sync)
+            COMPREPLY=( $(compgen -W "--status" -- "$cur") )
+            return
+            ;;
+        log)
+            COMPREPLY=( $(compgen -W "-n --all" -- "$cur") )
+            return
+            ;;
+        conflicts)
+            COMPREPLY=( $(compgen -W "list resolve" -- "$cur") )
+            return
+            ;;
         completion)
             COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") )
             return
@@ -41,11 +83,19 @@ const bashCompletion = `_tsk() {
         case "${COMP_WORDS[1]}" in
             add)
                 if [[ "$cur" == -* ]]; then
-                    COMPREPLY=( $(compgen -W "-p" -- "$cur") )
+                    COMPREPLY=( $(compgen -W "-p -P --due --every --parent" -- "$cur") )
                 fi
                 ;;
-            list|ls|export)
-                COMPREPLY=( $(compgen -W "--done --pending" -- "$cur") )
+            list|ls)
+                COMPREPLY=( $(compgen -W "--done --pending --overdue --blocked --ready --due-before -P" -- "$cur") )
+                ;;
+            export)
+                COMPREPLY=( $(compgen -W "--done --pending -P" -- "$cur") )
+                ;;
+            conflicts)
+                if [[ "$COMP_CWORD" -ge 4 ]]; then
+                    COMPREPLY=( $(compgen -W "--ours --theirs" -- "$cur") )
+                fi
                 ;;
         esac
     fi
@@ -58,27 +108,60 @@ const zshCompletion = `#compdef tsk
 
 _tsk() {
     local -a commands
-    commands=(add list ls done rm edit clear export config version completion)
+    commands=(add list ls done rm edit tag note block unblock recur start stop timesheet clear undo redo sync log history encrypt decrypt conflicts export project config version completion)
 
     if (( CURRENT == 2 )); then
         compadd -a commands
+        compadd -- --timeout --config --set
         return
     fi
 
     case "$words[2]" in
-        done|rm|edit)
+        done|rm|edit|tag|note|block|unblock|start|history)
             local -a ids
             ids=(${(f)"$(tsk list 2>/dev/null | awk '{print $1}')"})
             compadd -a ids
             ;;
-        list|ls|export)
-            compadd -- --done --pending
+        list|ls)
+            compadd -- --done --pending --overdue --blocked --ready --due-before -P
+            ;;
+        export)
+            compadd -- --done --pending -P
             ;;
         add)
             if [[ "$words[CURRENT-1]" == "-p" ]]; then
                 compadd -- h m l high medium low
             elif [[ "$words[CURRENT]" == -* ]]; then
-                compadd -- -p
+                compadd -- -p -P --due --every --parent
+            fi
+            ;;
+        project)
+            compadd -- list rename archive unarchive
+            ;;
+        config)
+            if (( CURRENT == 3 )); then
+                compadd -- get set unset edit init check
+            elif (( CURRENT == 4 )) && [[ "$words[3]" == (get|set|unset) ]]; then
+                compadd -- color.enabled storage.type storage.path storage.gist_token storage.gist_id storage.gist_timeout storage.git_dir storage.git_remote storage.events_path storage.indexed_path storage.passphrase_cmd
+            fi
+            ;;
+        recur)
+            compadd -- list stop
+            ;;
+        undo)
+            compadd -- --list
+            ;;
+        sync)
+            compadd -- --status
+            ;;
+        log)
+            compadd -- -n --all
+            ;;
+        conflicts)
+            if (( CURRENT == 3 )); then
+                compadd -- list resolve
+            elif (( CURRENT >= 5 )); then
+                compadd -- --ours --theirs
             fi
             ;;
         completion)
@@ -91,10 +174,23 @@ _tsk "$@"
 `
 
 const fishCompletion = `complete -c tsk -e
-complete -c tsk -n __fish_use_subcommand -a "add list ls done rm edit clear export config version completion" -f
-complete -c tsk -n "__fish_seen_subcommand_from done rm edit" -a "(tsk list 2>/dev/null | string match -r '^\s*\\d+' | string trim)" -f
-complete -c tsk -n "__fish_seen_subcommand_from list ls export" -a "--done --pending" -f
-complete -c tsk -n "__fish_seen_subcommand_from add" -a "-p" -f
+complete -c tsk -n __fish_use_subcommand -l timeout -r -d "give up after this long"
+complete -c tsk -n __fish_use_subcommand -l config -r -F -d "use this config file"
+complete -c tsk -n __fish_use_subcommand -l set -r -f -d "override a setting (key=value)"
+complete -c tsk -n __fish_use_subcommand -a "add list ls done rm edit tag note block unblock recur start stop timesheet clear undo redo sync log history encrypt decrypt conflicts export project config version completion" -f
+complete -c tsk -n "__fish_seen_subcommand_from done rm edit tag note block unblock start history" -a "(tsk list 2>/dev/null | string match -r '^\s*\\d+' | string trim)" -f
+complete -c tsk -n "__fish_seen_subcommand_from list ls" -a "--done --pending --overdue --blocked --ready --due-before -P" -f
+complete -c tsk -n "__fish_seen_subcommand_from export" -a "--done --pending -P" -f
+complete -c tsk -n "__fish_seen_subcommand_from add" -a "-p -P --due --every --parent" -f
+complete -c tsk -n "__fish_seen_subcommand_from project" -a "list rename archive unarchive" -f
+complete -c tsk -n "__fish_seen_subcommand_from config; and not __fish_seen_subcommand_from get set unset edit init check" -a "get set unset edit init check" -f
+complete -c tsk -n "__fish_seen_subcommand_from config; and __fish_seen_subcommand_from get set unset" -a "color.enabled storage.type storage.path storage.gist_token storage.gist_id storage.gist_timeout storage.git_dir storage.git_remote storage.events_path storage.indexed_path storage.passphrase_cmd" -f
+complete -c tsk -n "__fish_seen_subcommand_from recur" -a "list stop" -f
+complete -c tsk -n "__fish_seen_subcommand_from undo" -a "--list" -f
+complete -c tsk -n "__fish_seen_subcommand_from sync" -a "--status" -f
+complete -c tsk -n "__fish_seen_subcommand_from log" -a "-n --all" -f
+complete -c tsk -n "__fish_seen_subcommand_from conflicts; and not __fish_seen_subcommand_from list resolve" -a "list resolve" -f
+complete -c tsk -n "__fish_seen_subcommand_from resolve" -a "--ours --theirs" -f
 complete -c tsk -n "__fish_seen_subcommand_from completion" -a "bash zsh fish" -f
 `
 
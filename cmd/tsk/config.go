@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zarldev/tsk/internal/config"
+)
+
+// cmdConfig runs before the config is loaded, so that a config file
+// with mistakes in it can still be fixed. Printing the config shows
+// the values tsk runs with, overrides and all, and where each came
+// from; the other commands only touch the file.
+func cmdConfig(path string, set []string) {
+	if len(os.Args) < 3 {
+		cfg, err := loadConfig(path, set)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Print(cfg.Explain())
+		return
+	}
+
+	switch os.Args[2] {
+	case "get":
+		cmdConfigGet(path, set)
+	case "set":
+		cmdConfigSet(path)
+	case "unset":
+		cmdConfigUnset(path)
+	case "edit":
+		cmdConfigEdit(path)
+	case "init":
+		cmdConfigInit(path)
+	case "check":
+		cmdConfigCheck(path)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config command: %s\n", os.Args[2])
+		os.Exit(1)
+	}
+}
+
+func cmdConfigGet(path string, set []string) {
+	if len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk config get <key>")
+		os.Exit(1)
+	}
+	cfg, err := loadConfig(path, set)
+	if err != nil {
+		fatal(err)
+	}
+	v, err := cfg.Get(os.Args[3])
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(v)
+}
+
+func cmdConfigSet(path string) {
+	if len(os.Args) != 5 {
+		fmt.Fprintln(os.Stderr, "usage: tsk config set <key> <value>")
+		os.Exit(1)
+	}
+	key, value := os.Args[3], os.Args[4]
+	if err := config.Set(path, key, value); err != nil {
+		fatal(err)
+	}
+	v, err := config.EncodeValue(key, value)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("%s = %s\n", key, v)
+}
+
+func cmdConfigUnset(path string) {
+	if len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk config unset <key>")
+		os.Exit(1)
+	}
+	key := os.Args[3]
+	ok, err := config.Unset(path, key)
+	if err != nil {
+		fatal(err)
+	}
+	if !ok {
+		fmt.Printf("%s is not set\n", key)
+		return
+	}
+	fmt.Printf("unset %s\n", key)
+}
+
+// cmdConfigEdit opens the config file in the editor, and saves it only
+// once it is valid. A missing file starts from the template.
+func cmdConfigEdit(path string) {
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		data = []byte(config.Template)
+	case err != nil:
+		fatal(fmt.Errorf("read config: %w", err))
+	}
+	orig := strings.TrimRight(string(data), "\n")
+
+	text := orig
+	for {
+		text, err = editText(text, "tsk-config-*.toml")
+		if err != nil {
+			fatal(err)
+		}
+		text = strings.TrimRight(text, "\n")
+		if text == orig {
+			fmt.Println("config unchanged")
+			return
+		}
+		err = config.Check([]byte(text + "\n"))
+		if err == nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		if !confirm("edit again?") {
+			fmt.Fprintln(os.Stderr, "config not saved")
+			os.Exit(1)
+		}
+	}
+
+	if err := config.Save(path, []byte(text+"\n")); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("saved %s\n", path)
+}
+
+func cmdConfigInit(path string) {
+	if err := config.Init(path); err != nil {
+		fatal(err)
+	}
+	fmt.Printf("wrote %s\n", path)
+}
+
+func cmdConfigCheck(path string) {
+	problems, err := config.CheckFile(path)
+	if err != nil {
+		fatal(err)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s: no problems\n", path)
+		return
+	}
+	for _, p := range problems {
+		fmt.Printf("%s: %v\n", path, p)
+	}
+	fmt.Fprintf(os.Stderr, "%d %s\n", len(problems), pluralize(len(problems), "problem", "problems"))
+	os.Exit(1)
+}
+
+// loadConfig reads the config file at path, then applies the TSK_*
+// environment variables and the key=value settings given with --set.
+func loadConfig(path string, set []string) (config.Config, error) {
+	cfg, err := config.LoadFrom(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		return cfg, err
+	}
+	for _, kv := range set {
+		key, value, _ := strings.Cut(kv, "=")
+		if err := cfg.Override(key, value, config.SourceFlag); err != nil {
+			return cfg, fmt.Errorf("--set %s: %w", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// checkConfig reports the problems in the config file on stderr, and
+// exits unless all of them are ones tsk can run with.
+func checkConfig(path string) {
+	problems, err := config.CheckFile(path)
+	if err != nil {
+		fatal(err)
+	}
+	failed := false
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, p)
+		failed = failed || !p.Warning
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// confirm asks a yes/no question on stderr; the default is yes.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [Y/n] ", question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "" || answer == "y" || answer == "yes"
+}
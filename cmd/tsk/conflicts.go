@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdConflicts(ctx context.Context, store task.Store, cipher *task.Cipher, path string, c color.Palette) {
+	if len(os.Args) < 3 || os.Args[2] == "list" || os.Args[2] == "ls" {
+		cmdConflictsList(path, cipher, c)
+		return
+	}
+
+	if os.Args[2] != "resolve" || len(os.Args) < 5 {
+		fmt.Fprintln(os.Stderr, "usage: tsk conflicts [resolve <id> --ours|--theirs]")
+		os.Exit(1)
+	}
+
+	id, err := strconv.Atoi(os.Args[3])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid id: %s\n", os.Args[3])
+		os.Exit(1)
+	}
+
+	var useOurs bool
+	switch os.Args[4] {
+	case "--ours":
+		useOurs = true
+	case "--theirs":
+	default:
+		fmt.Fprintln(os.Stderr, "usage: tsk conflicts resolve <id> --ours|--theirs")
+		os.Exit(1)
+	}
+
+	conflicts, err := task.LoadConflicts(path)
+	if err != nil {
+		fatal(err)
+	}
+	i := slices.IndexFunc(conflicts, func(cf task.Conflict) bool { return cf.ID == id })
+	if i < 0 {
+		fmt.Fprintf(os.Stderr, "task %d: no conflict\n", id)
+		os.Exit(1)
+	}
+	// decrypt just this one; the rest are saved back as recorded
+	opened, err := openConflicts(cipher, conflicts[i:i+1])
+	if err != nil {
+		fatal(err)
+	}
+
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		return task.Resolve(tasks, opened[0], useOurs), nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	if err := task.SaveConflicts(path, slices.Delete(conflicts, i, i+1)); err != nil {
+		fatal(err)
+	}
+
+	side := "theirs"
+	if useOurs {
+		side = "ours"
+	}
+	fmt.Printf("task %s resolved with %s\n", c.BoldCyan(strconv.Itoa(id)), side)
+}
+
+func cmdConflictsList(path string, cipher *task.Cipher, c color.Palette) {
+	conflicts, err := task.LoadConflicts(path)
+	if err != nil {
+		fatal(err)
+	}
+	if conflicts, err = openConflicts(cipher, conflicts); err != nil {
+		fatal(err)
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("no conflicts")
+		return
+	}
+
+	for i, cf := range conflicts {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		var what string
+		switch {
+		case cf.Ours == nil:
+			what = "removed here, edited on another machine"
+		case cf.Theirs == nil:
+			what = "edited here, removed on another machine"
+		default:
+			what = "edited on both machines"
+		}
+		fmt.Printf("%s  %s  %s\n", c.BoldCyan(fmt.Sprintf("%3d", cf.ID)), cf.Title(), c.Yellow(what))
+
+		if cf.Ours == nil || cf.Theirs == nil {
+			continue
+		}
+		for _, d := range cf.Fields() {
+			fmt.Printf("     %s  %s %s\n", c.Dim(fmt.Sprintf("%-12s", d.Field)), c.Dim("ours:  "), fieldValue(d.Ours))
+			fmt.Printf("     %s  %s %s\n", fmt.Sprintf("%-12s", ""), c.Dim("theirs:"), fieldValue(d.Theirs))
+		}
+	}
+	fmt.Println()
+	fmt.Println(c.Dim("resolve with: tsk conflicts resolve <id> --ours|--theirs"))
+}
+
+// fieldValue formats a raw JSON field value for display.
+func fieldValue(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
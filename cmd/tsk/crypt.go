@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/config"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+// newCipher returns a Cipher for the passphrase in TSK_PASSPHRASE or
+// printed by passphrase_cmd, or nil if neither is set. The command is
+// only run once a task has to be encrypted or decrypted.
+func newCipher(cfg config.Config) *task.Cipher {
+	if p := os.Getenv("TSK_PASSPHRASE"); p != "" {
+		return task.NewCipher(func() (string, error) { return p, nil })
+	}
+	if cfg.Storage.PassphraseCmd == "" {
+		return nil
+	}
+	return task.NewCipher(func() (string, error) {
+		return runPassphraseCmd(cfg.Storage.PassphraseCmd)
+	})
+}
+
+// runPassphraseCmd runs command and returns the first line it prints,
+// so password managers that print extra lines after it work as is.
+func runPassphraseCmd(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("passphrase_cmd is blank")
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("run passphrase_cmd %s: %w", args[0], err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+func cmdEncrypt(ctx context.Context, store task.Store, cipher *task.Cipher, journal *task.Journal, storage string, c color.Palette) {
+	if cipher == nil {
+		fmt.Fprintln(os.Stderr, "encrypt requires a passphrase: set TSK_PASSPHRASE or storage.passphrase_cmd in config")
+		os.Exit(1)
+	}
+
+	var n int
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		tasks, err := cipher.OpenTasks(tasks)
+		if err != nil {
+			return nil, err
+		}
+		n = len(tasks)
+		return cipher.SealTasks(tasks)
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if err := scrub(store, journal, cipher); err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("encrypted %s %s\n", c.Bold(strconv.Itoa(n)), pluralize(n, "task", "tasks"))
+	switch storage {
+	case "gist":
+		fmt.Println(c.Dim("earlier revisions of the gist still hold the unencrypted tasks"))
+	case "git":
+		fmt.Println(c.Dim("earlier commits still hold the unencrypted tasks"))
+	}
+}
+
+func cmdDecrypt(ctx context.Context, store task.Store, cipher *task.Cipher, journal *task.Journal, c color.Palette) {
+	if cipher == nil {
+		fmt.Fprintln(os.Stderr, "decrypt requires the passphrase: set TSK_PASSPHRASE or storage.passphrase_cmd in config")
+		os.Exit(1)
+	}
+
+	var n int
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		n = len(tasks)
+		return cipher.OpenTasks(tasks)
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if err := scrub(store, journal, nil); err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("decrypted %s %s\n", c.Bold(strconv.Itoa(n)), pluralize(n, "task", "tasks"))
+	fmt.Println(c.Dim("unset TSK_PASSPHRASE and passphrase_cmd, or the next change encrypts them again"))
+}
+
+// scrub rewrites the copies of the tasks kept besides the current ones
+// — the undo journal, and superseded records or events in the store —
+// so they match the new encryption.
+func scrub(store task.Store, journal *task.Journal, cipher *task.Cipher) error {
+	if err := journal.SetCipher(cipher); err != nil {
+		return err
+	}
+	if s, ok := store.(interface{ Compact() error }); ok {
+		return s.Compact()
+	}
+	return nil
+}
+
+// openConflicts decrypts the tasks in conflicts, which are recorded as
+// the store saw them.
+func openConflicts(cipher *task.Cipher, conflicts []task.Conflict) ([]task.Conflict, error) {
+	out := slices.Clone(conflicts)
+	for i := range out {
+		for _, t := range []**task.Task{&out[i].Base, &out[i].Ours, &out[i].Theirs} {
+			if err := openTask(cipher, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// openChanges decrypts the tasks and tags in changes.
+func openChanges(cipher *task.Cipher, changes []task.Change) error {
+	for i := range changes {
+		ch := &changes[i]
+		if err := openTask(cipher, &ch.Before); err != nil {
+			return err
+		}
+		if err := openTask(cipher, &ch.After); err != nil {
+			return err
+		}
+		for j, tag := range ch.Tags {
+			text, err := cipher.Open(tag)
+			if err != nil {
+				return err
+			}
+			ch.Tags[j] = text
+		}
+	}
+	return nil
+}
+
+// openTask replaces *t with a decrypted copy, unless it is nil.
+func openTask(cipher *task.Cipher, t **task.Task) error {
+	if *t == nil {
+		return nil
+	}
+	opened, err := cipher.OpenTasks([]task.Task{**t})
+	if err != nil {
+		return err
+	}
+	*t = &opened[0]
+	return nil
+}
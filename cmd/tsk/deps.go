@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdBlock(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 5 || os.Args[3] != "--on" {
+		fmt.Fprintln(os.Stderr, "usage: tsk block <id> --on <id>[,<id>,...]")
+		os.Exit(1)
+	}
+
+	id, err := parseID(ctx, store, os.Args[2])
+	if err != nil {
+		fatal(err)
+	}
+
+	ons, err := parseIDs(ctx, store, os.Args[4])
+	if err != nil {
+		fatal(err)
+	}
+
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		for _, on := range ons {
+			if err := task.Block(tasks, id, on); err != nil {
+				return nil, err
+			}
+		}
+		return tasks, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("task %s now waits on %s\n", c.BoldCyan(strconv.Itoa(id)), c.BoldCyan(joinIDs(ons)))
+}
+
+func cmdUnblock(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: tsk unblock <id> [--on <id>]")
+		os.Exit(1)
+	}
+
+	id, err := parseID(ctx, store, os.Args[2])
+	if err != nil {
+		fatal(err)
+	}
+
+	on := 0
+	if len(os.Args) > 3 {
+		if os.Args[3] != "--on" || len(os.Args) < 5 {
+			fmt.Fprintln(os.Stderr, "usage: tsk unblock <id> [--on <id>]")
+			os.Exit(1)
+		}
+		on, err = parseID(ctx, store, os.Args[4])
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		return tasks, task.Unblock(tasks, id, on)
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	if on == 0 {
+		fmt.Printf("task %s no longer waits on anything\n", c.BoldCyan(strconv.Itoa(id)))
+		return
+	}
+	fmt.Printf("task %s no longer waits on %s\n", c.BoldCyan(strconv.Itoa(id)), c.BoldCyan(strconv.Itoa(on)))
+}
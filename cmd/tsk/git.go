@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+const logSize = 20
+
+func cmdLog(ctx context.Context, repo *task.GitStore, c color.Palette) {
+	if repo == nil {
+		fmt.Fprintln(os.Stderr, "log requires git storage (set storage.type in config)")
+		os.Exit(1)
+	}
+
+	n := logSize
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-n":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk log [-n <count>|--all]")
+				os.Exit(1)
+			}
+			i++
+			v, err := strconv.Atoi(args[i])
+			if err != nil || v < 1 {
+				fmt.Fprintf(os.Stderr, "invalid count: %s\n", args[i])
+				os.Exit(1)
+			}
+			n = v
+		case "--all", "-a":
+			n = 0
+		default:
+			fmt.Fprintln(os.Stderr, "usage: tsk log [-n <count>|--all]")
+			os.Exit(1)
+		}
+	}
+
+	commits, err := repo.Log(ctx, n)
+	if err != nil {
+		fatal(err)
+	}
+	if len(commits) == 0 {
+		fmt.Println("no history yet")
+		return
+	}
+
+	for _, cm := range commits {
+		when := cm.Time.Format("2006-01-02 15:04")
+		fmt.Printf("%s  %s  %s\n", c.Cyan(cm.Hash), c.Dim(when), cm.Subject)
+	}
+}
+
+func cmdGitStatus(ctx context.Context, repo *task.GitStore, c color.Palette) {
+	st, err := repo.Status(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%s  %s %s\n", c.Dim("origin:  "), st.Remote, c.Dim("("+st.Branch+")"))
+
+	if st.Ahead > 0 {
+		fmt.Printf("%s  %s %s not pushed\n", c.Dim("local:   "),
+			c.Yellow(strconv.Itoa(st.Ahead)), pluralize(st.Ahead, "commit", "commits"))
+	} else {
+		fmt.Printf("%s  %s\n", c.Dim("local:   "), "up to date")
+	}
+
+	if st.Behind > 0 {
+		fmt.Printf("%s  %s %s not pulled\n", c.Dim("remote:  "),
+			c.Yellow(strconv.Itoa(st.Behind)), pluralize(st.Behind, "commit", "commits"))
+	} else {
+		fmt.Printf("%s  %s\n", c.Dim("remote:  "), "up to date")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdHistory(ctx context.Context, events *task.EventStore, cipher *task.Cipher, c color.Palette) {
+	if events == nil {
+		fmt.Fprintln(os.Stderr, "history requires events storage (set storage.type in config)")
+		os.Exit(1)
+	}
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: tsk history <id>")
+		os.Exit(1)
+	}
+
+	id, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid id: %s\n", os.Args[2])
+		os.Exit(1)
+	}
+
+	changes, err := events.History(ctx, id)
+	if err != nil {
+		fatal(err)
+	}
+	if len(changes) == 0 {
+		fatal(fmt.Errorf("no history for task %d", id))
+	}
+	if err := openChanges(cipher, changes); err != nil {
+		fatal(err)
+	}
+
+	for _, ch := range changes {
+		when := c.Dim(ch.Time.Local().Format("2006-01-02 15:04"))
+		kind := fmt.Sprintf("%-9s", ch.Type)
+
+		switch ch.Type {
+		case task.EventCreated:
+			fmt.Printf("%s  %s  %s\n", when, c.Cyan(kind), ch.After.Title)
+		case task.EventSnapshot:
+			fmt.Printf("%s  %s  %s %s\n", when, c.Dim(kind), ch.After.Title, c.Dim("(earlier history compacted)"))
+		case task.EventCompleted:
+			fmt.Printf("%s  %s\n", when, c.Green(string(ch.Type)))
+			printFields(ch, c, "done", "completed_at")
+		case task.EventRemoved:
+			fmt.Printf("%s  %s\n", when, c.Red(string(ch.Type)))
+		case task.EventTagged:
+			var before []string
+			if ch.Before != nil {
+				before = ch.Before.Tags
+			}
+			fmt.Printf("%s  %s  %s\n", when, kind, tagChanges(before, ch.Tags))
+		default:
+			fmt.Printf("%s  %s\n", when, ch.Type)
+			printFields(ch, c)
+		}
+	}
+}
+
+// printFields lists the fields ch changed, leaving out skip.
+func printFields(ch task.Change, c color.Palette, skip ...string) {
+	for _, d := range ch.Fields() {
+		if slices.Contains(skip, d.Field) {
+			continue
+		}
+		fmt.Printf("%s  %s  %s %s %s\n", strings.Repeat(" ", 16), c.Dim(fmt.Sprintf("%-12s", d.Field)),
+			fieldValue(d.Ours), c.Dim("->"), fieldValue(d.Theirs))
+	}
+}
+
+// tagChanges formats the tags added and removed between two tag lists,
+// such as "+auth -oncall".
+func tagChanges(before, after []string) string {
+	var parts []string
+	for _, t := range after {
+		if !slices.Contains(before, t) {
+			parts = append(parts, "+"+t)
+		}
+	}
+	for _, t := range before {
+		if !slices.Contains(after, t) {
+			parts = append(parts, "-"+t)
+		}
+	}
+	return strings.Join(parts, " ")
+}
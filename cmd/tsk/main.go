@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/zarldev/tsk/internal/color"
@@ -15,29 +20,87 @@ import (
 var version = "dev"
 
 func main() {
+	flags, err := parseGlobalFlags()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	if len(os.Args) < 2 {
 		usage()
 		os.Exit(1)
 	}
 
-	cfg, err := config.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if flags.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.timeout)
+		defer cancel()
+	}
+
+	configPath := flags.config
+	if configPath == "" {
+		if configPath, err = config.Path(); err != nil {
+			fatal(err)
+		}
+	}
+	if os.Args[1] == "config" {
+		cmdConfig(configPath, flags.set)
+		return
+	}
+
+	cfg, err := loadConfig(configPath, flags.set)
+	if err != nil {
+		fatal(err)
+	}
+	checkConfig(configPath)
+
+	moved, err := config.Migrate(cfg)
+	for _, m := range moved {
+		fmt.Fprintf(os.Stderr, "moved %s to %s\n", m.From, m.To)
+	}
+	if err != nil {
+		// the files stay where they were; tsk carries on without them
+		fmt.Fprintf(os.Stderr, "warning: %v\nmove the files by hand to keep using them\n", err)
+	}
+
+	conflictsPath, err := config.ConflictsPath()
 	if err != nil {
 		fatal(err)
 	}
 
 	var store task.Store
+	var gist *task.GistStore
+	var repo *task.GitStore
+	var events *task.EventStore
+	var index *task.IndexedStore
 	switch cfg.Storage.Type {
 	case "file":
 		store = task.NewFileStore(cfg.Storage.Path)
 	case "gist":
 		token := cfg.Storage.GistToken
-		if env := os.Getenv("TSK_GIST_TOKEN"); env != "" {
-			token = env
-		}
 		if token == "" {
 			fatal(fmt.Errorf("gist storage requires gist_token in config or TSK_GIST_TOKEN env var"))
 		}
-		store = task.NewGistStore(token, cfg.Storage.GistID)
+		cachePath, err := config.GistCachePath()
+		if err != nil {
+			fatal(err)
+		}
+		gist = task.NewGistStore(token, cfg.Storage.GistID)
+		gist.ConflictsPath = conflictsPath
+		gist.CachePath = cachePath
+		gist.SetTimeout(cfg.Storage.GistTimeout)
+		store = gist
+	case "git":
+		repo = task.NewGitStore(cfg.Storage.GitDir, cfg.Storage.GitRemote)
+		repo.ConflictsPath = conflictsPath
+		store = repo
+	case "events":
+		events = task.NewEventStore(cfg.Storage.EventsPath)
+		store = events
+	case "indexed":
+		index = task.NewIndexedStore(cfg.Storage.IndexedPath)
+		store = index
 	default:
 		fmt.Fprintf(os.Stderr, "unknown storage type: %s\n", cfg.Storage.Type)
 		os.Exit(1)
@@ -45,80 +108,235 @@ func main() {
 
 	c := color.New(cfg.Color.Enabled)
 
+	journalPath, err := config.JournalPath()
+	if err != nil {
+		fatal(err)
+	}
+	cipher := newCipher(cfg)
+	journal := task.NewJournal(journalPath)
+	journal.Cipher = cipher
+	if cipher != nil {
+		// sealed tags cannot be looked up in the index
+		index = nil
+	}
+
+	switch os.Args[1] {
+	case "encrypt":
+		cmdEncrypt(ctx, store, cipher, journal, cfg.Storage.Type, c)
+		return
+	case "decrypt":
+		cmdDecrypt(ctx, store, cipher, journal, c)
+		return
+	}
+
+	store = task.NewEncryptedStore(store, cipher)
+
+	switch os.Args[1] {
+	case "undo":
+		cmdUndo(ctx, store, journal, c)
+		return
+	case "redo":
+		cmdRedo(ctx, store, journal, c)
+		return
+	}
+
+	store = task.NewJournaledStore(store, journal, strings.Join(os.Args[1:], " "))
+
 	switch os.Args[1] {
 	case "add":
-		cmdAdd(store, c)
+		cmdAdd(ctx, store, c)
 	case "list", "ls":
-		cmdList(store, c)
+		cmdList(ctx, store, index, cfg.Projects.Archived, c)
 	case "done":
-		cmdDone(store, c)
+		cmdDone(ctx, store, c)
 	case "edit":
-		cmdEdit(store, c)
+		cmdEdit(ctx, store, c)
+	case "tag":
+		cmdTag(ctx, store, c)
+	case "project":
+		cmdProject(ctx, store, configPath, cfg, c)
+	case "note":
+		cmdNote(ctx, store, c)
+	case "block":
+		cmdBlock(ctx, store, c)
+	case "unblock":
+		cmdUnblock(ctx, store, c)
+	case "recur":
+		cmdRecur(ctx, store, c)
+	case "start":
+		cmdStart(ctx, store, c)
+	case "stop":
+		cmdStop(ctx, store, c)
+	case "timesheet":
+		cmdTimesheet(ctx, store, c)
 	case "rm":
-		cmdRm(store, c)
+		cmdRm(ctx, store, c)
 	case "clear":
-		cmdClear(store, c)
+		cmdClear(ctx, store, c)
+	case "conflicts":
+		cmdConflicts(ctx, store, cipher, conflictsPath, c)
+	case "sync":
+		cmdSync(ctx, gist, repo, c)
+	case "log":
+		cmdLog(ctx, repo, c)
+	case "history":
+		cmdHistory(ctx, events, cipher, c)
 	case "export":
-		cmdExport(store)
-	case "config":
-		cmdConfig(cfg)
+		cmdExport(ctx, store)
 	case "completion":
 		cmdCompletion()
 	case "version":
 		fmt.Printf("tsk %s\n", version)
 	default:
-		if id, err := strconv.Atoi(os.Args[1]); err == nil {
-			cmdShow(store, c, id)
+		id, err := parseID(ctx, store, os.Args[1])
+		if err == nil {
+			cmdShow(ctx, store, index, cfg.Projects.Archived, c, id)
 			return
 		}
+		if _, _, ok := task.ParseRef(os.Args[1]); ok {
+			fatal(err)
+		}
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
 		usage()
 		os.Exit(1)
 	}
 }
 
-func cmdAdd(store task.Store, c color.Palette) {
+func cmdAdd(ctx context.Context, store task.Store, c color.Palette) {
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "usage: tsk add [-p h|m|l] <title>")
+		fmt.Fprintln(os.Stderr, "usage: tsk add [-p h|m|l] [-P project] [--due <date>] [--every <rule>] [--parent <id>] <title>")
 		os.Exit(1)
 	}
 
-	var title string
+	var words []string
 	var priority task.Priority
-
-	if os.Args[2] == "-p" {
-		if len(os.Args) < 5 {
-			fmt.Fprintln(os.Stderr, "usage: tsk add -p <priority> <title>")
-			os.Exit(1)
-		}
-		p, ok := task.ValidPriority(os.Args[3])
-		if !ok {
-			fmt.Fprintf(os.Stderr, "invalid priority: %s (use h, m, l)\n", os.Args[3])
-			os.Exit(1)
+	var due *time.Time
+	var project string
+	var parent int
+	var every string
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-p":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk add -p <priority> <title>")
+				os.Exit(1)
+			}
+			i++
+			p, ok := task.ValidPriority(args[i])
+			if !ok {
+				fmt.Fprintf(os.Stderr, "invalid priority: %s (use h, m, l)\n", args[i])
+				os.Exit(1)
+			}
+			priority = p
+		case "--due":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk add --due <date> <title>")
+				os.Exit(1)
+			}
+			i++
+			d, err := task.ParseDue(args[i], time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			due = &d
+		case "-P":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk add -P <project> <title>")
+				os.Exit(1)
+			}
+			i++
+			if !task.ValidProject(args[i]) {
+				fmt.Fprintf(os.Stderr, "invalid project name: %q\n", args[i])
+				os.Exit(1)
+			}
+			project = args[i]
+		case "--parent":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk add --parent <id> <title>")
+				os.Exit(1)
+			}
+			i++
+			id, err := parseID(ctx, store, args[i])
+			if err != nil {
+				fatal(err)
+			}
+			parent = id
+		case "--every":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk add --every <rule> <title>")
+				os.Exit(1)
+			}
+			i++
+			if !task.ValidRecur(args[i]) {
+				fmt.Fprintf(os.Stderr, "invalid recurrence: %s (use 3d/1w/2m, a weekday, month-end, daily, weekly or monthly)\n", args[i])
+				os.Exit(1)
+			}
+			every = args[i]
+		default:
+			words = append(words, args[i])
 		}
-		priority = p
-		title = os.Args[4]
-	} else {
-		title = os.Args[2]
 	}
 
-	tasks, err := store.Load()
-	if err != nil {
-		fatal(err)
+	if every != "" && due == nil {
+		d, err := task.FirstDue(every, time.Now())
+		if err != nil {
+			fatal(err)
+		}
+		due = &d
 	}
 
-	tasks = task.Add(tasks, title, priority)
+	title, tags := task.ExtractTags(strings.Join(words, " "))
+	if title == "" {
+		fmt.Fprintln(os.Stderr, "usage: tsk add [-p h|m|l] [-P project] [--due <date>] [--every <rule>] [--parent <id>] <title>")
+		os.Exit(1)
+	}
 
-	if err := store.Save(tasks); err != nil {
+	var t task.Task
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		tasks = task.Add(tasks, title, priority)
+		added := &tasks[len(tasks)-1]
+		added.Due = due
+		added.Tags = tags
+		added.Recur = every
+
+		into := project
+		if parent != 0 {
+			if err := task.SetParent(tasks, added.ID, parent); err != nil {
+				return nil, err
+			}
+			// subtasks live in their parent's project unless told otherwise
+			if p := task.Find(tasks, parent); into == "" {
+				into = p.Project
+			}
+		}
+		if err := task.SetProject(tasks, added.ID, into); err != nil {
+			return nil, err
+		}
+		t = *added
+		return tasks, nil
+	})
+	if err != nil {
 		fatal(err)
 	}
 
-	t := tasks[len(tasks)-1]
-	fmt.Printf("added task %s: %s\n", c.BoldCyan(strconv.Itoa(t.ID)), t.Title)
+	fmt.Printf("added task %s: %s\n", formatID(c, t), t.Title)
 }
 
-func cmdShow(store task.Store, c color.Palette, id int) {
-	tasks, err := store.Load()
+func cmdShow(ctx context.Context, store task.Store, index *task.IndexedStore, archived []string, c color.Palette, id int) {
+	var tasks []task.Task
+	var err error
+	if index != nil {
+		// the task and the ones it mentions are enough
+		var t task.Task
+		if t, err = index.Get(ctx, id); err == nil {
+			tasks, err = related(ctx, index, []task.Task{t})
+		}
+	} else {
+		tasks, err = store.Load(ctx)
+	}
 	if err != nil {
 		fatal(err)
 	}
@@ -137,7 +355,7 @@ func cmdShow(store task.Store, c color.Palette, id int) {
 	created := t.CreatedAt.Format("2006-01-02 15:04:05")
 	createdAge := age(t.CreatedAt)
 
-	fmt.Printf("  %s  %s\n", c.Dim("id:"), c.BoldCyan(strconv.Itoa(t.ID)))
+	fmt.Printf("  %s  %s\n", c.Dim("id:"), formatID(c, *t))
 	fmt.Printf("  %s  %s\n", c.Dim("title:"), t.Title)
 
 	if t.Priority != task.PriorityNone {
@@ -145,7 +363,66 @@ func cmdShow(store task.Store, c color.Palette, id int) {
 		fmt.Printf("  %s  %s\n", c.Dim("priority:"), pv)
 	}
 
+	if t.Parent != 0 {
+		parent := strconv.Itoa(t.Parent)
+		if p := task.Find(tasks, t.Parent); p != nil {
+			parent = c.BoldCyan(parent) + " " + p.Title
+		}
+		fmt.Printf("  %s  %s\n", c.Dim("parent:"), parent)
+	}
+
+	if done, total := task.Progress(tasks, t.ID); total > 0 {
+		fmt.Printf("  %s  %d/%d done\n", c.Dim("subtasks:"), done, total)
+	}
+
+	for _, d := range t.DependsOn {
+		line := c.BoldCyan(strconv.Itoa(d))
+		if dep := task.Find(tasks, d); dep != nil {
+			line += " " + dep.Title
+			if dep.Done {
+				line += " " + c.Green("(done)")
+			}
+		}
+		fmt.Printf("  %s  %s\n", c.Dim("waits on:"), line)
+	}
+
+	for _, dep := range task.Dependents(tasks, t.ID) {
+		fmt.Printf("  %s  %s %s\n", c.Dim("blocks:"), c.BoldCyan(strconv.Itoa(dep.ID)), dep.Title)
+	}
+
+	if t.Project != "" {
+		project := t.Project
+		if slices.Contains(archived, t.Project) {
+			project += " " + c.Dim("(archived)")
+		}
+		fmt.Printf("  %s  %s\n", c.Dim("project:"), project)
+	}
+
+	if len(t.Tags) > 0 {
+		fmt.Printf("  %s  %s\n", c.Dim("tags:"), c.Cyan(formatTags(t.Tags)))
+	}
+
 	fmt.Printf("  %s  %s\n", c.Dim("status:"), status)
+
+	if t.Due != nil {
+		label := dueLabel(*t.Due, time.Now())
+		if !t.Done {
+			label = colorDue(c, *t.Due, time.Now(), label)
+		}
+		fmt.Printf("  %s  %s %s\n", c.Dim("due:"), t.Due.Format("2006-01-02"), "("+label+")")
+	}
+
+	if t.Recur != "" {
+		fmt.Printf("  %s  every %s\n", c.Dim("repeats:"), t.Recur)
+	}
+
+	if len(t.Intervals) > 0 {
+		tracked := formatDuration(task.Tracked(*t, time.Now()))
+		if t.Running() {
+			tracked += " " + c.Green("(running)")
+		}
+		fmt.Printf("  %s  %s\n", c.Dim("tracked:"), tracked)
+	}
 	fmt.Printf("  %s  %s %s\n", c.Dim("created:"), created, c.Dim("("+createdAge+")"))
 
 	if t.CompletedAt != nil {
@@ -153,195 +430,535 @@ func cmdShow(store task.Store, c color.Palette, id int) {
 		completedAge := age(*t.CompletedAt)
 		fmt.Printf("  %s  %s %s\n", c.Dim("completed:"), completed, c.Dim("("+completedAge+")"))
 	}
+
+	if t.Notes != "" {
+		fmt.Printf("\n  %s\n", c.Dim("notes:"))
+		for _, line := range strings.Split(t.Notes, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
 }
 
-func cmdList(store task.Store, c color.Palette) {
-	f := task.FilterAll
-	if len(os.Args) > 2 {
-		switch os.Args[2] {
+func cmdList(ctx context.Context, store task.Store, index *task.IndexedStore, archived []string, c color.Palette) {
+	now := time.Now()
+	var filters []task.Filter
+	var project, tag string
+	var done, pending, blocked, ready bool
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
 		case "--done":
-			f = task.FilterDone
+			filters = append(filters, task.FilterDone)
+			done = true
 		case "--pending":
-			f = task.FilterPending
+			filters = append(filters, task.FilterPending)
+			pending = true
+		case "--overdue":
+			filters = append(filters, task.Overdue(now))
+		case "--blocked":
+			blocked = true
+		case "--ready":
+			ready = true
+		case "--due-before":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk list --due-before <date>")
+				os.Exit(1)
+			}
+			i++
+			d, err := task.ParseDue(args[i], now)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			filters = append(filters, task.DueBefore(d))
+		case "-P":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk list -P <project>")
+				os.Exit(1)
+			}
+			i++
+			project = args[i]
 		default:
-			fmt.Fprintf(os.Stderr, "unknown flag: %s\n", os.Args[2])
+			if t, ok := strings.CutPrefix(args[i], "+"); ok && t != "" {
+				filters = append(filters, task.HasTag(t))
+				tag = t
+				continue
+			}
+			if tag, ok := strings.CutPrefix(args[i], "-"); ok && tag != "" && tag[0] != '-' {
+				filters = append(filters, task.Not(task.HasTag(tag)))
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "unknown flag: %s\n", args[i])
 			os.Exit(1)
 		}
 	}
 
-	tasks, err := store.Load()
+	// archived projects stay hidden unless asked for by name
+	if project != "" {
+		filters = append(filters, task.InProject(project))
+	} else {
+		filters = append(filters, task.Unarchived(archived))
+	}
+
+	tasks, err := listTasks(ctx, store, index, tag, done, pending, filters)
 	if err != nil {
 		fatal(err)
 	}
 
-	filtered := task.List(tasks, f)
+	if blocked {
+		filters = append(filters, task.Blocked(tasks))
+	}
+	if ready {
+		filters = append(filters, task.Ready(tasks))
+	}
+
+	filtered := task.List(tasks, filters...)
 	if len(filtered) == 0 {
 		fmt.Println("no tasks")
 		return
 	}
 
-	for _, t := range filtered {
-		id := c.BoldCyan(fmt.Sprintf("%3d", t.ID))
-		a := c.Dim(fmt.Sprintf("(%s)", age(t.CreatedAt)))
-		pri := priorityIndicator(c, t.Priority)
+	if project != "" {
+		printTree(c, tasks, filtered, now)
+		return
+	}
 
-		if t.Done {
-			check := c.Green("[x]")
-			title := c.DimStrikethrough(t.Title)
-			fmt.Printf("%s %s %s %s  %s\n", id, pri, check, title, a)
-		} else {
-			fmt.Printf("%s %s [ ] %s  %s\n", id, pri, t.Title, a)
+	for i, p := range task.Projects(filtered) {
+		if p.Name != "" {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(c.Bold(p.Name))
+		}
+		printTree(c, tasks, task.List(filtered, task.InProject(p.Name)), now)
+	}
+}
+
+// listTasks returns the tasks cmdList works from. With an index and a
+// tag or status to look up, those are the tasks passing filters and the
+// tasks related to them, which is all the list shows or counts;
+// otherwise it is every task.
+func listTasks(ctx context.Context, store task.Store, index *task.IndexedStore, tag string, done, pending bool, filters []task.Filter) ([]task.Task, error) {
+	var tasks []task.Task
+	var err error
+	switch {
+	case index == nil:
+		return store.Load(ctx)
+	case tag != "":
+		tasks, err = index.Tagged(ctx, tag)
+	case done || pending:
+		tasks, err = index.WithStatus(ctx, done)
+	default:
+		return store.Load(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return related(ctx, index, task.List(tasks, filters...))
+}
+
+// related returns tasks and the tasks related to them from the index.
+// The index is only used without a cipher, so encrypted tasks are an
+// error, as they are from EncryptedStore.
+func related(ctx context.Context, index *task.IndexedStore, tasks []task.Task) ([]task.Task, error) {
+	all, err := index.Related(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
+	if task.Encrypted(all) {
+		return nil, task.ErrNoPassphrase
+	}
+	return all, nil
+}
+
+// printTree prints set as a tree for the list view. Tasks whose parent
+// is not in set are roots; subtasks are indented below their parent.
+// all holds at least the tasks related to set, for the progress
+// counters and open dependencies.
+func printTree(c color.Palette, all, set []task.Task, now time.Time) {
+	in := make(map[int]bool, len(set))
+	for _, t := range set {
+		in[t.ID] = true
+	}
+
+	children := make(map[int][]task.Task)
+	var roots []task.Task
+	for _, t := range set {
+		if t.Parent != 0 && in[t.Parent] {
+			children[t.Parent] = append(children[t.Parent], t)
+			continue
+		}
+		roots = append(roots, t)
+	}
+
+	var walk func(t task.Task, depth int)
+	walk = func(t task.Task, depth int) {
+		progress := ""
+		if done, total := task.Progress(all, t.ID); total > 0 {
+			progress = fmt.Sprintf("(%d/%d)", done, total)
 		}
+		printTask(c, t, now, depth, progress, task.OpenDependencies(all, t))
+		for _, child := range children[t.ID] {
+			walk(child, depth+1)
+		}
+	}
+	for _, t := range roots {
+		walk(t, 0)
 	}
 }
 
-func cmdDone(store task.Store, c color.Palette) {
+// printTask prints a single task row for the list view, indented by
+// depth levels and followed by an optional progress counter and the
+// IDs of any pending tasks it waits on.
+func printTask(c color.Palette, t task.Task, now time.Time, depth int, progress string, waiting []int) {
+	id := c.BoldCyan(fmt.Sprintf("%3d", t.ID))
+	a := c.Dim(fmt.Sprintf("(%s)", age(t.CreatedAt)))
+	pri := priorityIndicator(c, t.Priority)
+	indent := strings.Repeat("  ", depth)
+
+	tags := ""
+	if len(t.Tags) > 0 {
+		tags = " " + c.Cyan(formatTags(t.Tags))
+	}
+	if progress != "" {
+		progress = " " + c.Dim(progress)
+	}
+
+	if t.Done {
+		check := c.Green("[x]")
+		title := c.DimStrikethrough(t.Title)
+		fmt.Printf("%s %s %s%s %s%s%s  %s\n", id, pri, indent, check, title, progress, tags, a)
+		return
+	}
+
+	due := ""
+	if t.Due != nil {
+		due = "  " + colorDue(c, *t.Due, now, dueLabel(*t.Due, now))
+	}
+	if t.Recur != "" {
+		due += " " + c.Dim("(every "+t.Recur+")")
+	}
+	if t.Running() {
+		due += "  " + c.Green("tracking "+formatDuration(task.Tracked(t, now)))
+	}
+	if len(waiting) > 0 {
+		due += "  " + c.Yellow("blocked by "+joinIDs(waiting))
+	}
+	fmt.Printf("%s %s %s[ ] %s%s%s%s  %s\n", id, pri, indent, t.Title, progress, tags, due, a)
+}
+
+func cmdDone(ctx context.Context, store task.Store, c color.Palette) {
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "usage: tsk done <id>[,<id>,...]")
+		fmt.Fprintln(os.Stderr, "usage: tsk done <id>[,<id>,...] [--recursive]")
 		os.Exit(1)
 	}
 
-	ids, err := parseIDs(os.Args[2])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	var idArg string
+	var recursive bool
+	for _, arg := range os.Args[2:] {
+		switch arg {
+		case "--recursive", "-r":
+			recursive = true
+		default:
+			if idArg != "" {
+				fmt.Fprintln(os.Stderr, "usage: tsk done <id>[,<id>,...] [--recursive]")
+				os.Exit(1)
+			}
+			idArg = arg
+		}
+	}
+	if idArg == "" {
+		fmt.Fprintln(os.Stderr, "usage: tsk done <id>[,<id>,...] [--recursive]")
 		os.Exit(1)
 	}
 
-	tasks, err := store.Load()
+	ids, err := parseIDs(ctx, store, idArg)
 	if err != nil {
 		fatal(err)
 	}
 
+	// output is collected and printed once the update has been saved
+	var out strings.Builder
 	var hadErr bool
-	for _, id := range ids {
-		if err := task.Done(tasks, id); err != nil {
-			fmt.Fprintf(os.Stderr, "task %d: not found\n", id)
-			hadErr = true
-			continue
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		for _, id := range ids {
+			var done task.Completion
+			var err error
+			if recursive {
+				tasks, done, err = task.DoneRecursive(tasks, id)
+			} else {
+				tasks, done, err = task.Done(tasks, id)
+			}
+			if err != nil {
+				if errors.Is(err, task.ErrOpenSubtasks) {
+					fmt.Fprintf(os.Stderr, "task %d has open subtasks (use --recursive to complete them too)\n", id)
+				} else {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				hadErr = true
+				continue
+			}
+			for _, cid := range done.Completed {
+				fmt.Fprintf(&out, "task %s marked %s\n", c.BoldCyan(strconv.Itoa(cid)), c.Green("done"))
+			}
+			for _, uid := range done.Unblocked {
+				fmt.Fprintf(&out, "task %s is now %s\n", c.BoldCyan(strconv.Itoa(uid)), c.Green("unblocked"))
+			}
+			for _, nid := range done.Spawned {
+				next := task.Find(tasks, nid)
+				fmt.Fprintf(&out, "next occurrence: task %s due %s\n", c.BoldCyan(strconv.Itoa(nid)), next.Due.Format("2006-01-02"))
+			}
 		}
-		fmt.Printf("task %s marked %s\n", c.BoldCyan(strconv.Itoa(id)), c.Green("done"))
-	}
-
-	if err := store.Save(tasks); err != nil {
+		return tasks, nil
+	})
+	if err != nil {
 		fatal(err)
 	}
+	fmt.Print(out.String())
 
 	if hadErr {
 		os.Exit(1)
 	}
 }
 
-func cmdEdit(store task.Store, c color.Palette) {
+func cmdEdit(ctx context.Context, store task.Store, c color.Palette) {
 	if len(os.Args) < 4 {
-		fmt.Fprintln(os.Stderr, "usage: tsk edit <id> <title>")
+		fmt.Fprintln(os.Stderr, "usage: tsk edit <id> [--due <date>|none] [--parent <id>|none] [<title>]")
 		os.Exit(1)
 	}
 
-	id, err := strconv.Atoi(os.Args[2])
+	id, err := parseID(ctx, store, os.Args[2])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "invalid id: %s\n", os.Args[2])
-		os.Exit(1)
+		fatal(err)
 	}
 
-	title := os.Args[3]
-
-	tasks, err := store.Load()
+	var words []string
+	var due *time.Time
+	var setDue, setParent bool
+	var parent int
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--due":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk edit <id> --due <date>|none")
+				os.Exit(1)
+			}
+			i++
+			setDue = true
+			if args[i] == "none" {
+				continue
+			}
+			d, err := task.ParseDue(args[i], time.Now())
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			due = &d
+		case "--parent":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk edit <id> --parent <id>|none")
+				os.Exit(1)
+			}
+			i++
+			setParent = true
+			if args[i] == "none" {
+				continue
+			}
+			p, err := parseID(ctx, store, args[i])
+			if err != nil {
+				fatal(err)
+			}
+			parent = p
+		default:
+			words = append(words, args[i])
+		}
+	}
+	title := strings.Join(words, " ")
+
+	var t task.Task
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		if title != "" {
+			if err := task.Edit(tasks, id, title); err != nil {
+				return nil, err
+			}
+		}
+		if setDue {
+			if err := task.SetDue(tasks, id, due); err != nil {
+				return nil, err
+			}
+		}
+		if setParent {
+			if err := task.SetParent(tasks, id, parent); err != nil {
+				return nil, err
+			}
+		}
+		found := task.Find(tasks, id)
+		if found == nil {
+			return nil, fmt.Errorf("task %d: not found", id)
+		}
+		t = *found
+		return tasks, nil
+	})
 	if err != nil {
 		fatal(err)
 	}
 
-	if err := task.Edit(tasks, id, title); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	fmt.Printf("task %s updated: %s\n", c.BoldCyan(strconv.Itoa(id)), t.Title)
+	if setDue {
+		if t.Due == nil {
+			fmt.Println("due date cleared")
+		} else {
+			fmt.Printf("due %s (%s)\n", t.Due.Format("2006-01-02"), dueLabel(*t.Due, time.Now()))
+		}
+	}
+}
+
+func cmdTag(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk tag <id> +tag|-tag ...")
 		os.Exit(1)
 	}
 
-	if err := store.Save(tasks); err != nil {
+	id, err := parseID(ctx, store, os.Args[2])
+	if err != nil {
+		fatal(err)
+	}
+
+	var add, remove []string
+	for _, arg := range os.Args[3:] {
+		switch {
+		case len(arg) > 1 && arg[0] == '+':
+			add = append(add, arg[1:])
+		case len(arg) > 1 && arg[0] == '-':
+			remove = append(remove, arg[1:])
+		default:
+			fmt.Fprintf(os.Stderr, "invalid tag: %s (use +tag to add, -tag to remove)\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	var tags []string
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		if err := task.Tag(tasks, id, add, remove); err != nil {
+			return nil, err
+		}
+		tags = task.Find(tasks, id).Tags
+		return tasks, nil
+	})
+	if err != nil {
 		fatal(err)
 	}
 
-	fmt.Printf("task %s updated: %s\n", c.BoldCyan(strconv.Itoa(id)), title)
+	if len(tags) == 0 {
+		fmt.Printf("task %s has no tags\n", c.BoldCyan(strconv.Itoa(id)))
+		return
+	}
+	fmt.Printf("task %s tagged: %s\n", c.BoldCyan(strconv.Itoa(id)), c.Cyan(formatTags(tags)))
 }
 
-func cmdRm(store task.Store, c color.Palette) {
+func cmdRm(ctx context.Context, store task.Store, c color.Palette) {
 	if len(os.Args) < 3 {
 		fmt.Fprintln(os.Stderr, "usage: tsk rm <id>[,<id>,...]")
 		os.Exit(1)
 	}
 
-	ids, err := parseIDs(os.Args[2])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-
-	tasks, err := store.Load()
+	ids, err := parseIDs(ctx, store, os.Args[2])
 	if err != nil {
 		fatal(err)
 	}
 
+	var out strings.Builder
 	var hadErr bool
-	for _, id := range ids {
-		var rmErr error
-		tasks, rmErr = task.Remove(tasks, id)
-		if rmErr != nil {
-			fmt.Fprintf(os.Stderr, "task %d: not found\n", id)
-			hadErr = true
-			continue
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		// subtasks removed along with an earlier id are not reported
+		// as missing when they are listed too
+		removed := make(map[int]bool)
+		for _, id := range ids {
+			if removed[id] {
+				continue
+			}
+			subtasks := task.Descendants(tasks, id)
+			var rmErr error
+			tasks, rmErr = task.Remove(tasks, id)
+			if rmErr != nil {
+				fmt.Fprintf(os.Stderr, "task %d: not found\n", id)
+				hadErr = true
+				continue
+			}
+			for _, sid := range subtasks {
+				removed[sid] = true
+			}
+			if sub := len(subtasks); sub > 0 {
+				fmt.Fprintf(&out, "task %s removed (with %d %s)\n", c.BoldCyan(strconv.Itoa(id)),
+					sub, pluralize(sub, "subtask", "subtasks"))
+				continue
+			}
+			fmt.Fprintf(&out, "task %s removed\n", c.BoldCyan(strconv.Itoa(id)))
 		}
-		fmt.Printf("task %s removed\n", c.BoldCyan(strconv.Itoa(id)))
-	}
-
-	if err := store.Save(tasks); err != nil {
+		return tasks, nil
+	})
+	if err != nil {
 		fatal(err)
 	}
+	fmt.Print(out.String())
 
 	if hadErr {
 		os.Exit(1)
 	}
 }
 
-func cmdClear(store task.Store, c color.Palette) {
-	tasks, err := store.Load()
+func cmdClear(ctx context.Context, store task.Store, c color.Palette) {
+	var removed int
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		removed, tasks = task.ClearDone(tasks)
+		return tasks, nil
+	})
 	if err != nil {
 		fatal(err)
 	}
 
-	removed, tasks := task.ClearDone(tasks)
-
 	if removed == 0 {
 		fmt.Println("no done tasks to clear")
 		return
 	}
 
-	if err := store.Save(tasks); err != nil {
-		fatal(err)
-	}
-
 	fmt.Printf("cleared %s done %s\n",
 		c.BoldCyan(strconv.Itoa(removed)),
 		pluralize(removed, "task", "tasks"))
 }
 
-func cmdExport(store task.Store) {
-	f := task.FilterAll
-	if len(os.Args) > 2 {
-		switch os.Args[2] {
+func cmdExport(ctx context.Context, store task.Store) {
+	var filters []task.Filter
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
 		case "--done":
-			f = task.FilterDone
+			filters = append(filters, task.FilterDone)
 		case "--pending":
-			f = task.FilterPending
+			filters = append(filters, task.FilterPending)
+		case "-P":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk export -P <project>")
+				os.Exit(1)
+			}
+			i++
+			filters = append(filters, task.InProject(args[i]))
 		default:
-			fmt.Fprintf(os.Stderr, "unknown flag: %s\n", os.Args[2])
+			fmt.Fprintf(os.Stderr, "unknown flag: %s\n", args[i])
 			os.Exit(1)
 		}
 	}
 
-	tasks, err := store.Load()
+	tasks, err := store.Load(ctx)
 	if err != nil {
 		fatal(err)
 	}
 
-	filtered := task.List(tasks, f)
+	filtered := task.List(tasks, filters...)
 	if len(filtered) == 0 {
 		return
 	}
@@ -356,9 +973,33 @@ func cmdExport(store task.Store) {
 			line += fmt.Sprintf(" (%s)", t.Priority)
 		}
 		fmt.Println(line)
+		if t.Notes != "" {
+			for _, n := range strings.Split(t.Notes, "\n") {
+				fmt.Println(strings.TrimRight("  "+n, " "))
+			}
+		}
 	}
 }
 
+// formatTags renders tags in +tag form, separated by spaces.
+func formatTags(tags []string) string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = "+" + tag
+	}
+	return strings.Join(out, " ")
+}
+
+// formatID returns the ID of t, followed by its per-project ID if it
+// has one.
+func formatID(c color.Palette, t task.Task) string {
+	id := c.BoldCyan(strconv.Itoa(t.ID))
+	if ref := task.Ref(t); ref != "" {
+		id += " " + c.Dim("("+ref+")")
+	}
+	return id
+}
+
 func pluralize(n int, singular, plural string) string {
 	if n == 1 {
 		return singular
@@ -384,6 +1025,37 @@ func age(t time.Time) string {
 	}
 }
 
+// dueLabel describes a due date relative to now, e.g. "due in 2 days"
+// or "3 days overdue".
+func dueLabel(due, now time.Time) string {
+	days := task.DaysUntil(due, now)
+	switch {
+	case days == 0:
+		return "due today"
+	case days == 1:
+		return "due tomorrow"
+	case days > 1:
+		return fmt.Sprintf("due in %d days", days)
+	case days == -1:
+		return "1 day overdue"
+	default:
+		return fmt.Sprintf("%d days overdue", -days)
+	}
+}
+
+// colorDue colors a due label: red when overdue, yellow when due today.
+func colorDue(c color.Palette, due, now time.Time, label string) string {
+	days := task.DaysUntil(due, now)
+	switch {
+	case days < 0:
+		return c.Red(label)
+	case days == 0:
+		return c.Yellow(label)
+	default:
+		return c.Dim(label)
+	}
+}
+
 // priorityIndicator returns a 2-char wide indicator for the list view.
 func priorityIndicator(c color.Palette, p task.Priority) string {
 	switch p {
@@ -410,43 +1082,184 @@ func colorPriority(c color.Palette, p task.Priority) string {
 	}
 }
 
-func cmdConfig(cfg config.Config) {
-	fmt.Print(cfg.String())
+// joinIDs formats task IDs as a comma-separated list.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseID parses a task reference: an ID, or a per-project ID such as
+// work#3, which is looked up in store.
+func parseID(ctx context.Context, store task.Store, arg string) (int, error) {
+	ids, err := parseIDs(ctx, store, arg)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) != 1 {
+		return 0, fmt.Errorf("invalid id: %s", arg)
+	}
+	return ids[0], nil
 }
 
 // parseIDs splits a comma-separated string into a slice of task IDs.
-// All segments must be valid integers; returns an error on the first invalid one.
-func parseIDs(arg string) ([]int, error) {
+// Each segment is an ID or a per-project ID; tasks are loaded from
+// store only to look up the latter. Returns an error on the first
+// invalid or unknown one.
+func parseIDs(ctx context.Context, store task.Store, arg string) ([]int, error) {
 	parts := strings.Split(arg, ",")
 	ids := make([]int, 0, len(parts))
+	var tasks []task.Task
 	for _, p := range parts {
-		id, err := strconv.Atoi(p)
-		if err != nil {
+		if id, err := strconv.Atoi(p); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		project, n, ok := task.ParseRef(p)
+		if !ok {
 			return nil, fmt.Errorf("invalid id: %s", p)
 		}
-		ids = append(ids, id)
+		if tasks == nil {
+			var err error
+			if tasks, err = store.Load(ctx); err != nil {
+				return nil, err
+			}
+		}
+		t := task.FindRef(tasks, project, n)
+		if t == nil {
+			return nil, fmt.Errorf("task %s: not found", p)
+		}
+		ids = append(ids, t.ID)
 	}
 	return ids, nil
 }
 
+// globalFlags are the flags that come before the command.
+type globalFlags struct {
+	timeout time.Duration // 0 if none was given
+	config  string        // config file path, "" for the default
+	set     []string      // key=value settings overriding the config
+}
+
+// parseGlobalFlags removes the flags that come before the command from
+// os.Args and returns them.
+func parseGlobalFlags() (globalFlags, error) {
+	var flags globalFlags
+	for len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "--") {
+		name, value, ok := strings.Cut(os.Args[1], "=")
+		var arg string
+		switch name {
+		case "--timeout":
+			arg = "<duration>"
+		case "--config":
+			arg = "<path>"
+		case "--set":
+			arg = "<key>=<value>"
+		default:
+			return flags, fmt.Errorf("unknown flag: %s", name)
+		}
+		if !ok {
+			if len(os.Args) < 3 {
+				return flags, fmt.Errorf("usage: tsk %s %s <command>", name, arg)
+			}
+			value = os.Args[2]
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+		switch name {
+		case "--timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return flags, fmt.Errorf("invalid timeout: %s (use e.g. 10s, 1m)", value)
+			}
+			flags.timeout = d
+		case "--config":
+			if value == "" {
+				return flags, fmt.Errorf("usage: tsk --config <path> <command>")
+			}
+			flags.config = value
+		case "--set":
+			if !strings.Contains(value, "=") {
+				return flags, fmt.Errorf("usage: tsk --set <key>=<value> <command>")
+			}
+			flags.set = append(flags.set, value)
+		}
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	return flags, nil
+}
+
 func usage() {
-	fmt.Fprintln(os.Stderr, `usage: tsk <command> [args]
+	fmt.Fprintln(os.Stderr, `usage: tsk [--timeout <duration>] [--config <path>] [--set <key>=<value>] <command> [args]
 
 commands:
-  <id>                         show task details
-  add [-p h|m|l] <title>  add a new task (h=high, m=medium, l=low)
-  list, ls [--done|--pending]  list tasks
-  done <id>[,<id>,...]         mark tasks as done
-  edit <id> <title>            rename a task
-  rm <id>[,<id>,...]           remove tasks
+  <id>                         show task details; an <id> is a task ID
+                               or a per-project ID such as work#3
+  add [-p h|m|l] [-P project] [--due <date>] [--every <rule>] [--parent <id>] <title> [+tag ...]
+                               add a new task (h=high, m=medium, l=low)
+  list, ls [--done|--pending] [--overdue] [--blocked|--ready] [--due-before <date>] [-P project] [+tag|-tag ...]
+                               list tasks
+  done <id>[,<id>,...] [--recursive]
+                               mark tasks (and with --recursive, their subtasks) as done
+  edit <id> [--due <date>|none] [--parent <id>|none] [<title>]
+                               rename a task or change its due date or parent
+  tag <id> +tag|-tag ...       add or remove tags
+  note <id> [-m <text>]        edit notes in $EDITOR, or append a line
+  block <id> --on <id>[,<id>]  make a task wait on other tasks
+  unblock <id> [--on <id>]     remove one or all dependencies
+  recur [stop <id>]            list recurring tasks, or stop a series
+  start <id>                   start tracking time on a task
+  stop                         stop tracking time
+  timesheet [--since <date>]   report tracked time by day and task
+  rm <id>[,<id>,...]           remove tasks and their subtasks
   clear                        remove all done tasks
-  export [--done|--pending]    export tasks as markdown
-  config                       show current configuration
+  undo [--list]                undo the last change, or list recent changes
+  redo                         reapply the last undone change
+  sync [--status]              sync with the gist or git remote, or show sync status
+  log [-n <count>|--all]       show task history (git storage)
+  history <id>                 show every change to a task (events storage)
+  encrypt                      encrypt stored tasks with the configured passphrase
+  decrypt                      store tasks unencrypted again
+  conflicts                    list tasks changed on two machines at once
+  conflicts resolve <id> --ours|--theirs
+                               keep one side of a conflict
+  project list                 show per-project task counts
+  project rename <old> <new>   rename a project
+  project archive <name>       hide a project from the list view
+  project unarchive <name>     show an archived project again
+  export [--done|--pending] [-P project]
+                               export tasks as markdown
+  config                       show current configuration and where each value came from
+  config get <key>             print one setting, e.g. storage.type
+  config set <key> <value>     change a setting in the config file
+  config unset <key>           remove a setting, restoring its default
+  config edit                  edit the config file in $EDITOR
+  config init                  write a commented config file to start from
+  config check                 list every problem in the config file
   completion <bash|zsh|fish>   generate shell completions
-  version                      print version`)
+  version                      print version
+
+global flags:
+  --timeout <duration>         give up if the command takes longer (e.g. 10s)
+  --config <path>              use this config file instead of the default
+                               (or set TSK_CONFIG)
+  --set <key>=<value>          override a setting for this command, e.g.
+                               --set storage.type=git (or set TSK_STORAGE_TYPE)`)
 }
 
 func fatal(err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(os.Stderr, "interrupted")
+		os.Exit(130)
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "timed out: %v\n", err)
+		os.Exit(1)
+	case errors.Is(err, task.ErrNoPassphrase):
+		fmt.Fprintf(os.Stderr, "%v: set TSK_PASSPHRASE or storage.passphrase_cmd in config\n", err)
+		os.Exit(1)
+	}
 	fmt.Fprintln(os.Stderr, err)
 	os.Exit(1)
 }
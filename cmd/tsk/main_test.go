@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func TestParseIDs(t *testing.T) {
+	store := task.NewFileStore(filepath.Join(t.TempDir(), "tasks.json"))
+	err := store.Save(context.Background(), []task.Task{
+		{ID: 1, Title: "a", Project: "work", ProjectID: 1},
+		{ID: 4, Title: "b", Project: "work", ProjectID: 2},
+		{ID: 5, Title: "c"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		arg     string
+		want    []int
+		wantErr string
+	}{
+		{"3", []int{3}, ""},
+		{"1,2,3", []int{1, 2, 3}, ""},
+		{"work#2", []int{4}, ""},
+		{"5,work#1", []int{5, 1}, ""},
+		{"abc", nil, "invalid id: abc"},
+		{"1,,2", nil, "invalid id: "},
+		{"work#0", nil, "invalid id: work#0"},
+		{"work#9", nil, "task work#9: not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseIDs(context.Background(), store, tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseIDs(%q) err = %v, want %q", tt.arg, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseIDs(%q) = %v, want %v", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	args := os.Args
+	t.Cleanup(func() { os.Args = args })
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     globalFlags
+		wantArgs []string
+		wantErr  string
+	}{
+		{"none", []string{"tsk", "ls"}, globalFlags{}, []string{"tsk", "ls"}, ""},
+		{"timeout", []string{"tsk", "--timeout", "5s", "ls"}, globalFlags{timeout: 5 * time.Second}, []string{"tsk", "ls"}, ""},
+		{"timeout with =", []string{"tsk", "--timeout=1m", "ls", "-a"}, globalFlags{timeout: time.Minute}, []string{"tsk", "ls", "-a"}, ""},
+		{"several", []string{"tsk", "--config", "/tmp/tsk.toml", "--set", "color.enabled=never", "--set=storage.type=file", "add", "--due", "x"},
+			globalFlags{config: "/tmp/tsk.toml", set: []string{"color.enabled=never", "storage.type=file"}}, []string{"tsk", "add", "--due", "x"}, ""},
+		{"no command", []string{"tsk"}, globalFlags{}, []string{"tsk"}, ""},
+		{"missing value", []string{"tsk", "--timeout"}, globalFlags{}, nil, "usage: tsk --timeout <duration> <command>"},
+		{"bad timeout", []string{"tsk", "--timeout=abc", "ls"}, globalFlags{}, nil, "invalid timeout: abc (use e.g. 10s, 1m)"},
+		{"zero timeout", []string{"tsk", "--timeout=0s", "ls"}, globalFlags{}, nil, "invalid timeout: 0s (use e.g. 10s, 1m)"},
+		{"empty config", []string{"tsk", "--config=", "ls"}, globalFlags{}, nil, "usage: tsk --config <path> <command>"},
+		{"set without =", []string{"tsk", "--set", "color.enabled", "ls"}, globalFlags{}, nil, "usage: tsk --set <key>=<value> <command>"},
+		{"unknown", []string{"tsk", "--verbose", "ls"}, globalFlags{}, nil, "unknown flag: --verbose"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = slices.Clone(tt.args)
+			got, err := parseGlobalFlags()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flags = %+v, want %+v", got, tt.want)
+			}
+			if !slices.Equal(os.Args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", os.Args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdNote(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: tsk note <id> [-m <text>]")
+		os.Exit(1)
+	}
+
+	id, err := parseID(ctx, store, os.Args[2])
+	if err != nil {
+		fatal(err)
+	}
+
+	var message string
+	var appendMode bool
+	if len(os.Args) > 3 {
+		if os.Args[3] != "-m" || len(os.Args) < 5 {
+			fmt.Fprintln(os.Stderr, "usage: tsk note <id> -m <text>")
+			os.Exit(1)
+		}
+		message = strings.Join(os.Args[4:], " ")
+		appendMode = true
+	}
+
+	if appendMode {
+		err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+			return tasks, task.AppendNote(tasks, id, message, time.Now())
+		})
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("task %s notes updated\n", c.BoldCyan(strconv.Itoa(id)))
+		return
+	}
+
+	// the editor runs outside the update so other commands are not
+	// kept waiting while the notes are open
+	tasks, err := store.Load(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	t := task.Find(tasks, id)
+	if t == nil {
+		fmt.Fprintf(os.Stderr, "task %d: not found\n", id)
+		os.Exit(1)
+	}
+
+	notes, err := editText(t.Notes, "tsk-note-*.md")
+	if err != nil {
+		fatal(err)
+	}
+	if strings.TrimRight(notes, " \t\r\n") == t.Notes {
+		fmt.Println("notes unchanged")
+		return
+	}
+
+	loaded := t.Notes
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		// the notes may have changed while the editor was open
+		if cur := task.Find(tasks, id); cur != nil && cur.Notes != loaded {
+			return nil, &task.ConflictError{}
+		}
+		return tasks, task.SetNotes(tasks, id, notes)
+	})
+	var conflict *task.ConflictError
+	if errors.As(err, &conflict) {
+		// don't lose the edit
+		fmt.Fprintf(os.Stderr, "%v\nyour notes for task %d were:\n%s\n", err, id, strings.TrimRight(notes, "\n"))
+		os.Exit(1)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("task %s notes updated\n", c.BoldCyan(strconv.Itoa(id)))
+}
+
+// editText opens text in the user's editor via a temp file and returns
+// the edited result. The temp file name follows pattern.
+func editText(text, pattern string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if text != "" {
+		text += "\n"
+	}
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := runEditor(f.Name()); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", fmt.Errorf("read temp file: %w", err)
+	}
+	return string(data), nil
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi.
+// The editor variable may include arguments, e.g. "code --wait"; one
+// that is blank counts as unset.
+func runEditor(path string) error {
+	args := strings.Fields(os.Getenv("VISUAL"))
+	if len(args) == 0 {
+		args = strings.Fields(os.Getenv("EDITOR"))
+	}
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run editor %s: %w", args[0], err)
+	}
+	return nil
+}
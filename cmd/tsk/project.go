@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/config"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+// cmdProject manages projects. The archived projects are kept in the
+// config file at configPath; cfg is the config tsk runs with, which
+// overrides may have changed.
+func cmdProject(ctx context.Context, store task.Store, configPath string, cfg config.Config, c color.Palette) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: tsk project <list|rename|archive|unarchive>")
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "list", "ls":
+		cmdProjectList(ctx, store, cfg.Projects.Archived, c)
+	case "rename":
+		cmdProjectRename(ctx, store, configPath, cfg, c)
+	case "archive":
+		cmdProjectArchive(ctx, store, configPath, cfg, c)
+	case "unarchive":
+		cmdProjectUnarchive(configPath, cfg, c)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown project command: %s\n", os.Args[2])
+		os.Exit(1)
+	}
+}
+
+func cmdProjectList(ctx context.Context, store task.Store, archived []string, c color.Palette) {
+	tasks, err := store.Load(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	projects := task.Projects(tasks)
+	if len(projects) == 0 {
+		fmt.Println("no projects")
+		return
+	}
+
+	width := len("(none)")
+	for _, p := range projects {
+		width = max(width, len(p.Name))
+	}
+
+	for _, p := range projects {
+		name := p.Name
+		if name == "" {
+			name = "(none)"
+		}
+		line := fmt.Sprintf("%-*s  %s pending, %s done",
+			width, name,
+			c.BoldCyan(strconv.Itoa(p.Pending)),
+			c.Green(strconv.Itoa(p.Done)))
+		if slices.Contains(archived, p.Name) {
+			line += " " + c.Dim("(archived)")
+		}
+		fmt.Println(line)
+	}
+}
+
+func cmdProjectRename(ctx context.Context, store task.Store, configPath string, cfg config.Config, c color.Palette) {
+	if len(os.Args) < 5 {
+		fmt.Fprintln(os.Stderr, "usage: tsk project rename <old> <new>")
+		os.Exit(1)
+	}
+	from, to := os.Args[3], os.Args[4]
+
+	var n int
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		var err error
+		n, err = task.RenameProject(tasks, from, to)
+		return tasks, err
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("renamed project %s to %s (%s %s)\n",
+		from, c.Bold(to), c.BoldCyan(strconv.Itoa(n)), pluralize(n, "task", "tasks"))
+
+	// an archived project stays archived under its new name
+	archived := archivedProjects(configPath)
+	if i := slices.Index(archived, from); i >= 0 {
+		archived = slices.Delete(archived, i, i+1)
+		if !slices.Contains(archived, to) {
+			archived = append(archived, to)
+			slices.Sort(archived)
+		}
+		saveArchived(configPath, cfg, archived)
+	}
+}
+
+func cmdProjectArchive(ctx context.Context, store task.Store, configPath string, cfg config.Config, c color.Palette) {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk project archive <name>")
+		os.Exit(1)
+	}
+	name := os.Args[3]
+
+	tasks, err := store.Load(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	archived, err := task.ArchiveProject(archivedProjects(configPath), tasks, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	saveArchived(configPath, cfg, archived)
+
+	n := len(task.List(tasks, task.InProject(name)))
+	fmt.Printf("archived project %s (%s %s)\n",
+		c.Bold(name), c.BoldCyan(strconv.Itoa(n)), pluralize(n, "task", "tasks"))
+}
+
+func cmdProjectUnarchive(configPath string, cfg config.Config, c color.Palette) {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk project unarchive <name>")
+		os.Exit(1)
+	}
+	name := os.Args[3]
+
+	archived, err := task.UnarchiveProject(archivedProjects(configPath), name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	saveArchived(configPath, cfg, archived)
+
+	fmt.Printf("unarchived project %s\n", c.Bold(name))
+}
+
+// archivedProjects returns the archived projects in the config file,
+// without the overrides.
+func archivedProjects(configPath string) []string {
+	cfg, err := config.LoadFrom(configPath)
+	if err != nil {
+		fatal(err)
+	}
+	return cfg.Projects.Archived
+}
+
+// saveArchived writes the archived projects to the config file.
+func saveArchived(configPath string, cfg config.Config, archived []string) {
+	const key = "projects.archived"
+	if err := config.SetList(configPath, key, archived); err != nil {
+		fatal(err)
+	}
+	if src := cfg.Source(key); src == config.SourceEnv || src == config.SourceFlag {
+		fmt.Fprintf(os.Stderr, "note: %s is overridden by %s, so the change to %s is not in effect\n", key, src, configPath)
+	}
+}
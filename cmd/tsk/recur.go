@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdRecur(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 3 || os.Args[2] == "list" || os.Args[2] == "ls" {
+		cmdRecurList(ctx, store, c)
+		return
+	}
+
+	if os.Args[2] != "stop" || len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "usage: tsk recur [list|stop <id>]")
+		os.Exit(1)
+	}
+
+	id, err := parseID(ctx, store, os.Args[3])
+	if err != nil {
+		fatal(err)
+	}
+
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		return tasks, task.StopRecur(tasks, id)
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("task %s no longer repeats\n", c.BoldCyan(strconv.Itoa(id)))
+}
+
+func cmdRecurList(ctx context.Context, store task.Store, c color.Palette) {
+	tasks, err := store.Load(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	recurring := task.List(tasks, task.FilterRecurring)
+	if len(recurring) == 0 {
+		fmt.Println("no recurring tasks")
+		return
+	}
+
+	now := time.Now()
+	for _, t := range recurring {
+		id := c.BoldCyan(fmt.Sprintf("%3d", t.ID))
+		rule := c.Dim(fmt.Sprintf("every %-10s", t.Recur))
+		next := ""
+		if t.Due != nil {
+			next = "  " + colorDue(c, *t.Due, now, dueLabel(*t.Due, now))
+		}
+		fmt.Printf("%s  %s %s%s\n", id, rule, t.Title, next)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdSync(ctx context.Context, gist *task.GistStore, repo *task.GitStore, c color.Palette) {
+	if gist == nil && repo == nil {
+		fmt.Fprintln(os.Stderr, "sync requires gist or git storage (set storage.type in config)")
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 2 {
+		if os.Args[2] != "--status" && os.Args[2] != "-s" {
+			fmt.Fprintln(os.Stderr, "usage: tsk sync [--status]")
+			os.Exit(1)
+		}
+		if repo != nil {
+			cmdGitStatus(ctx, repo, c)
+		} else {
+			cmdSyncStatus(ctx, gist, c)
+		}
+		return
+	}
+
+	var err error
+	if repo != nil {
+		err = repo.Sync(ctx)
+	} else {
+		err = gist.Sync(ctx)
+	}
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(c.Green("synced"))
+}
+
+func cmdSyncStatus(ctx context.Context, gist *task.GistStore, c color.Palette) {
+	st, err := gist.Status(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	id := st.GistID
+	if id == "" {
+		id = c.Dim("(not created yet)")
+	}
+	fmt.Printf("%s  %s\n", c.Dim("gist:    "), id)
+
+	if st.Synced.IsZero() {
+		fmt.Printf("%s  %s\n", c.Dim("synced:  "), "never")
+	} else {
+		fmt.Printf("%s  %s (%s)\n", c.Dim("synced:  "), st.Synced.Local().Format("2006-01-02 15:04"), age(st.Synced))
+	}
+
+	switch {
+	case st.Ahead > 0:
+		fmt.Printf("%s  %s %s not pushed\n", c.Dim("local:   "),
+			c.Yellow(strconv.Itoa(st.Ahead)), pluralize(st.Ahead, "task change", "task changes"))
+	case st.Pending:
+		fmt.Printf("%s  %s\n", c.Dim("local:   "), c.Yellow("changes queued"))
+	default:
+		fmt.Printf("%s  %s\n", c.Dim("local:   "), "up to date")
+	}
+
+	switch {
+	case !st.Online:
+		fmt.Printf("%s  %s\n", c.Dim("remote:  "), c.Red("unreachable"))
+	case st.Behind > 0:
+		fmt.Printf("%s  %s %s not pulled\n", c.Dim("remote:  "),
+			c.Yellow(strconv.Itoa(st.Behind)), pluralize(st.Behind, "task change", "task changes"))
+	default:
+		fmt.Printf("%s  %s\n", c.Dim("remote:  "), "up to date")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+func cmdStart(ctx context.Context, store task.Store, c color.Palette) {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "usage: tsk start <id>")
+		os.Exit(1)
+	}
+
+	id, err := parseID(ctx, store, os.Args[2])
+	if err != nil {
+		fatal(err)
+	}
+
+	var stopped int
+	var title string
+	err = store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		var err error
+		stopped, err = task.Start(tasks, id, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		title = task.Find(tasks, id).Title
+		return tasks, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	if stopped != 0 {
+		fmt.Printf("stopped task %s\n", c.BoldCyan(strconv.Itoa(stopped)))
+	}
+	fmt.Printf("started task %s: %s\n", c.BoldCyan(strconv.Itoa(id)), title)
+}
+
+func cmdStop(ctx context.Context, store task.Store, c color.Palette) {
+	var id int
+	var d, total time.Duration
+	err := store.Update(ctx, func(tasks []task.Task) ([]task.Task, error) {
+		now := time.Now()
+		var err error
+		id, d, err = task.Stop(tasks, now)
+		if err != nil {
+			return nil, err
+		}
+		total = task.Tracked(*task.Find(tasks, id), now)
+		return tasks, nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("stopped task %s after %s (total %s)\n",
+		c.BoldCyan(strconv.Itoa(id)), formatDuration(d), formatDuration(total))
+
+}
+
+func cmdTimesheet(ctx context.Context, store task.Store, c color.Palette) {
+	now := time.Now()
+	sinceArg := "monday"
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "usage: tsk timesheet [--since <date>]")
+				os.Exit(1)
+			}
+			i++
+			sinceArg = args[i]
+		default:
+			fmt.Fprintf(os.Stderr, "unknown flag: %s\n", args[i])
+			os.Exit(1)
+		}
+	}
+
+	since, err := task.ParseSince(sinceArg, now)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	tasks, err := store.Load(ctx)
+	if err != nil {
+		fatal(err)
+	}
+
+	entries := task.Timesheet(tasks, since, now)
+	if len(entries) == 0 {
+		fmt.Printf("no time tracked since %s\n", since.Format("2006-01-02"))
+		return
+	}
+
+	// per-day sections
+	var total time.Duration
+	byTask := make(map[int]time.Duration)
+	var order []int
+	for i := 0; i < len(entries); {
+		day := entries[i].Day
+		j := i
+		var dayTotal time.Duration
+		for ; j < len(entries) && entries[j].Day.Equal(day); j++ {
+			dayTotal += entries[j].Duration
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s %8s\n", c.Bold(fmt.Sprintf("%-40s", day.Format("Mon 2006-01-02"))), formatDuration(dayTotal))
+		for _, e := range entries[i:j] {
+			fmt.Printf("  %s  %-33s %8s\n", c.BoldCyan(fmt.Sprintf("%3d", e.TaskID)), e.Title, formatDuration(e.Duration))
+			if _, ok := byTask[e.TaskID]; !ok {
+				order = append(order, e.TaskID)
+			}
+			byTask[e.TaskID] += e.Duration
+		}
+		total += dayTotal
+		i = j
+	}
+
+	// per-task totals
+	fmt.Printf("\n%s\n", c.Bold("by task"))
+	for _, id := range order {
+		title := ""
+		for _, e := range entries {
+			if e.TaskID == id {
+				title = e.Title
+				break
+			}
+		}
+		fmt.Printf("  %s  %-33s %8s\n", c.BoldCyan(fmt.Sprintf("%3d", id)), title, formatDuration(byTask[id]))
+	}
+	fmt.Printf("\n%s %8s\n", c.Bold(fmt.Sprintf("%-40s", "total")), formatDuration(total))
+}
+
+// formatDuration renders d as hours and minutes, e.g. "1h 05m" or "25m".
+func formatDuration(d time.Duration) string {
+	m := int(d.Round(time.Minute).Minutes())
+	if m < 60 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %02dm", m/60, m%60)
+}
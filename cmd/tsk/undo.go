@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/zarldev/tsk/internal/color"
+	"github.com/zarldev/tsk/internal/task"
+)
+
+// undoListSize is how many operations undo --list shows.
+const undoListSize = 10
+
+func cmdUndo(ctx context.Context, store task.Store, journal *task.Journal, c color.Palette) {
+	if len(os.Args) > 2 {
+		if os.Args[2] != "--list" && os.Args[2] != "-l" {
+			fmt.Fprintln(os.Stderr, "usage: tsk undo [--list]")
+			os.Exit(1)
+		}
+		cmdUndoList(journal, c)
+		return
+	}
+
+	op, err := journal.Undo(ctx, store)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("undone: %s\n", c.Bold(op.Command))
+}
+
+func cmdRedo(ctx context.Context, store task.Store, journal *task.Journal, c color.Palette) {
+	op, err := journal.Redo(ctx, store)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("redone: %s\n", c.Bold(op.Command))
+}
+
+func cmdUndoList(journal *task.Journal, c color.Palette) {
+	ops, applied, err := journal.Recent(undoListSize)
+	if err != nil {
+		fatal(err)
+	}
+	if len(ops) == 0 {
+		fmt.Println("no recorded changes")
+		return
+	}
+
+	// newest first; undone operations are dimmed and marked for redo
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		when := op.Time.Format("2006-01-02 15:04")
+		if i >= applied {
+			fmt.Printf("  %s  %s  %s\n", c.Dim(when), c.Dim(op.Command), c.Dim("(undone)"))
+			continue
+		}
+		marker := " "
+		if i == applied-1 {
+			marker = c.Cyan(">")
+		}
+		fmt.Printf("%s %s  %s\n", marker, c.Dim(when), op.Command)
+	}
+}
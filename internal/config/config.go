@@ -1,18 +1,25 @@
 package config
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 )
 
 // Config holds all tsk configuration.
 type Config struct {
-	Color   ColorConfig
-	Storage StorageConfig
+	Color    ColorConfig
+	Storage  StorageConfig
+	Projects ProjectsConfig
+
+	sources map[string]Source // keys not set by default
 }
 
 // ColorConfig controls colored output behavior.
@@ -22,36 +29,57 @@ type ColorConfig struct {
 
 // StorageConfig controls task storage.
 type StorageConfig struct {
-	Type      string // "file", "gist"
+	Type      string // "file", "gist", "git", "events", "indexed"
 	Path      string // file path for "file" type
 	GistToken string // GitHub PAT with gist scope
 	GistID    string // gist ID (created on first save if empty)
+
+	GistTimeout time.Duration // per-request limit for the Gist API; 0 = none
+
+	GitDir    string // repository directory for "git" type
+	GitRemote string // remote URL `tsk sync` pulls from and pushes to
+
+	EventsPath  string // event log path for "events" type
+	IndexedPath string // database path for "indexed" type
+
+	PassphraseCmd string // command printing the encryption passphrase
+}
+
+// ProjectsConfig holds settings about projects.
+type ProjectsConfig struct {
+	Archived []string // projects hidden from tsk list
 }
 
 // DefaultConfig returns configuration with sensible defaults.
 func DefaultConfig() Config {
-	home, err := os.UserHomeDir()
+	data, err := DataDir()
 	if err != nil {
-		home = "~"
+		// no home directory to put it in: use the same directory under
+		// the working directory, as a literal ~ is never expanded
+		data = filepath.Join(".local", "share", "tsk")
 	}
 	return Config{
 		Color: ColorConfig{
 			Enabled: "auto",
 		},
 		Storage: StorageConfig{
-			Type: "file",
-			Path: filepath.Join(home, ".tasks.json"),
+			Type:        "file",
+			Path:        filepath.Join(data, "tasks.json"),
+			GistTimeout: 30 * time.Second,
+			GitDir:      filepath.Join(data, "repo"),
+			EventsPath:  filepath.Join(data, "events.jsonl"),
+			IndexedPath: filepath.Join(data, "tasks.db"),
 		},
 	}
 }
 
-// Path returns the config file path (~/.config/tsk/config.toml).
+// Path returns the config file path: $TSK_CONFIG if set, otherwise
+// config.toml in ConfigDir (~/.config/tsk/config.toml).
 func Path() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("user home dir: %w", err)
+	if p := os.Getenv("TSK_CONFIG"); p != "" {
+		return expandHome(p), nil
 	}
-	return filepath.Join(home, ".config", "tsk", "config.toml"), nil
+	return inDir(ConfigDir, "config.toml")
 }
 
 // Load reads config from the standard config file path.
@@ -70,167 +98,320 @@ func Load() (Config, error) {
 // LoadFrom reads config from the given path.
 // Missing file or missing values fall back to defaults.
 func LoadFrom(path string) (Config, error) {
-	cfg := DefaultConfig()
-
-	f, err := os.Open(path)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return cfg, nil
+			return DefaultConfig(), nil
 		}
-		return cfg, fmt.Errorf("open config: %w", err)
+		return DefaultConfig(), fmt.Errorf("open config: %w", err)
 	}
-	defer f.Close()
 
-	sections, err := parseTOML(f)
+	cfg, err := parse(data)
 	if err != nil {
 		return cfg, fmt.Errorf("parse config: %w", err)
 	}
+	return cfg, nil
+}
 
-	if color, ok := sections["color"]; ok {
-		if v, ok := color["enabled"]; ok {
-			cfg.Color.Enabled = v
-		}
+// parse reads config from the contents of a config file.
+func parse(data []byte) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, _ = quoteLegacy(data)
+	doc, err := decodeTOML(data)
+	if err != nil {
+		return cfg, err
 	}
 
-	if storage, ok := sections["storage"]; ok {
-		if v, ok := storage["type"]; ok {
-			cfg.Storage.Type = v
-		}
-		if v, ok := storage["path"]; ok {
-			cfg.Storage.Path = expandHome(v)
+	for _, s := range settings {
+		section, name, _ := strings.Cut(s.key, ".")
+		v, ok, err := lookup(doc, section, name)
+		if err != nil {
+			return cfg, err
 		}
-		if v, ok := storage["gist_token"]; ok {
-			cfg.Storage.GistToken = v
+		if !ok {
+			continue
 		}
-		if v, ok := storage["gist_id"]; ok {
-			cfg.Storage.GistID = v
+		if err := s.set(&cfg, v); err != nil {
+			return cfg, fmt.Errorf("%s: %w", s.key, err)
 		}
+		cfg.setSource(s.key, SourceFile)
 	}
-
 	return cfg, nil
 }
 
-// String returns the config in TOML format.
-func (c Config) String() string {
-	var b strings.Builder
-	b.WriteString("# tsk configuration\n\n")
-	b.WriteString("[color]\n")
-	fmt.Fprintf(&b, "enabled = %q\n", c.Color.Enabled)
-	b.WriteString("\n[storage]\n")
-	fmt.Fprintf(&b, "type = %q\n", c.Storage.Type)
-	fmt.Fprintf(&b, "path = %q\n", c.Storage.Path)
-	fmt.Fprintf(&b, "gist_token = %q\n", c.Storage.GistToken)
-	fmt.Fprintf(&b, "gist_id = %q\n", c.Storage.GistID)
-	return b.String()
+// legacyValue is an unquoted string quoted by quoteLegacy.
+type legacyValue struct {
+	offset int // where the quoted value starts
+	value  string
 }
 
-// expandHome replaces a leading ~ with the user's home directory.
-func expandHome(path string) string {
-	if !strings.HasPrefix(path, "~") {
-		return path
+// quoteLegacy quotes the unquoted strings that versions of tsk before
+// TOML support accepted, such as type = file, so that old config files
+// keep working. As then, the value runs to the end of the line or to a
+// #. It returns the changed document and the values it quoted.
+func quoteLegacy(data []byte) ([]byte, []legacyValue) {
+	var quoted []legacyValue
+	for {
+		_, err := decodeTOML(data)
+		var perr *ParseError
+		if !errors.As(err, &perr) || !perr.unquoted {
+			return data, quoted
+		}
+		start := perr.offset
+		end, _ := endOfLine(data, start)
+		v := data[start:end]
+		if i := bytes.IndexByte(v, '#'); i >= 0 {
+			v = v[:i]
+		}
+		v = bytes.TrimRightFunc(v, unicode.IsSpace)
+		quoted = append(quoted, legacyValue{start, string(v)})
+		data = splice(data, start, start+len(v), encodeString(string(v)))
 	}
-	home, err := os.UserHomeDir()
+}
+
+// lookup returns the value of name in the given section of doc, and
+// whether it is set. See settingValue for the types it returns.
+func lookup(doc map[string]any, section, name string) (any, bool, error) {
+	v, ok := doc[section]
+	if !ok {
+		return nil, false, nil
+	}
+	tbl, ok := v.(map[string]any)
+	if !ok {
+		return nil, false, fmt.Errorf("%s: expected a table, got %s", section, typeName(v))
+	}
+	if tbl[name] == nil {
+		return nil, false, nil
+	}
+	sv, err := settingValue(tbl[name])
 	if err != nil {
-		return path
+		return nil, false, fmt.Errorf("%s.%s: %w", section, name, err)
 	}
-	return filepath.Join(home, path[1:])
+	return sv, true, nil
 }
 
-// parseTOML is a minimal TOML parser supporting top-level sections
-// with string, boolean, and integer values. Comments start with #.
-func parseTOML(f *os.File) (map[string]map[string]string, error) {
-	sections := make(map[string]map[string]string)
-	currentSection := ""
-
-	scanner := bufio.NewScanner(f)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-
-		// skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// section header
-		if strings.HasPrefix(line, "[") {
-			if !strings.HasSuffix(line, "]") {
-				return nil, fmt.Errorf("line %d: unclosed section header", lineNum)
-			}
-			currentSection = strings.TrimSpace(line[1 : len(line)-1])
-			if _, ok := sections[currentSection]; !ok {
-				sections[currentSection] = make(map[string]string)
+// settingValue converts a decoded TOML value to the value of a setting:
+// a string, or a []string for an array of strings. Booleans are
+// accepted as their text, so `enabled = true` works like "true".
+func settingValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		list := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected an array of strings, got %s in the array", typeName(e))
 			}
-			continue
+			list[i] = s
 		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("expected a string, got %s", typeName(v))
+	}
+}
 
-		// key = value
-		eqIdx := strings.IndexByte(line, '=')
-		if eqIdx < 0 {
-			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+// setting is one key of the config file and the Config field it sets.
+// Its value is a string, or for a list setting a []string, written in
+// TOML as an array.
+type setting struct {
+	key  string // section.name
+	list bool
+	get  func(*Config) any
+	set  func(*Config, any) error
+}
+
+// parse converts text from the command line or the environment to a
+// value for s. A list setting takes a TOML array, such as ["a", "b"].
+func (s setting) parse(text string) (any, error) {
+	if !s.list {
+		return text, nil
+	}
+	doc, err := decodeTOML([]byte("v = " + text))
+	if err == nil {
+		if v, err := settingValue(doc["v"]); err == nil {
+			if list, ok := v.([]string); ok {
+				return list, nil
+			}
 		}
+	}
+	return nil, fmt.Errorf("%q is not an array of strings, such as [\"a\", \"b\"]", text)
+}
 
-		key := strings.TrimSpace(line[:eqIdx])
-		valRaw := strings.TrimSpace(line[eqIdx+1:])
+// encodeValue returns a setting value in TOML.
+func encodeValue(v any) string {
+	list, ok := v.([]string)
+	if !ok {
+		return encodeString(v.(string))
+	}
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = encodeString(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
 
-		// strip inline comment (only outside quotes)
-		val, err := parseValue(valRaw)
-		if err != nil {
-			return nil, fmt.Errorf("line %d: %w", lineNum, err)
-		}
+// text returns a setting value as Get does: a string as it is, and a
+// list in TOML.
+func text(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return encodeValue(v)
+}
 
-		if currentSection == "" {
-			// top-level keys go into an empty-string section
-			if _, ok := sections[""]; !ok {
-				sections[""] = make(map[string]string)
+// settings lists every config key, in the order String writes them.
+var settings = []setting{
+	stringSetting("color.enabled", func(c *Config) *string { return &c.Color.Enabled }),
+	stringSetting("storage.type", func(c *Config) *string { return &c.Storage.Type }),
+	pathSetting("storage.path", func(c *Config) *string { return &c.Storage.Path }),
+	stringSetting("storage.gist_token", func(c *Config) *string { return &c.Storage.GistToken }),
+	stringSetting("storage.gist_id", func(c *Config) *string { return &c.Storage.GistID }),
+	{
+		key: "storage.gist_timeout",
+		get: func(c *Config) any { return c.Storage.GistTimeout.String() },
+		set: func(c *Config, v any) error {
+			s, err := asString(v)
+			if err != nil {
+				return err
 			}
-			sections[""][key] = val
-			continue
-		}
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid duration %q (use e.g. \"30s\" or \"2m\")", s)
+			}
+			c.Storage.GistTimeout = d
+			return nil
+		},
+	},
+	pathSetting("storage.git_dir", func(c *Config) *string { return &c.Storage.GitDir }),
+	stringSetting("storage.git_remote", func(c *Config) *string { return &c.Storage.GitRemote }),
+	pathSetting("storage.events_path", func(c *Config) *string { return &c.Storage.EventsPath }),
+	pathSetting("storage.indexed_path", func(c *Config) *string { return &c.Storage.IndexedPath }),
+	stringSetting("storage.passphrase_cmd", func(c *Config) *string { return &c.Storage.PassphraseCmd }),
+	listSetting("projects.archived", func(c *Config) *[]string { return &c.Projects.Archived }),
+}
 
-		sections[currentSection][key] = val
+func stringSetting(key string, field func(*Config) *string) setting {
+	return setting{
+		key: key,
+		get: func(c *Config) any { return *field(c) },
+		set: func(c *Config, v any) error {
+			s, err := asString(v)
+			if err != nil {
+				return err
+			}
+			*field(c) = s
+			return nil
+		},
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("read config: %w", err)
+// pathSetting is a stringSetting that expands a leading ~.
+func pathSetting(key string, field func(*Config) *string) setting {
+	s := stringSetting(key, field)
+	set := s.set
+	s.set = func(c *Config, v any) error {
+		if err := set(c, v); err != nil {
+			return err
+		}
+		*field(c) = expandHome(*field(c))
+		return nil
 	}
+	return s
+}
 
-	return sections, nil
+// listSetting is a setting holding an array of strings.
+func listSetting(key string, field func(*Config) *[]string) setting {
+	return setting{
+		key:  key,
+		list: true,
+		get:  func(c *Config) any { return slices.Clone(*field(c)) },
+		set: func(c *Config, v any) error {
+			list, ok := v.([]string)
+			if !ok {
+				return errors.New("expected an array of strings, such as [\"a\", \"b\"]")
+			}
+			*field(c) = slices.Clone(list)
+			return nil
+		},
+	}
 }
 
-// parseValue extracts a value from a TOML value string.
-// Handles quoted strings, booleans, and integers.
-func parseValue(raw string) (string, error) {
-	if raw == "" {
-		return "", nil
+// asString returns v if it is a string value.
+func asString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.New("expected a string, got an array")
 	}
+	return s, nil
+}
 
-	// quoted string
-	if raw[0] == '"' {
-		// find closing quote, respecting that inline comments may follow
-		endQuote := strings.IndexByte(raw[1:], '"')
-		if endQuote < 0 {
-			return "", fmt.Errorf("unclosed quote")
+func findSetting(key string) (setting, error) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, nil
 		}
-		return raw[1 : endQuote+1], nil
 	}
+	return setting{}, fmt.Errorf("unknown config key %q", key)
+}
 
-	// unquoted value: strip inline comment
-	if idx := strings.IndexByte(raw, '#'); idx >= 0 {
-		raw = strings.TrimSpace(raw[:idx])
+// Keys returns every config key, as section.name.
+func Keys() []string {
+	keys := make([]string, len(settings))
+	for i, s := range settings {
+		keys[i] = s.key
 	}
+	return keys
+}
 
-	// boolean
-	if raw == "true" || raw == "false" {
-		return raw, nil
+// Get returns the value of a config key, such as "storage.type". The
+// value of a list setting is a TOML array, such as ["a", "b"].
+func (c Config) Get(key string) (string, error) {
+	s, err := findSetting(key)
+	if err != nil {
+		return "", err
 	}
+	return text(s.get(&c)), nil
+}
+
+// String returns the config in TOML format.
+func (c Config) String() string {
+	return c.format(nil)
+}
 
-	// integer
-	if _, err := strconv.Atoi(raw); err == nil {
-		return raw, nil
+// format writes the config in TOML format, followed on each line by
+// the comment returned by note, if any.
+func (c Config) format(note func(key string) string) string {
+	var b strings.Builder
+	b.WriteString("# tsk configuration\n")
+	current := ""
+	for _, s := range settings {
+		section, name, _ := strings.Cut(s.key, ".")
+		if section != current {
+			fmt.Fprintf(&b, "\n[%s]\n", section)
+			current = section
+		}
+		fmt.Fprintf(&b, "%s = %s", name, encodeValue(s.get(&c)))
+		if note != nil {
+			fmt.Fprintf(&b, "  # %s", note(s.key))
+		}
+		b.WriteString("\n")
 	}
+	return b.String()
+}
 
-	// unquoted string
-	return raw, nil
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
 }
@@ -3,8 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func writeConfig(t *testing.T, content string) string {
@@ -17,6 +19,7 @@ func writeConfig(t *testing.T, content string) string {
 }
 
 func TestDefaultConfig(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "")
 	cfg := DefaultConfig()
 
 	if cfg.Color.Enabled != "auto" {
@@ -25,8 +28,21 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Storage.Type != "file" {
 		t.Errorf("Storage.Type = %q, want %q", cfg.Storage.Type, "file")
 	}
-	if !strings.HasSuffix(cfg.Storage.Path, ".tasks.json") {
-		t.Errorf("Storage.Path = %q, want suffix %q", cfg.Storage.Path, ".tasks.json")
+	if want := filepath.Join(".local", "share", "tsk", "tasks.json"); !strings.HasSuffix(cfg.Storage.Path, want) {
+		t.Errorf("Storage.Path = %q, want suffix %q", cfg.Storage.Path, want)
+	}
+}
+
+func TestDefaultConfigWithoutHome(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	if _, err := os.UserHomeDir(); err == nil {
+		t.Skip("home directory found without $HOME")
+	}
+
+	want := filepath.Join(".local", "share", "tsk", "tasks.json")
+	if got := DefaultConfig().Storage.Path; got != want {
+		t.Errorf("Storage.Path = %q, want %q", got, want)
 	}
 }
 
@@ -182,6 +198,75 @@ type = file
 	}
 }
 
+func TestLoadUnquotedNumberLikeValues(t *testing.T) {
+	content := `[color]
+enabled = never
+
+[storage]
+type = indexed
+gist_id = 8f3a9c0d1e
+gist_timeout = 30s
+`
+	p := writeConfig(t, content)
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Color.Enabled != "never" {
+		t.Errorf("Color.Enabled = %q, want %q", cfg.Color.Enabled, "never")
+	}
+	if cfg.Storage.Type != "indexed" {
+		t.Errorf("Storage.Type = %q, want %q", cfg.Storage.Type, "indexed")
+	}
+	if cfg.Storage.GistID != "8f3a9c0d1e" {
+		t.Errorf("Storage.GistID = %q, want %q", cfg.Storage.GistID, "8f3a9c0d1e")
+	}
+	if cfg.Storage.GistTimeout != 30*time.Second {
+		t.Errorf("Storage.GistTimeout = %v, want 30s", cfg.Storage.GistTimeout)
+	}
+}
+
+func TestQuoteLegacy(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"valid TOML", "type = \"file\"\n", "type = \"file\"\n"},
+		{"word", "type = file\n", "type = \"file\"\n"},
+		{"words and comment", "passphrase_cmd = pass show tsk  # secret\n", "passphrase_cmd = \"pass show tsk\"  # secret\n"},
+		{"path, last line", "path = ~/tasks.json", "path = \"~/tasks.json\""},
+		{"several", "[storage]\ntype = gist\ngist_id = abc123\r\n", "[storage]\ntype = \"gist\"\ngist_id = \"abc123\"\r\n"},
+		{"starts like inf", "type = indexed\n", "type = \"indexed\"\n"},
+		{"starts like nan", "enabled = never\n", "enabled = \"never\"\n"},
+		{"starts like a number", "gist_timeout = 30s\n", "gist_timeout = \"30s\"\n"},
+		{"hex starting with a digit", "gist_id = 8f3a9c0d1e\n", "gist_id = \"8f3a9c0d1e\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := quoteLegacy([]byte(tt.src))
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadWrongType(t *testing.T) {
+	content := `[storage]
+gist_id = 42
+`
+	p := writeConfig(t, content)
+	_, err := LoadFrom(p)
+	if err == nil {
+		t.Fatal("expected error for integer gist_id")
+	}
+	if !strings.Contains(err.Error(), "storage.gist_id: expected a string, got an integer") {
+		t.Errorf("error = %q, want storage.gist_id type error", err.Error())
+	}
+}
+
 func TestExpandHome(t *testing.T) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -359,6 +444,8 @@ enabled = "always"
 }
 
 func TestPath(t *testing.T) {
+	t.Setenv("TSK_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
 	p, err := Path()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -466,3 +553,136 @@ func TestDefaultConfigGistFieldsEmpty(t *testing.T) {
 		t.Errorf("GistID = %q, want empty", cfg.Storage.GistID)
 	}
 }
+
+func TestLoadGistTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"seconds", `"10s"`, 10 * time.Second, false},
+		{"minutes", `"2m"`, 2 * time.Minute, false},
+		{"disabled", `"0s"`, 0, false},
+		{"no unit", `"30"`, 0, true},
+		{"negative", `"-5s"`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeConfig(t, "[storage]\ngist_timeout = "+tt.value+"\n")
+			cfg, err := LoadFrom(p)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Storage.GistTimeout != tt.want {
+				t.Errorf("GistTimeout = %v, want %v", cfg.Storage.GistTimeout, tt.want)
+			}
+		})
+	}
+
+	if d := DefaultConfig().Storage.GistTimeout; d != 30*time.Second {
+		t.Errorf("default GistTimeout = %v, want 30s", d)
+	}
+}
+
+func TestLoadGitConfig(t *testing.T) {
+	content := `[storage]
+type = "git"
+git_dir = "/srv/tasks"
+git_remote = "git@example.com:me/tasks.git"
+`
+	p := writeConfig(t, content)
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Storage.Type != "git" {
+		t.Errorf("Storage.Type = %q, want %q", cfg.Storage.Type, "git")
+	}
+	if cfg.Storage.GitDir != "/srv/tasks" {
+		t.Errorf("Storage.GitDir = %q, want %q", cfg.Storage.GitDir, "/srv/tasks")
+	}
+	if cfg.Storage.GitRemote != "git@example.com:me/tasks.git" {
+		t.Errorf("Storage.GitRemote = %q, want %q", cfg.Storage.GitRemote, "git@example.com:me/tasks.git")
+	}
+
+	// String() output loads back to the same values
+	loaded, err := LoadFrom(writeConfig(t, cfg.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loaded.Storage != cfg.Storage {
+		t.Errorf("round trip = %+v, want %+v", loaded.Storage, cfg.Storage)
+	}
+}
+
+func TestLoadEventsPath(t *testing.T) {
+	p := writeConfig(t, "[storage]\ntype = \"events\"\nevents_path = \"~/tasks.jsonl\"\n")
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, "tasks.jsonl"); cfg.Storage.EventsPath != want {
+		t.Errorf("Storage.EventsPath = %q, want %q", cfg.Storage.EventsPath, want)
+	}
+	if !strings.HasSuffix(DefaultConfig().Storage.EventsPath, filepath.Join("tsk", "events.jsonl")) {
+		t.Errorf("default EventsPath = %q", DefaultConfig().Storage.EventsPath)
+	}
+}
+
+func TestLoadIndexedPath(t *testing.T) {
+	p := writeConfig(t, "[storage]\ntype = \"indexed\"\nindexed_path = \"~/tasks.db\"\n")
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, "tasks.db"); cfg.Storage.IndexedPath != want {
+		t.Errorf("Storage.IndexedPath = %q, want %q", cfg.Storage.IndexedPath, want)
+	}
+	if !strings.HasSuffix(DefaultConfig().Storage.IndexedPath, filepath.Join("tsk", "tasks.db")) {
+		t.Errorf("default IndexedPath = %q", DefaultConfig().Storage.IndexedPath)
+	}
+}
+
+func TestLoadPassphraseCmd(t *testing.T) {
+	p := writeConfig(t, "[storage]\npassphrase_cmd = \"pass show tsk\"  # from the password store\n")
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.PassphraseCmd != "pass show tsk" {
+		t.Errorf("Storage.PassphraseCmd = %q, want %q", cfg.Storage.PassphraseCmd, "pass show tsk")
+	}
+	if DefaultConfig().Storage.PassphraseCmd != "" {
+		t.Errorf("default PassphraseCmd = %q, want empty", DefaultConfig().Storage.PassphraseCmd)
+	}
+}
+
+func TestLoadArchivedProjects(t *testing.T) {
+	p := writeConfig(t, "[projects]\narchived = [\"release-1.3\", \"old\"]\n")
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"release-1.3", "old"}; !slices.Equal(cfg.Projects.Archived, want) {
+		t.Errorf("Projects.Archived = %q, want %q", cfg.Projects.Archived, want)
+	}
+	if v, _ := cfg.Get("projects.archived"); v != `["release-1.3", "old"]` {
+		t.Errorf("Get(projects.archived) = %q", v)
+	}
+	if DefaultConfig().Projects.Archived != nil {
+		t.Errorf("default Archived = %q, want none", DefaultConfig().Projects.Archived)
+	}
+}
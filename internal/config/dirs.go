@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// tsk keeps its files in the XDG base directories
+// (https://specifications.freedesktop.org/basedir-spec/latest/):
+//
+//	config  $XDG_CONFIG_HOME/tsk  ~/.config/tsk       config.toml
+//	data    $XDG_DATA_HOME/tsk    ~/.local/share/tsk  the tasks
+//	state   $XDG_STATE_HOME/tsk   ~/.local/state/tsk  undo journal, conflicts
+//	cache   $XDG_CACHE_HOME/tsk   ~/.cache/tsk        gist cache
+
+// ConfigDir returns the directory of the config file.
+func ConfigDir() (string, error) { return xdgDir("XDG_CONFIG_HOME", ".config") }
+
+// DataDir returns the directory tasks are stored in by default.
+func DataDir() (string, error) { return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")) }
+
+// StateDir returns the directory of the undo journal and conflicts.
+func StateDir() (string, error) { return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")) }
+
+// CacheDir returns the directory of the gist cache.
+func CacheDir() (string, error) { return xdgDir("XDG_CACHE_HOME", ".cache") }
+
+// xdgDir returns tsk's directory under $env, or under ~/fallback if env
+// is unset. As the spec asks, a relative $env is ignored.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "tsk"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("user home dir: %w", err)
+	}
+	return filepath.Join(home, fallback, "tsk"), nil
+}
+
+// JournalPath returns the undo journal path.
+func JournalPath() (string, error) { return inDir(StateDir, "journal.json") }
+
+// ConflictsPath returns the path of unresolved merge conflicts.
+func ConflictsPath() (string, error) { return inDir(StateDir, "conflicts.json") }
+
+// GistCachePath returns the gist cache path. Besides the copy of the
+// gist it holds changes made offline until they are synced.
+func GistCachePath() (string, error) { return inDir(CacheDir, "gist.json") }
+
+func inDir(dir func() (string, error), name string) (string, error) {
+	d, err := dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d, name), nil
+}
+
+// Move is a file moved by Migrate.
+type Move struct {
+	From, To string
+}
+
+// Migrate moves the files older versions of tsk kept in the home
+// directory to their place in the XDG directories. A file is only moved
+// if it is still in the home directory and nothing is at its new path
+// yet, so this happens once. Tasks are only moved for the storage type
+// cfg uses, and only if cfg keeps them at the default path. A file that
+// cannot be moved is left where it is and reported in the error, after
+// the others have been moved.
+func Migrate(cfg Config) ([]Move, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		// without a home there is nothing to move
+		return nil, nil
+	}
+
+	var moves []Move
+	add := func(name, to string) {
+		moves = append(moves, Move{filepath.Join(home, name), to})
+	}
+	def, s := DefaultConfig().Storage, cfg.Storage
+	switch {
+	case s.Type == "file" && s.Path == def.Path:
+		add(".tasks.json", def.Path)
+	case s.Type == "git" && s.GitDir == def.GitDir:
+		add(".tasks", def.GitDir)
+	case s.Type == "events" && s.EventsPath == def.EventsPath:
+		add(".tasks.events.jsonl", def.EventsPath)
+	case s.Type == "indexed" && s.IndexedPath == def.IndexedPath:
+		add(".tasks.db", def.IndexedPath)
+	}
+	for _, f := range []struct {
+		name string
+		path func() (string, error)
+	}{
+		{".tasks.journal.json", JournalPath},
+		{".tasks.conflicts.json", ConflictsPath},
+		{".tasks.gist-cache.json", GistCachePath},
+	} {
+		p, err := f.path()
+		if err != nil {
+			return nil, err
+		}
+		add(f.name, p)
+	}
+
+	var moved []Move
+	var errs []error
+	for _, m := range moves {
+		if _, err := os.Lstat(m.From); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(m.To); !errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := move(m.From, m.To); err != nil {
+			errs = append(errs, fmt.Errorf("move %s to %s: %w", m.From, m.To, err))
+			continue
+		}
+		// the lock file is empty; a new one is made next to the new path
+		os.Remove(m.From + ".lock")
+		moved = append(moved, m)
+	}
+	return moved, errors.Join(errs...)
+}
+
+// rename is os.Rename, replaced in tests.
+var rename = os.Rename
+
+// move renames from to to, creating the directory of to. If the two
+// are on different filesystems, from is copied and then removed.
+func move(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	err := rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyAll(from, to); err != nil {
+		// leave no half copy behind, so the next run tries again
+		os.RemoveAll(to)
+		return err
+	}
+	return os.RemoveAll(from)
+}
+
+// copyAll copies the file or directory tree from to to, keeping
+// permissions and symbolic links.
+func copyAll(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(to, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.Mkdir(dst, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, dst)
+		default:
+			return copyFile(path, dst, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(from, to string, perm fs.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// setHome points the home and XDG directories at a temp dir.
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "TSK_CONFIG"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+func TestDirs(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		dir  func() (string, error)
+		home string // default, relative to home
+	}{
+		{"config", "XDG_CONFIG_HOME", ConfigDir, ".config/tsk"},
+		{"data", "XDG_DATA_HOME", DataDir, ".local/share/tsk"},
+		{"state", "XDG_STATE_HOME", StateDir, ".local/state/tsk"},
+		{"cache", "XDG_CACHE_HOME", CacheDir, ".cache/tsk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := setHome(t)
+			for _, c := range []struct {
+				env, want string
+			}{
+				{"", filepath.Join(home, tt.home)},
+				{"/xdg", "/xdg/tsk"},
+				{"relative", filepath.Join(home, tt.home)},
+			} {
+				t.Setenv(tt.env, c.env)
+				got, err := tt.dir()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != c.want {
+					t.Errorf("%s=%q: dir = %q, want %q", tt.env, c.env, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPathFromEnv(t *testing.T) {
+	home := setHome(t)
+	tests := []struct {
+		tskConfig, xdg string
+		want           string
+	}{
+		{"", "", filepath.Join(home, ".config", "tsk", "config.toml")},
+		{"", "/xdg", "/xdg/tsk/config.toml"},
+		{"/etc/tsk.toml", "/xdg", "/etc/tsk.toml"},
+		{"~/tsk.toml", "", filepath.Join(home, "tsk.toml")},
+	}
+	for _, tt := range tests {
+		t.Setenv("TSK_CONFIG", tt.tskConfig)
+		t.Setenv("XDG_CONFIG_HOME", tt.xdg)
+		got, err := Path()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("TSK_CONFIG=%q XDG_CONFIG_HOME=%q: Path() = %q, want %q", tt.tskConfig, tt.xdg, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPathsFollowXDG(t *testing.T) {
+	setHome(t)
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("XDG_STATE_HOME", "/state")
+	t.Setenv("XDG_CACHE_HOME", "/cache")
+
+	s := DefaultConfig().Storage
+	for _, p := range [][2]string{
+		{s.Path, "/data/tsk/tasks.json"},
+		{s.GitDir, "/data/tsk/repo"},
+		{s.EventsPath, "/data/tsk/events.jsonl"},
+		{s.IndexedPath, "/data/tsk/tasks.db"},
+	} {
+		if p[0] != p[1] {
+			t.Errorf("default path = %q, want %q", p[0], p[1])
+		}
+	}
+	for _, f := range []struct {
+		path func() (string, error)
+		want string
+	}{
+		{JournalPath, "/state/tsk/journal.json"},
+		{ConflictsPath, "/state/tsk/conflicts.json"},
+		{GistCachePath, "/cache/tsk/gist.json"},
+	} {
+		if got, _ := f.path(); got != f.want {
+			t.Errorf("path = %q, want %q", got, f.want)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := setHome(t)
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(home, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".tasks.json", "old tasks")
+	write(".tasks.json.lock", "")
+	write(".tasks/tasks.json", "another storage type, stays")
+	write(".tasks.journal.json", "journal")
+	write(".local/state/tsk/conflicts.json", "new conflicts")
+	write(".tasks.conflicts.json", "old conflicts, stays")
+
+	cfg := DefaultConfig()
+	moved, err := Migrate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Move{
+		{filepath.Join(home, ".tasks.json"), filepath.Join(home, ".local/share/tsk/tasks.json")},
+		{filepath.Join(home, ".tasks.journal.json"), filepath.Join(home, ".local/state/tsk/journal.json")},
+	}
+	if len(moved) != len(want) {
+		t.Fatalf("moved = %v, want %v", moved, want)
+	}
+	for i := range want {
+		if moved[i] != want[i] {
+			t.Errorf("moved[%d] = %v, want %v", i, moved[i], want[i])
+		}
+	}
+
+	for name, want := range map[string]string{
+		".local/share/tsk/tasks.json":     "old tasks",
+		".tasks/tasks.json":               "another storage type, stays",
+		".local/state/tsk/journal.json":   "journal",
+		".local/state/tsk/conflicts.json": "new conflicts",
+		".tasks.conflicts.json":           "old conflicts, stays",
+	} {
+		data, err := os.ReadFile(filepath.Join(home, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(home, ".tasks.json.lock")); !os.IsNotExist(err) {
+		t.Errorf("old lock file left behind: %v", err)
+	}
+
+	// nothing left to move the second time
+	if moved, err := Migrate(cfg); err != nil || len(moved) != 0 {
+		t.Errorf("second Migrate = %v, %v; want nothing moved", moved, err)
+	}
+
+	// tasks kept somewhere else stay there
+	write(".tasks.db", "indexed")
+	cfg.Storage.Type = "indexed"
+	cfg.Storage.IndexedPath = filepath.Join(home, ".tasks.db")
+	if moved, err := Migrate(cfg); err != nil || len(moved) != 0 {
+		t.Errorf("Migrate with custom path = %v, %v; want nothing moved", moved, err)
+	}
+}
+
+func TestMigrateAcrossFilesystems(t *testing.T) {
+	home := setHome(t)
+	rename = func(from, to string) error {
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	repo := filepath.Join(home, ".tasks")
+	if err := os.MkdirAll(filepath.Join(repo, ".git", "refs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "tasks.json"), []byte("tasks"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("refs", filepath.Join(repo, ".git", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Storage.Type = "git"
+	moved, err := Migrate(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0].To != cfg.Storage.GitDir {
+		t.Fatalf("moved = %v, want the repository", moved)
+	}
+
+	if _, err := os.Stat(repo); !os.IsNotExist(err) {
+		t.Errorf("old repository left behind: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(cfg.Storage.GitDir, "tasks.json"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("copied tasks.json: %v, %v; want mode 0600", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(cfg.Storage.GitDir, ".git", "link")); err != nil || target != "refs" {
+		t.Errorf("copied symlink = %q, %v; want refs", target, err)
+	}
+}
+
+func TestMigrateKeepsGoingAfterAFailure(t *testing.T) {
+	home := setHome(t)
+	rename = func(from, to string) error {
+		if filepath.Base(from) == ".tasks.json" {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.EACCES}
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	for _, name := range []string{".tasks.json", ".tasks.journal.json"} {
+		if err := os.WriteFile(filepath.Join(home, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	moved, err := Migrate(DefaultConfig())
+	if err == nil || !strings.Contains(err.Error(), ".tasks.json") {
+		t.Errorf("err = %v, want the failed move", err)
+	}
+	if len(moved) != 1 || filepath.Base(moved[0].From) != ".tasks.journal.json" {
+		t.Errorf("moved = %v, want the journal", moved)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".tasks.json")); err != nil {
+		t.Errorf("tasks not left in place: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Template is the commented config file written by Init.
+const Template = `# tsk configuration
+#
+# Every setting is optional; the commented-out ones show their defaults.
+# Change them here or with: tsk config set <section.key> <value>
+
+[color]
+# "auto" colors output when it goes to a terminal; "always" or "never"
+enabled = "auto"
+
+[storage]
+# where tasks are kept: "file", "gist", "git", "events" or "indexed"
+type = "file"
+
+# file storage
+# path = "~/.local/share/tsk/tasks.json"
+
+# gist storage: a GitHub token with the gist scope (or set
+# TSK_GIST_TOKEN), and the gist to use — created on first save if empty
+# gist_token = ""
+# gist_id = ""
+# gist_timeout = "30s"
+
+# git storage: the repository, and the remote tsk sync pushes to
+# git_dir = "~/.local/share/tsk/repo"
+# git_remote = ""
+
+# events storage
+# events_path = "~/.local/share/tsk/events.jsonl"
+
+# indexed storage
+# indexed_path = "~/.local/share/tsk/tasks.db"
+
+# a command printing the passphrase to encrypt tasks with (or set
+# TSK_PASSPHRASE), e.g. "pass show tsk"
+# passphrase_cmd = ""
+
+[projects]
+# projects hidden from tsk list; tsk project archive and unarchive
+# change this
+# archived = ["release-1.3"]
+`
+
+// Init writes Template to path, creating its directory. It fails if
+// the file already exists.
+func Init(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return fmt.Errorf("create config: %w", err)
+	}
+	if _, err := f.WriteString(Template); err != nil {
+		f.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+// Save replaces the config file at path with data, if it is a valid
+// config.
+func Save(path string, data []byte) error {
+	if err := Check(data); err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// Set sets key, such as "storage.type", to value in the config file at
+// path, creating the file if needed. The value of a list setting is a
+// TOML array, such as ["a", "b"]. Everything else in the file —
+// comments, blank lines and the order of keys — is kept as it is,
+// except that unquoted strings from older config files get quoted. If
+// the new value is not valid the file is left untouched; problems with
+// other settings are left for Check to report.
+func Set(path, key, value string) error {
+	s, err := findSetting(key)
+	if err != nil {
+		return err
+	}
+	v, err := s.parse(value)
+	if err != nil {
+		return err
+	}
+	return set(path, key, v)
+}
+
+// SetList sets the list setting key to values in the config file at
+// path, like Set.
+func SetList(path, key string, values []string) error {
+	s, err := findSetting(key)
+	if err != nil {
+		return err
+	}
+	if !s.list {
+		return fmt.Errorf("%s is not a list", key)
+	}
+	return set(path, key, values)
+}
+
+// EncodeValue returns value, as given to Set, the way Set writes it
+// for key: as a quoted string, or for a list setting, as an array.
+func EncodeValue(key, value string) (string, error) {
+	s, err := findSetting(key)
+	if err != nil {
+		return "", err
+	}
+	v, err := s.parse(value)
+	if err != nil {
+		return "", err
+	}
+	return encodeValue(v), nil
+}
+
+func set(path, key string, v any) error {
+	src, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	src, _ = quoteLegacy(src)
+	out, err := setKey(src, key, encodeValue(v))
+	if err != nil {
+		return err
+	}
+	for _, p := range Validate(out) {
+		if !p.Warning && p.Key == key {
+			return p
+		}
+	}
+	return writeFile(path, out)
+}
+
+// Unset removes key from the config file at path, so that it takes its
+// default value again, and reports whether it was set.
+func Unset(path, key string) (bool, error) {
+	if _, err := findSetting(key); err != nil {
+		return false, err
+	}
+	src, err := readFile(path)
+	if err != nil {
+		return false, err
+	}
+	src, _ = quoteLegacy(src)
+	out, err := unsetKey(src, key)
+	if err != nil || out == nil {
+		return false, err
+	}
+	return true, writeFile(path, out)
+}
+
+// setKey returns src with key set to the TOML value v.
+func setKey(src []byte, key, v string) ([]byte, error) {
+	entries, err := locate(src)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.Split(key, ".")
+	section := path[:1]
+
+	var last, header *entry
+	for i := range entries {
+		e := &entries[i]
+		switch {
+		case e.header && slices.Equal(e.path, section):
+			header = e
+		case e.header:
+		case slices.Equal(e.path, path):
+			return splice(src, e.value, e.end, v), nil
+		case len(e.path) < len(path) && slices.Equal(e.path, path[:len(e.path)]):
+			return nil, fmt.Errorf("cannot set %s: %s is not written as a [%s] table; use tsk config edit", key, dotted(e.path), section[0])
+		case slices.Equal(e.path[:1], section) && len(e.path) == len(path):
+			last = e
+		}
+	}
+
+	nl := newlineOf(src)
+	switch {
+	case last != nil:
+		// after the section's last key, written the same way
+		name := dotted(append(slices.Clone(path[len(last.table):len(path)-1]), path[len(path)-1]))
+		at, prefix := endOfLine(src, last.end)
+		return splice(src, at, at, prefix+name+" = "+v+nl), nil
+	case header != nil:
+		at, prefix := endOfLine(src, header.end)
+		return splice(src, at, at, prefix+path[1]+" = "+v+nl), nil
+	default:
+		var b bytes.Buffer
+		b.Write(src)
+		if len(src) > 0 {
+			if !bytes.HasSuffix(src, []byte("\n")) {
+				b.WriteString(nl)
+			}
+			b.WriteString(nl)
+		}
+		fmt.Fprintf(&b, "[%s]%s%s = %s%s", section[0], nl, path[1], v, nl)
+		return b.Bytes(), nil
+	}
+}
+
+// unsetKey returns src without the line setting key, or nil if key is
+// not set.
+func unsetKey(src []byte, key string) ([]byte, error) {
+	entries, err := locate(src)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.Split(key, ".")
+	for _, e := range entries {
+		switch {
+		case e.header:
+		case slices.Equal(e.path, path):
+			start := bytes.LastIndexByte(src[:e.start], '\n') + 1
+			end, _ := endOfLine(src, e.end)
+			return splice(src, start, end, ""), nil
+		case len(e.path) < len(path) && slices.Equal(e.path, path[:len(e.path)]):
+			return nil, fmt.Errorf("cannot unset %s: it is set inside %s; use tsk config edit", key, dotted(e.path))
+		}
+	}
+	return nil, nil
+}
+
+// locate parses src and returns where its headers and keys are.
+func locate(src []byte) ([]entry, error) {
+	_, entries, err := parseTOML(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	return entries, nil
+}
+
+// endOfLine returns the offset just past the newline that ends the
+// line containing offset i, trailing comment and all. If that is the
+// last line and has no newline, prefix is the newline to write first.
+func endOfLine(src []byte, i int) (at int, prefix string) {
+	n := bytes.IndexByte(src[i:], '\n')
+	if n < 0 {
+		return len(src), newlineOf(src)
+	}
+	return i + n + 1, ""
+}
+
+// newlineOf returns the line ending src uses.
+func newlineOf(src []byte) string {
+	if bytes.Contains(src, []byte("\r\n")) {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+func splice(src []byte, start, end int, s string) []byte {
+	out := make([]byte, 0, len(src)+len(s))
+	out = append(out, src[:start]...)
+	out = append(out, s...)
+	return append(out, src[end:]...)
+}
+
+func readFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return data, nil
+}
+
+// writeFile replaces the config file at path with data, keeping its
+// permissions. A new file is only readable by its owner, as it may
+// hold a token.
+func writeFile(path string, data []byte) error {
+	perm := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Chmod(f.Name(), perm); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name:  "empty file",
+			src:   "",
+			key:   "storage.type",
+			value: "git",
+			want:  "[storage]\ntype = \"git\"\n",
+		},
+		{
+			name: "replace value, keep comments",
+			src: `# my config
+[storage]
+type = "file"  # for now
+path = "~/tasks.json"
+`,
+			key:   "storage.type",
+			value: "gist",
+			want: `# my config
+[storage]
+type = "gist"  # for now
+path = "~/tasks.json"
+`,
+		},
+		{
+			name: "add after the section's last key",
+			src: `[storage]
+type = "gist"
+gist_token = "t" # secret
+
+[color]
+enabled = "never"
+`,
+			key:   "storage.gist_id",
+			value: "abc",
+			want: `[storage]
+type = "gist"
+gist_token = "t" # secret
+gist_id = "abc"
+
+[color]
+enabled = "never"
+`,
+		},
+		{
+			name:  "add under an empty header",
+			src:   "[color] # colors\n\n[storage]\n",
+			key:   "color.enabled",
+			value: "always",
+			want:  "[color] # colors\nenabled = \"always\"\n\n[storage]\n",
+		},
+		{
+			name:  "add a section",
+			src:   "[color]\nenabled = \"auto\"",
+			key:   "storage.type",
+			value: "git",
+			want:  "[color]\nenabled = \"auto\"\n\n[storage]\ntype = \"git\"\n",
+		},
+		{
+			name:  "dotted keys stay dotted",
+			src:   "storage.type = \"git\"\n\n[color]\n",
+			key:   "storage.git_dir",
+			value: "~/notes",
+			want:  "storage.type = \"git\"\nstorage.git_dir = \"~/notes\"\n\n[color]\n",
+		},
+		{
+			name:  "multi-line value",
+			src:   "[storage]\npassphrase_cmd = \"\"\"\npass show tsk\"\"\"\ntype = \"file\"\n",
+			key:   "storage.passphrase_cmd",
+			value: "op read op://tsk",
+			want:  "[storage]\npassphrase_cmd = \"op read op://tsk\"\ntype = \"file\"\n",
+		},
+		{
+			name:  "CRLF line endings",
+			src:   "[storage]\r\ntype = \"file\"\r\n",
+			key:   "storage.path",
+			value: `C:\tasks.json`,
+			want:  "[storage]\r\ntype = \"file\"\r\npath = \"C:\\\\tasks.json\"\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setKey([]byte(tt.src), tt.key, encodeString(tt.value))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsetKey(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		key  string
+		want string // "" when the key is not set
+	}{
+		{
+			name: "remove line and trailing comment",
+			src:  "# tsk\n[storage]\ntype = \"gist\" # remote\ngist_id = \"abc\"\n",
+			key:  "storage.type",
+			want: "# tsk\n[storage]\ngist_id = \"abc\"\n",
+		},
+		{
+			name: "last line without newline",
+			src:  "[storage]\ntype = \"gist\"\ngist_id = \"abc\"",
+			key:  "storage.gist_id",
+			want: "[storage]\ntype = \"gist\"\n",
+		},
+		{
+			name: "dotted key",
+			src:  "color.enabled = \"never\"\nstorage.type = \"git\"\n",
+			key:  "color.enabled",
+			want: "storage.type = \"git\"\n",
+		},
+		{
+			name: "not set",
+			src:  "[storage]\ntype = \"gist\"\n",
+			key:  "storage.gist_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unsetKey([]byte(tt.src), tt.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("got:\n%s\nwant key not set", got)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditInlineTable(t *testing.T) {
+	src := []byte(`color = { enabled = "never" }` + "\n")
+	if _, err := setKey(src, "color.enabled", `"auto"`); err == nil || !strings.Contains(err.Error(), "tsk config edit") {
+		t.Errorf("set in inline table: err = %v, want hint to edit", err)
+	}
+	if _, err := unsetKey(src, "color.enabled"); err == nil || !strings.Contains(err.Error(), "tsk config edit") {
+		t.Errorf("unset in inline table: err = %v, want hint to edit", err)
+	}
+}
+
+func TestSetAndUnset(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tsk", "config.toml")
+	if err := Init(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := Init(p); err == nil {
+		t.Error("second Init succeeded, want error")
+	}
+
+	if err := Set(p, "storage.type", "gist"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(p, "storage.gist_timeout", "soon"); err == nil {
+		t.Error("set invalid duration succeeded")
+	}
+	if err := Set(p, "storage.kind", "gist"); err == nil {
+		t.Error("set unknown key succeeded")
+	}
+	if ok, err := Unset(p, "color.enabled"); err != nil || !ok {
+		t.Errorf("Unset(color.enabled) = %v, %v", ok, err)
+	}
+	if ok, err := Unset(p, "storage.gist_id"); err != nil || ok {
+		t.Errorf("Unset(storage.gist_id) = %v, %v; want not set", ok, err)
+	}
+
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Storage.Type != "gist" || cfg.Storage.GistTimeout != DefaultConfig().Storage.GistTimeout {
+		t.Errorf("Storage = %+v", cfg.Storage)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(Template, `type = "file"`, `type = "gist"`, 1)
+	want = strings.Replace(want, "enabled = \"auto\"\n", "", 1)
+	if string(data) != want {
+		t.Errorf("config file:\n%s\nwant:\n%s", data, want)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestSetQuotesLegacyValues(t *testing.T) {
+	p := writeConfig(t, "[storage]\ntype = git  # for now\n")
+	if err := Set(p, "storage.git_remote", "origin"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[storage]\ntype = \"git\"  # for now\ngit_remote = \"origin\"\n"; string(data) != want {
+		t.Errorf("config file:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestSetList(t *testing.T) {
+	p := writeConfig(t, "[color]\nenabled = \"never\"\n")
+	if err := SetList(p, "projects.archived", []string{"old", "release-1.3"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[color]\nenabled = \"never\"\n\n[projects]\narchived = [\"old\", \"release-1.3\"]\n"; string(data) != want {
+		t.Errorf("config file:\n%s\nwant:\n%s", data, want)
+	}
+
+	if err := Set(p, "projects.archived", `["old"]`); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFrom(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Projects.Archived) != 1 || cfg.Projects.Archived[0] != "old" {
+		t.Errorf("Projects.Archived = %q, want [old]", cfg.Projects.Archived)
+	}
+
+	if err := Set(p, "projects.archived", "old"); err == nil {
+		t.Error("set a list to a string succeeded")
+	}
+	if err := SetList(p, "storage.type", []string{"git"}); err == nil {
+		t.Error("SetList on a string setting succeeded")
+	}
+}
+
+func TestGet(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, key := range Keys() {
+		if _, err := cfg.Get(key); err != nil {
+			t.Errorf("Get(%q): %v", key, err)
+		}
+	}
+	if v, _ := cfg.Get("storage.gist_timeout"); v != "30s" {
+		t.Errorf("Get(storage.gist_timeout) = %q, want 30s", v)
+	}
+	if v, _ := cfg.Get("projects.archived"); v != "[]" {
+		t.Errorf("Get(projects.archived) = %q, want []", v)
+	}
+	if _, err := cfg.Get("storage"); err == nil {
+		t.Error("Get(storage) succeeded, want unknown key error")
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source is where the value of a setting came from. Each one overrides
+// the ones before it.
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile           // the config file
+	SourceEnv            // a TSK_* environment variable
+	SourceFlag           // a --set flag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// Source returns where the value of key came from.
+func (c Config) Source(key string) Source {
+	return c.sources[key]
+}
+
+func (c *Config) setSource(key string, src Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = src
+}
+
+// EnvVar returns the environment variable that overrides key:
+// storage.gist_id is TSK_STORAGE_GIST_ID.
+func EnvVar(key string) string {
+	return "TSK_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envAliases are older names still read for a key, after its EnvVar.
+var envAliases = map[string][]string{
+	"storage.gist_token": {"TSK_GIST_TOKEN"},
+}
+
+// lookupEnv returns the first non-empty variable overriding key, and
+// its name.
+func lookupEnv(key string) (name, value string, ok bool) {
+	for _, name := range append([]string{EnvVar(key)}, envAliases[key]...) {
+		if v := os.Getenv(name); v != "" {
+			return name, v, true
+		}
+	}
+	return "", "", false
+}
+
+// ApplyEnv overrides every setting whose environment variable is set,
+// such as TSK_STORAGE_TYPE for storage.type. Empty variables are
+// ignored.
+func (c *Config) ApplyEnv() error {
+	for _, s := range settings {
+		name, v, ok := lookupEnv(s.key)
+		if !ok {
+			continue
+		}
+		if err := c.Override(s.key, v, SourceEnv); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Override sets key to value, recording src as where it came from.
+// Unlike a value in the config file, which Validate reports on, an
+// invalid value is an error.
+func (c *Config) Override(key, value string, src Source) error {
+	s, err := findSetting(key)
+	if err != nil {
+		return err
+	}
+	if err := checkEnum(key, value); err != nil {
+		return err
+	}
+	v, err := s.parse(value)
+	if err != nil {
+		return err
+	}
+	if err := s.set(c, v); err != nil {
+		return err
+	}
+	c.setSource(key, src)
+	return nil
+}
+
+// Explain returns the config in TOML format, like String, with a
+// comment after each value saying where it came from.
+func (c Config) Explain() string {
+	return c.format(func(key string) string {
+		src := c.Source(key)
+		if src == SourceEnv {
+			if name, _, ok := lookupEnv(key); ok {
+				return "env " + name
+			}
+		}
+		return src.String()
+	})
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every variable ApplyEnv reads.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range Keys() {
+		t.Setenv(EnvVar(key), "")
+		for _, alias := range envAliases[key] {
+			t.Setenv(alias, "")
+		}
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"color.enabled", "TSK_COLOR_ENABLED"},
+		{"storage.type", "TSK_STORAGE_TYPE"},
+		{"storage.gist_timeout", "TSK_STORAGE_GIST_TIMEOUT"},
+	}
+	for _, tt := range tests {
+		if got := EnvVar(tt.key); got != tt.want {
+			t.Errorf("EnvVar(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(Config) bool
+		wantErr string
+	}{
+		{
+			name:  "string",
+			env:   map[string]string{"TSK_STORAGE_TYPE": "git"},
+			check: func(c Config) bool { return c.Storage.Type == "git" },
+		},
+		{
+			name:  "duration",
+			env:   map[string]string{"TSK_STORAGE_GIST_TIMEOUT": "1m"},
+			check: func(c Config) bool { return c.Storage.GistTimeout == time.Minute },
+		},
+		{
+			name:  "empty is ignored",
+			env:   map[string]string{"TSK_COLOR_ENABLED": ""},
+			check: func(c Config) bool { return c.Color.Enabled == "never" },
+		},
+		{
+			name:  "old gist token name",
+			env:   map[string]string{"TSK_GIST_TOKEN": "old"},
+			check: func(c Config) bool { return c.Storage.GistToken == "old" },
+		},
+		{
+			name:  "new gist token name wins",
+			env:   map[string]string{"TSK_GIST_TOKEN": "old", "TSK_STORAGE_GIST_TOKEN": "new"},
+			check: func(c Config) bool { return c.Storage.GistToken == "new" },
+		},
+		{
+			name:  "list",
+			env:   map[string]string{"TSK_PROJECTS_ARCHIVED": `["old", "release-1.3"]`},
+			check: func(c Config) bool { return len(c.Projects.Archived) == 2 && c.Projects.Archived[1] == "release-1.3" },
+		},
+		{
+			name:    "bad list",
+			env:     map[string]string{"TSK_PROJECTS_ARCHIVED": "old"},
+			wantErr: `TSK_PROJECTS_ARCHIVED: "old" is not an array of strings`,
+		},
+		{
+			name:    "bad enum",
+			env:     map[string]string{"TSK_COLOR_ENABLED": "yes"},
+			wantErr: `TSK_COLOR_ENABLED: "yes" is not a color mode`,
+		},
+		{
+			name:    "bad duration",
+			env:     map[string]string{"TSK_STORAGE_GIST_TIMEOUT": "soon"},
+			wantErr: `TSK_STORAGE_GIST_TIMEOUT: invalid duration "soon"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := LoadFrom(writeConfig(t, "[color]\nenabled = \"never\"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.ApplyEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("config = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestSources(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("TSK_STORAGE_TYPE", "git")
+	t.Setenv("TSK_STORAGE_GIT_DIR", "/srv/tasks")
+
+	cfg, err := LoadFrom(writeConfig(t, "[color]\nenabled = \"never\"\n[storage]\ntype = \"file\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Override("storage.git_dir", "/tmp/tasks", SourceFlag); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]Source{
+		"color.enabled":   SourceFile,
+		"storage.type":    SourceEnv,
+		"storage.git_dir": SourceFlag,
+		"storage.path":    SourceDefault,
+	}
+	for key, src := range want {
+		if got := cfg.Source(key); got != src {
+			t.Errorf("Source(%q) = %v, want %v", key, got, src)
+		}
+	}
+	if cfg.Storage.GitDir != "/tmp/tasks" {
+		t.Errorf("GitDir = %q, want the flag's value", cfg.Storage.GitDir)
+	}
+
+	s := cfg.Explain()
+	for _, line := range []string{
+		`enabled = "never"  # file`,
+		`type = "git"  # env TSK_STORAGE_TYPE`,
+		`git_dir = "/tmp/tasks"  # flag`,
+		`gist_id = ""  # default`,
+	} {
+		if !strings.Contains(s, line+"\n") {
+			t.Errorf("Explain() missing %q:\n%s", line, s)
+		}
+	}
+	if _, err := decodeTOML([]byte(s)); err != nil {
+		t.Errorf("Explain() is not valid TOML: %v", err)
+	}
+}
+
+func TestOverrideErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := cfg.Override("storage.kind", "git", SourceFlag); err == nil {
+		t.Error("override unknown key succeeded")
+	}
+	if err := cfg.Override("storage.type", "gits", SourceFlag); err == nil || !strings.Contains(err.Error(), `did you mean "git"?`) {
+		t.Errorf("override bad type: err = %v, want suggestion", err)
+	}
+	if cfg.Storage.Type != "file" || cfg.Source("storage.type") != SourceDefault {
+		t.Errorf("failed override changed the config: %+v", cfg)
+	}
+}
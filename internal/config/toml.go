@@ -0,0 +1,1005 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// This file implements a TOML 1.0 decoder (https://toml.io/en/v1.0.0).
+// decodeTOML returns the document as a map; values keep their TOML type:
+//
+//	string, int64, float64, bool
+//	time.Time (offset date-time), LocalDateTime, LocalDate, LocalTime
+//	[]any (array), map[string]any (table), []map[string]any (array of tables)
+
+// ParseError is an error in a TOML document, at a 1-based line and
+// column. Columns count characters, not bytes.
+type ParseError struct {
+	Line   int
+	Column int
+	Msg    string
+
+	offset   int  // where in the document the error is
+	unquoted bool // the error is an unquoted string value
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// LocalDate is a date without a time or offset, such as 1979-05-27.
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func (d LocalDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// LocalTime is a time of day without a date or offset, such as 07:32:00.
+type LocalTime struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+func (t LocalTime) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", t.Nanosecond), "0")
+	}
+	return s
+}
+
+// LocalDateTime is a date and time without an offset, such as
+// 1979-05-27T07:32:00.
+type LocalDateTime struct {
+	Date LocalDate
+	Time LocalTime
+}
+
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + "T" + dt.Time.String()
+}
+
+// tableKind records how a table came to exist, which decides whether
+// it may be defined or extended later.
+type tableKind int
+
+const (
+	tableImplicit tableKind = iota // parent of a [header]; may get its own header once
+	tableHeader                    // defined by a [header]
+	tableDotted                    // created by a dotted key; only dotted keys may extend it
+	tableInline                    // an inline table; closed
+)
+
+type table struct {
+	kind   tableKind
+	values map[string]any // scalars, []any, *table or *tableArray
+}
+
+func newTable(kind tableKind) *table {
+	return &table{kind: kind, values: make(map[string]any)}
+}
+
+// tableArray is an array of tables built by [[header]]s.
+type tableArray struct {
+	tables []*table
+}
+
+// entry locates a table header or a key/value pair outside inline
+// tables in the source, so that a document can be edited in place.
+type entry struct {
+	path   []string // full path of the table or key
+	table  []string // path of the header a key/value pair is under
+	header bool
+	start  int // offset of the [ or key
+	value  int // offset of the value
+	end    int // offset just past the ] or value
+}
+
+type parser struct {
+	src     []byte
+	pos     int
+	root    *table
+	cur     *table   // table that key/value pairs go into
+	path    []string // path of cur's header
+	inline  int      // depth of inline tables being parsed
+	entries []entry
+}
+
+// decodeTOML parses a TOML document.
+func decodeTOML(src []byte) (map[string]any, error) {
+	doc, _, err := parseTOML(src)
+	return doc, err
+}
+
+// parseTOML parses a TOML document and also returns where its headers
+// and key/value pairs are, in source order.
+func parseTOML(src []byte) (map[string]any, []entry, error) {
+	p := &parser{src: src, root: newTable(tableHeader)}
+	p.cur = p.root
+	if err := p.document(); err != nil {
+		return nil, nil, err
+	}
+	return exportTable(p.root), p.entries, nil
+}
+
+func (p *parser) document() error {
+	if bytes.HasPrefix(p.src, []byte("\xef\xbb\xbf")) {
+		p.pos = 3
+	}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil
+		}
+		switch p.src[p.pos] {
+		case '#', '\n', '\r':
+		case '[':
+			if err := p.header(); err != nil {
+				return err
+			}
+		default:
+			if err := p.keyValue(p.cur); err != nil {
+				return err
+			}
+		}
+		if err := p.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// endOfLine consumes trailing whitespace, a comment and the newline
+// that end a line.
+func (p *parser) endOfLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		if err := p.comment(); err != nil {
+			return err
+		}
+	}
+	if p.eof() || p.newline() {
+		return nil
+	}
+	return p.errorf("expected end of line, found %s", p.found())
+}
+
+func (p *parser) comment() error {
+	p.pos++ // #
+	for !p.eof() && p.src[p.pos] != '\n' && !p.lookingAt("\r\n") {
+		if _, err := p.char("comment"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipBlank skips whitespace, newlines and comments, as allowed
+// between array elements.
+func (p *parser) skipBlank() error {
+	for {
+		p.skipSpace()
+		switch {
+		case p.peek() == '#':
+			if err := p.comment(); err != nil {
+				return err
+			}
+		case p.newline():
+		default:
+			return nil
+		}
+	}
+}
+
+// header parses a [table] or [[array of tables]] header and makes its
+// table the current one.
+func (p *parser) header() error {
+	start := p.pos
+	p.pos++ // [
+	array := p.peek() == '['
+	if array {
+		p.pos++
+	}
+	p.skipSpace()
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !p.consume("]") || array && !p.consume("]") {
+		return p.errorf("unclosed section header: expected %s, found %s", strings.Repeat("]", 1+b2i(array)), p.found())
+	}
+
+	t := p.root
+	for i, k := range keys[:len(keys)-1] {
+		switch v := t.values[k].(type) {
+		case nil:
+			next := newTable(tableImplicit)
+			t.values[k] = next
+			t = next
+		case *table:
+			if v.kind == tableInline {
+				return p.errorAt(start, "cannot add to inline table %s", dotted(keys[:i+1]))
+			}
+			t = v
+		case *tableArray:
+			t = v.tables[len(v.tables)-1]
+		default:
+			return p.errorAt(start, "key %s is already defined as a value", dotted(keys[:i+1]))
+		}
+	}
+
+	p.path = keys
+	p.entries = append(p.entries, entry{path: keys, header: true, start: start, end: p.pos})
+
+	name, last := dotted(keys), keys[len(keys)-1]
+	existing := t.values[last]
+	if array {
+		arr, ok := existing.(*tableArray)
+		if existing != nil && !ok {
+			return p.errorAt(start, "%s is already defined and is not an array of tables", name)
+		}
+		if arr == nil {
+			arr = &tableArray{}
+			t.values[last] = arr
+		}
+		p.cur = newTable(tableHeader)
+		arr.tables = append(arr.tables, p.cur)
+		return nil
+	}
+
+	switch v := existing.(type) {
+	case nil:
+		p.cur = newTable(tableHeader)
+		t.values[last] = p.cur
+	case *table:
+		if v.kind != tableImplicit {
+			return p.errorAt(start, "table %s is already defined", name)
+		}
+		v.kind = tableHeader
+		p.cur = v
+	case *tableArray:
+		return p.errorAt(start, "%s is already defined as an array of tables", name)
+	default:
+		return p.errorAt(start, "key %s is already defined as a value", name)
+	}
+	return nil
+}
+
+// keyValue parses a key = value pair into t.
+func (p *parser) keyValue(t *table) error {
+	start := p.pos
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	if !p.consume("=") {
+		return p.errorf("expected key = value, found %s", p.found())
+	}
+	p.skipSpace()
+	valueStart := p.pos
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	if p.inline == 0 {
+		p.entries = append(p.entries, entry{
+			path:  append(slices.Clone(p.path), keys...),
+			table: p.path,
+			start: start,
+			value: valueStart,
+			end:   p.pos,
+		})
+	}
+
+	for i, k := range keys[:len(keys)-1] {
+		switch next := t.values[k].(type) {
+		case nil:
+			n := newTable(tableDotted)
+			t.values[k] = n
+			t = n
+		case *table:
+			if next.kind != tableDotted {
+				return p.errorAt(start, "table %s is already defined", dotted(keys[:i+1]))
+			}
+			t = next
+		default:
+			return p.errorAt(start, "key %s is already defined", dotted(keys[:i+1]))
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, ok := t.values[last]; ok {
+		return p.errorAt(start, "duplicate key %s", dotted(keys))
+	}
+	t.values[last] = v
+	return nil
+}
+
+// key parses a possibly dotted key, and the whitespace after it.
+func (p *parser) key() ([]string, error) {
+	var keys []string
+	for {
+		var k string
+		var err error
+		switch c := p.peek(); {
+		case c == '"':
+			k, err = p.basicString()
+		case c == '\'':
+			k, err = p.literalString()
+		case isBare(c):
+			start := p.pos
+			for isBare(p.peek()) {
+				p.pos++
+			}
+			k = string(p.src[start:p.pos])
+		default:
+			err = p.errorf("expected key, found %s", p.found())
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+
+		p.skipSpace()
+		if !p.consume(".") {
+			return keys, nil
+		}
+		p.skipSpace()
+	}
+}
+
+func (p *parser) value() (any, error) {
+	switch c := p.peek(); {
+	case p.lookingAt(`"""`):
+		return p.multilineString('"')
+	case c == '"':
+		return p.basicString()
+	case p.lookingAt(`'''`):
+		return p.multilineString('\'')
+	case c == '\'':
+		return p.literalString()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case p.lookingAt("true") && !isBare(p.at(p.pos+4)):
+		p.pos += 4
+		return true, nil
+	case p.lookingAt("false") && !isBare(p.at(p.pos+5)):
+		p.pos += 5
+		return false, nil
+	case isDigit(c) || c == '+' || c == '-' || c == 'i' || c == 'n':
+		start := p.pos
+		v, err := p.scalar()
+		if err != nil {
+			// older versions of tsk took words such as never, 30s and
+			// 8f3a9c0d1e as strings
+			err.(*ParseError).offset = start
+			err.(*ParseError).unquoted = true
+		}
+		return v, err
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n#,]}", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf("expected a value, found %s", p.found())
+	}
+	err := p.errorAt(start, "invalid value %s: strings must be quoted, as in %q", p.src[start:p.pos], p.src[start:p.pos])
+	err.(*ParseError).unquoted = true
+	return nil, err
+}
+
+func (p *parser) array() ([]any, error) {
+	start := p.pos
+	p.pos++ // [
+	arr := []any{}
+	for {
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorAt(start, "unclosed array")
+		}
+		if p.consume("]") {
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.consume(","):
+		case p.consume("]"):
+			return arr, nil
+		case p.eof():
+			return nil, p.errorAt(start, "unclosed array")
+		default:
+			return nil, p.errorf("expected , or ] after array element, found %s", p.found())
+		}
+	}
+}
+
+func (p *parser) inlineTable() (*table, error) {
+	p.pos++ // {
+	p.inline++
+	defer func() { p.inline-- }()
+	t := newTable(tableInline)
+	p.skipSpace()
+	if p.consume("}") {
+		return t, nil
+	}
+	for {
+		p.skipSpace()
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch {
+		case p.consume(","):
+			p.skipSpace()
+			if p.peek() == '}' {
+				return nil, p.errorf("trailing comma in inline table")
+			}
+		case p.consume("}"):
+			seal(t)
+			return t, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table, found %s", p.found())
+		}
+	}
+}
+
+// seal closes t and the tables its dotted keys created to additions.
+func seal(t *table) {
+	t.kind = tableInline
+	for _, v := range t.values {
+		if sub, ok := v.(*table); ok {
+			seal(sub)
+		}
+	}
+}
+
+func (p *parser) basicString() (string, error) {
+	start := p.pos
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof() || c == '\n' || c == '\r':
+			return "", p.errorAt(start, "unclosed quote")
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		case c == '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			r, err := p.char("string")
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) literalString() (string, error) {
+	start := p.pos
+	p.pos++ // '
+	for {
+		switch c := p.peek(); {
+		case p.eof() || c == '\n' || c == '\r':
+			return "", p.errorAt(start, "unclosed quote")
+		case c == '\'':
+			p.pos++
+			return string(p.src[start+1 : p.pos-1]), nil
+		default:
+			if _, err := p.char("string"); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// multilineString parses a multi-line string opened by three quotes:
+// basic if quote is a double quote, literal if it is a single one.
+func (p *parser) multilineString(quote byte) (string, error) {
+	start := p.pos
+	p.pos += 3
+	p.newline() // a newline right after the opening quotes is trimmed
+
+	var b strings.Builder
+	for {
+		switch c := p.peek(); {
+		case p.eof():
+			return "", p.errorAt(start, "unclosed multi-line string")
+		case c == quote && p.lookingAt(strings.Repeat(string(quote), 3)):
+			// up to two quotes may come right before the closing three
+			n := 0
+			for p.at(p.pos+n) == quote {
+				n++
+			}
+			if n > 5 {
+				return "", p.errorf("too many quotes in a row in multi-line string")
+			}
+			b.WriteString(strings.Repeat(string(quote), n-3))
+			p.pos += n
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if p.lineEndingBackslash() {
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		case c == '\n' || p.lookingAt("\r\n"):
+			p.newline()
+			b.WriteByte('\n')
+		default:
+			r, err := p.char("string")
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		}
+	}
+}
+
+// lineEndingBackslash skips a backslash that ends a line, along with
+// all whitespace and newlines after it. It reports whether it did.
+func (p *parser) lineEndingBackslash() bool {
+	i := p.pos + 1
+	for p.at(i) == ' ' || p.at(i) == '\t' {
+		i++
+	}
+	if p.at(i) != '\n' && !(p.at(i) == '\r' && p.at(i+1) == '\n') {
+		return false
+	}
+	p.pos = i
+	for p.newline() || p.peek() == ' ' || p.peek() == '\t' {
+		if c := p.peek(); c == ' ' || c == '\t' {
+			p.pos++
+		}
+	}
+	return true
+}
+
+func (p *parser) escape(b *strings.Builder) error {
+	start := p.pos
+	p.pos++ // \
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return p.errorAt(start, "invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+n]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorAt(start, "invalid unicode escape %s", p.src[start:p.pos+n])
+		}
+		p.pos += n
+		b.WriteRune(rune(code))
+	default:
+		p.pos = start
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+// char consumes one UTF-8 character of a string or comment, rejecting
+// control characters other than tab.
+func (p *parser) char(what string) (rune, error) {
+	r, size := utf8.DecodeRune(p.src[p.pos:])
+	if r == utf8.RuneError && size <= 1 {
+		return 0, p.errorf("invalid UTF-8 in %s", what)
+	}
+	if r < 0x20 && r != '\t' || r == 0x7f {
+		return 0, p.errorf("control character %U in %s", r, what)
+	}
+	p.pos += size
+	return r, nil
+}
+
+// scalar parses a number, date or time.
+func (p *parser) scalar() (any, error) {
+	start := p.pos
+	switch {
+	case p.digitsAt(p.pos, 4) && p.at(p.pos+4) == '-':
+		return p.dateTime()
+	case p.digitsAt(p.pos, 2) && p.at(p.pos+2) == ':':
+		t, err := p.timeOfDay()
+		return t, err
+	}
+
+	for c := p.peek(); isBare(c) || c == '+' || c == '.'; c = p.peek() {
+		p.pos++
+	}
+	v, err := parseNumber(string(p.src[start:p.pos]))
+	if err != nil {
+		return nil, p.errorAt(start, "%v", err)
+	}
+	return v, nil
+}
+
+func (p *parser) dateTime() (any, error) {
+	start := p.pos
+	year, _ := strconv.Atoi(string(p.src[p.pos : p.pos+4]))
+	p.pos += 5
+	if !p.digitsAt(p.pos, 2) || p.at(p.pos+2) != '-' || !p.digitsAt(p.pos+3, 2) {
+		return nil, p.errorAt(start, "invalid date, want YYYY-MM-DD")
+	}
+	month, _ := strconv.Atoi(string(p.src[p.pos : p.pos+2]))
+	day, _ := strconv.Atoi(string(p.src[p.pos+3 : p.pos+5]))
+	p.pos += 5
+	if month < 1 || month > 12 || day < 1 || day > daysIn(year, time.Month(month)) {
+		return nil, p.errorAt(start, "invalid date %s", p.src[start:p.pos])
+	}
+	date := LocalDate{year, time.Month(month), day}
+
+	switch c := p.peek(); {
+	case c == 'T' || c == 't':
+	case c == ' ' && p.digitsAt(p.pos+1, 2) && p.at(p.pos+3) == ':':
+	default:
+		return date, nil
+	}
+	p.pos++
+	tod, err := p.timeOfDay()
+	if err != nil {
+		return nil, err
+	}
+
+	var loc *time.Location
+	switch c := p.peek(); {
+	case c == 'Z' || c == 'z':
+		p.pos++
+		loc = time.UTC
+	case c == '+' || c == '-':
+		if !p.digitsAt(p.pos+1, 2) || p.at(p.pos+3) != ':' || !p.digitsAt(p.pos+4, 2) {
+			return nil, p.errorf("invalid offset, want Z or ±HH:MM")
+		}
+		h, _ := strconv.Atoi(string(p.src[p.pos+1 : p.pos+3]))
+		m, _ := strconv.Atoi(string(p.src[p.pos+4 : p.pos+6]))
+		if h > 23 || m > 59 {
+			return nil, p.errorf("invalid offset %s", p.src[p.pos:p.pos+6])
+		}
+		offset := (h*60 + m) * 60
+		if c == '-' {
+			offset = -offset
+		}
+		p.pos += 6
+		loc = time.FixedZone("", offset)
+	default:
+		return LocalDateTime{date, tod}, nil
+	}
+	return time.Date(year, time.Month(month), day, tod.Hour, tod.Minute, tod.Second, tod.Nanosecond, loc), nil
+}
+
+func (p *parser) timeOfDay() (LocalTime, error) {
+	start := p.pos
+	if !p.digitsAt(p.pos, 2) || p.at(p.pos+2) != ':' || !p.digitsAt(p.pos+3, 2) || p.at(p.pos+5) != ':' || !p.digitsAt(p.pos+6, 2) {
+		return LocalTime{}, p.errorAt(start, "invalid time, want HH:MM:SS")
+	}
+	var t LocalTime
+	t.Hour, _ = strconv.Atoi(string(p.src[p.pos : p.pos+2]))
+	t.Minute, _ = strconv.Atoi(string(p.src[p.pos+3 : p.pos+5]))
+	t.Second, _ = strconv.Atoi(string(p.src[p.pos+6 : p.pos+8]))
+	p.pos += 8
+	if t.Hour > 23 || t.Minute > 59 || t.Second > 59 {
+		return LocalTime{}, p.errorAt(start, "invalid time %s", p.src[start:p.pos])
+	}
+
+	if p.peek() == '.' {
+		p.pos++
+		digits := 0
+		for ; isDigit(p.peek()); p.pos++ {
+			// precision beyond nanoseconds is truncated
+			if digits < 9 {
+				t.Nanosecond = t.Nanosecond*10 + int(p.peek()-'0')
+				digits++
+			}
+		}
+		if digits == 0 {
+			return LocalTime{}, p.errorf("expected fractional seconds, found %s", p.found())
+		}
+		for ; digits < 9; digits++ {
+			t.Nanosecond *= 10
+		}
+	}
+	return t, nil
+}
+
+// parseNumber parses an integer or float, including inf and nan.
+func parseNumber(s string) (any, error) {
+	switch s {
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	if len(s) > 2 && s[0] == '0' && strings.ContainsRune("xob", rune(s[1])) {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[s[1]]
+		if !validDigits(s[2:], base) {
+			return nil, fmt.Errorf("invalid number %s", s)
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(s[2:], "_", ""), base, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer %s out of range", s)
+		}
+		return n, nil
+	}
+
+	body := strings.TrimLeft(s, "+-")
+	if len(s)-len(body) > 1 {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+	if strings.ContainsAny(body, ".eE") {
+		return parseFloat(s, body)
+	}
+	if !validDigits(body, 10) || len(body) > 1 && body[0] == '0' {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(s, "_", ""), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("integer %s out of range", s)
+	}
+	return n, nil
+}
+
+// parseFloat parses s, whose unsigned part is body, as a float.
+func parseFloat(s, body string) (any, error) {
+	mantissa, exp, hasExp := strings.Cut(strings.ToLower(body), "e")
+	whole, frac, hasFrac := strings.Cut(mantissa, ".")
+	ok := validDigits(whole, 10) && (len(whole) == 1 || whole[0] != '0') &&
+		(!hasFrac || validDigits(frac, 10)) &&
+		(!hasExp || validDigits(strings.TrimLeft(exp, "+-"), 10) && len(exp)-len(strings.TrimLeft(exp, "+-")) <= 1)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64)
+	if err != nil {
+		return nil, fmt.Errorf("float %s out of range", s)
+	}
+	return f, nil
+}
+
+// validDigits reports whether s is a non-empty run of digits in base,
+// with single underscores allowed between digits.
+func validDigits(s string, base int) bool {
+	if s == "" || s[0] == '_' || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return false
+	}
+	for _, c := range s {
+		if c == '_' {
+			continue
+		}
+		if d, err := strconv.ParseUint(string(c), base, 8); err != nil || int(d) >= base {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+// peek returns the current byte, or 0 at the end of the input.
+func (p *parser) peek() byte { return p.at(p.pos) }
+
+func (p *parser) at(i int) byte {
+	if i >= len(p.src) {
+		return 0
+	}
+	return p.src[i]
+}
+
+func (p *parser) lookingAt(s string) bool {
+	return bytes.HasPrefix(p.src[p.pos:], []byte(s))
+}
+
+func (p *parser) consume(s string) bool {
+	if !p.lookingAt(s) {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func (p *parser) newline() bool {
+	return p.consume("\n") || p.consume("\r\n")
+}
+
+func (p *parser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *parser) digitsAt(i, n int) bool {
+	for j := i; j < i+n; j++ {
+		if !isDigit(p.at(j)) {
+			return false
+		}
+	}
+	return true
+}
+
+// found describes the input at the current position for errors.
+func (p *parser) found() string {
+	switch c := p.peek(); {
+	case p.eof():
+		return "end of file"
+	case c == '\n' || c == '\r':
+		return "end of line"
+	default:
+		r, _ := utf8.DecodeRune(p.src[p.pos:])
+		return strconv.QuoteRune(r)
+	}
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *parser) errorAt(pos int, format string, args ...any) error {
+	pos = min(pos, len(p.src))
+	lineStart := bytes.LastIndexByte(p.src[:pos], '\n') + 1
+	return &ParseError{
+		Line:   1 + bytes.Count(p.src[:pos], []byte("\n")),
+		Column: 1 + utf8.RuneCount(p.src[lineStart:pos]),
+		Msg:    fmt.Sprintf(format, args...),
+		offset: pos,
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// isBare reports whether c may appear in a bare key.
+func isBare(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c == '_' || c == '-'
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dotted joins keys for error messages, quoting any that are not bare.
+func dotted(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k
+		if k == "" || strings.IndexFunc(k, func(r rune) bool { return r > 127 || !isBare(byte(r)) }) >= 0 {
+			parts[i] = strconv.Quote(k)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func exportTable(t *table) map[string]any {
+	m := make(map[string]any, len(t.values))
+	for k, v := range t.values {
+		m[k] = exportValue(v)
+	}
+	return m
+}
+
+func exportValue(v any) any {
+	switch v := v.(type) {
+	case *table:
+		return exportTable(v)
+	case *tableArray:
+		out := make([]map[string]any, len(v.tables))
+		for i, t := range v.tables {
+			out[i] = exportTable(t)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = exportValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// encodeString returns s as a TOML basic string.
+func encodeString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// typeName names the TOML type of a decoded value for errors.
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case float64:
+		return "a float"
+	case bool:
+		return "a boolean"
+	case time.Time, LocalDateTime, LocalDate, LocalTime:
+		return "a date or time"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "a table"
+	case []map[string]any:
+		return "an array of tables"
+	}
+	return fmt.Sprintf("%T", v)
+}
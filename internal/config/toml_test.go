@@ -0,0 +1,515 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tbl = map[string]any
+
+// The documents below are the examples from the TOML 1.0 spec.
+
+func TestDecodeTOML(t *testing.T) {
+	tz := func(h, m int) *time.Location { return time.FixedZone("", (h*60+m)*60) }
+
+	tests := []struct {
+		name string
+		doc  string
+		want tbl
+	}{
+		{
+			name: "comments",
+			doc: `# This is a full-line comment
+key = "value"  # This is a comment at the end of a line
+another = "# This is not a comment"
+`,
+			want: tbl{"key": "value", "another": "# This is not a comment"},
+		},
+		{
+			name: "bare and quoted keys",
+			doc: `key = "value"
+bare_key = "value"
+bare-key = "value"
+1234 = "value"
+"127.0.0.1" = "value"
+"character encoding" = "value"
+"ʎǝʞ" = "value"
+'key2' = "value"
+'quoted "value"' = "value"
+"" = "blank"
+`,
+			want: tbl{
+				"key": "value", "bare_key": "value", "bare-key": "value", "1234": "value",
+				"127.0.0.1": "value", "character encoding": "value", "ʎǝʞ": "value",
+				"key2": "value", `quoted "value"`: "value", "": "blank",
+			},
+		},
+		{
+			name: "dotted keys",
+			doc: `name = "Orange"
+physical.color = "orange"
+physical.shape = "round"
+site."google.com" = true
+fruit.name = "banana"
+fruit. color = "yellow"
+fruit . flavor = "banana"
+3.14159 = "pi"
+`,
+			want: tbl{
+				"name":     "Orange",
+				"physical": tbl{"color": "orange", "shape": "round"},
+				"site":     tbl{"google.com": true},
+				"fruit":    tbl{"name": "banana", "color": "yellow", "flavor": "banana"},
+				"3":        tbl{"14159": "pi"},
+			},
+		},
+		{
+			name: "basic strings",
+			doc: `str = "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."
+esc = "\b\f\r\\\U0001F600"
+`,
+			want: tbl{
+				"str": "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF.",
+				"esc": "\b\f\r\\\U0001F600",
+			},
+		},
+		{
+			name: "multi-line basic strings",
+			doc: `str1 = """
+Roses are red
+Violets are blue"""
+str2 = """
+The quick brown \
+
+
+  fox jumps over \
+    the lazy dog."""
+str3 = """\
+       The quick brown \
+       fox jumps over \
+       the lazy dog.\
+       """
+str4 = """Here are two quotation marks: "". Simple enough."""
+str5 = """Here are three quotation marks: ""\"."""
+str6 = """Here are fifteen quotation marks: ""\"""\"""\"""\"""\"."""
+str7 = """"This," she said, "is just a pointless statement.""""
+`,
+			want: tbl{
+				"str1": "Roses are red\nViolets are blue",
+				"str2": "The quick brown fox jumps over the lazy dog.",
+				"str3": "The quick brown fox jumps over the lazy dog.",
+				"str4": `Here are two quotation marks: "". Simple enough.`,
+				"str5": `Here are three quotation marks: """.`,
+				"str6": `Here are fifteen quotation marks: """"""""""""""".`,
+				"str7": `"This," she said, "is just a pointless statement."`,
+			},
+		},
+		{
+			name: "literal strings",
+			doc: `winpath  = 'C:\Users\nodejs\templates'
+winpath2 = '\\ServerX\admin$\system32\'
+quoted   = 'Tom "Dubs" Preston-Werner'
+regex    = '<\i\c*\s*>'
+regex2 = '''I [dw]on't need \d{2} apples'''
+lines  = '''
+The first newline is
+trimmed in raw strings.
+   All other whitespace
+   is preserved.
+'''
+quot15 = '''Here are fifteen quotation marks: """""""""""""""'''
+str = ''''That,' she said, 'is still pointless.''''
+`,
+			want: tbl{
+				"winpath":  `C:\Users\nodejs\templates`,
+				"winpath2": `\\ServerX\admin$\system32\`,
+				"quoted":   `Tom "Dubs" Preston-Werner`,
+				"regex":    `<\i\c*\s*>`,
+				"regex2":   `I [dw]on't need \d{2} apples`,
+				"lines":    "The first newline is\ntrimmed in raw strings.\n   All other whitespace\n   is preserved.\n",
+				"quot15":   `Here are fifteen quotation marks: """""""""""""""`,
+				"str":      `'That,' she said, 'is still pointless.'`,
+			},
+		},
+		{
+			name: "integers",
+			doc: `int1 = +99
+int2 = 42
+int3 = 0
+int4 = -17
+int5 = 1_000
+int6 = 5_349_221
+int7 = 53_49_221
+int8 = 1_2_3_4_5
+hex1 = 0xDEADBEEF
+hex2 = 0xdeadbeef
+hex3 = 0xdead_beef
+oct1 = 0o01234567
+oct2 = 0o755
+bin1 = 0b11010110
+max = 9223372036854775807
+min = -9223372036854775808
+`,
+			want: tbl{
+				"int1": int64(99), "int2": int64(42), "int3": int64(0), "int4": int64(-17),
+				"int5": int64(1000), "int6": int64(5349221), "int7": int64(5349221), "int8": int64(12345),
+				"hex1": int64(0xDEADBEEF), "hex2": int64(0xDEADBEEF), "hex3": int64(0xDEADBEEF),
+				"oct1": int64(0o1234567), "oct2": int64(0o755), "bin1": int64(0b11010110),
+				"max": int64(math.MaxInt64), "min": int64(math.MinInt64),
+			},
+		},
+		{
+			name: "floats",
+			doc: `flt1 = +1.0
+flt2 = 3.1415
+flt3 = -0.01
+flt4 = 5e+22
+flt5 = 1e06
+flt6 = -2E-2
+flt7 = 6.626e-34
+flt8 = 224_617.445_991_228
+sf1 = inf
+sf2 = +inf
+sf3 = -inf
+`,
+			want: tbl{
+				"flt1": 1.0, "flt2": 3.1415, "flt3": -0.01, "flt4": 5e+22,
+				"flt5": 1e06, "flt6": -2e-2, "flt7": 6.626e-34, "flt8": 224617.445991228,
+				"sf1": math.Inf(1), "sf2": math.Inf(1), "sf3": math.Inf(-1),
+			},
+		},
+		{
+			name: "booleans",
+			doc:  "bool1 = true\nbool2 = false\n",
+			want: tbl{"bool1": true, "bool2": false},
+		},
+		{
+			name: "dates and times",
+			doc: `odt1 = 1979-05-27T07:32:00Z
+odt2 = 1979-05-27T00:32:00-07:00
+odt3 = 1979-05-27T00:32:00.999999-07:00
+odt4 = 1979-05-27 07:32:00Z
+ldt1 = 1979-05-27T07:32:00
+ldt2 = 1979-05-27T00:32:00.999999
+ld1 = 1979-05-27
+lt1 = 07:32:00
+lt2 = 00:32:00.999999
+lt3 = 00:32:00.1234567891
+`,
+			want: tbl{
+				"odt1": time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+				"odt2": time.Date(1979, 5, 27, 0, 32, 0, 0, tz(-7, 0)),
+				"odt3": time.Date(1979, 5, 27, 0, 32, 0, 999999000, tz(-7, 0)),
+				"odt4": time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+				"ldt1": LocalDateTime{LocalDate{1979, 5, 27}, LocalTime{7, 32, 0, 0}},
+				"ldt2": LocalDateTime{LocalDate{1979, 5, 27}, LocalTime{0, 32, 0, 999999000}},
+				"ld1":  LocalDate{1979, 5, 27},
+				"lt1":  LocalTime{7, 32, 0, 0},
+				"lt2":  LocalTime{0, 32, 0, 999999000},
+				"lt3":  LocalTime{0, 32, 0, 123456789},
+			},
+		},
+		{
+			name: "arrays",
+			doc: `integers = [ 1, 2, 3 ]
+colors = [ "red", "yellow", "green" ]
+nested_arrays_of_ints = [ [ 1, 2 ], [3, 4, 5] ]
+nested_mixed_array = [ [ 1, 2 ], ["a", "b", "c"] ]
+string_array = [ "all", 'strings', """are the same""", '''type''' ]
+numbers = [ 0.1, 0.2, 0.5, 1, 2, 5 ]
+contributors = [
+  "Foo Bar <foo@example.com>",
+  { name = "Baz Qux", email = "bazqux@example.com", url = "https://example.com/bazqux" }
+]
+integers2 = [
+  1, 2, 3
+]
+integers3 = [
+  1,
+  2, # this is ok
+]
+empty = []
+`,
+			want: tbl{
+				"integers":              []any{int64(1), int64(2), int64(3)},
+				"colors":                []any{"red", "yellow", "green"},
+				"nested_arrays_of_ints": []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4), int64(5)}},
+				"nested_mixed_array":    []any{[]any{int64(1), int64(2)}, []any{"a", "b", "c"}},
+				"string_array":          []any{"all", "strings", "are the same", "type"},
+				"numbers":               []any{0.1, 0.2, 0.5, int64(1), int64(2), int64(5)},
+				"contributors": []any{
+					"Foo Bar <foo@example.com>",
+					tbl{"name": "Baz Qux", "email": "bazqux@example.com", "url": "https://example.com/bazqux"},
+				},
+				"integers2": []any{int64(1), int64(2), int64(3)},
+				"integers3": []any{int64(1), int64(2)},
+				"empty":     []any{},
+			},
+		},
+		{
+			name: "tables",
+			doc: `[table-1]
+key1 = "some string"
+key2 = 123
+
+[table-2]
+key1 = "another string"
+key2 = 456
+
+[dog."tater.man"]
+type.name = "pug"
+
+[a.b.c]            # this is best practice
+[ d.e.f ]          # same as [d.e.f]
+[ g .  h  . i ]    # same as [g.h.i]
+[ j . "ʞ" . 'l' ]  # same as [j."ʞ".'l']
+
+# [x] you
+# [x.y] don't
+# [x.y.z] need these
+[x.y.z.w] # for this to work
+
+[x] # defining a super-table afterward is ok
+`,
+			want: tbl{
+				"table-1": tbl{"key1": "some string", "key2": int64(123)},
+				"table-2": tbl{"key1": "another string", "key2": int64(456)},
+				"dog":     tbl{"tater.man": tbl{"type": tbl{"name": "pug"}}},
+				"a":       tbl{"b": tbl{"c": tbl{}}},
+				"d":       tbl{"e": tbl{"f": tbl{}}},
+				"g":       tbl{"h": tbl{"i": tbl{}}},
+				"j":       tbl{"ʞ": tbl{"l": tbl{}}},
+				"x":       tbl{"y": tbl{"z": tbl{"w": tbl{}}}},
+			},
+		},
+		{
+			name: "dotted keys and sub-tables",
+			doc: `fruit.apple.color = "red"
+fruit.apple.taste.sweet = true
+
+[fruit.apple.texture]  # you can add sub-tables
+smooth = true
+`,
+			want: tbl{"fruit": tbl{"apple": tbl{
+				"color":   "red",
+				"taste":   tbl{"sweet": true},
+				"texture": tbl{"smooth": true},
+			}}},
+		},
+		{
+			name: "inline tables",
+			doc: `name = { first = "Tom", last = "Preston-Werner" }
+point = { x = 1, y = 2 }
+animal = { type.name = "pug" }
+empty = {}
+`,
+			want: tbl{
+				"name":   tbl{"first": "Tom", "last": "Preston-Werner"},
+				"point":  tbl{"x": int64(1), "y": int64(2)},
+				"animal": tbl{"type": tbl{"name": "pug"}},
+				"empty":  tbl{},
+			},
+		},
+		{
+			name: "arrays of tables",
+			doc: `[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]  # empty table within the array
+
+[[products]]
+name = "Nail"
+sku = 284758393
+
+color = "gray"
+
+[[fruits]]
+name = "apple"
+
+[fruits.physical]  # subtable
+color = "red"
+shape = "round"
+
+[[fruits.varieties]]  # nested array of tables
+name = "red delicious"
+
+[[fruits.varieties]]
+name = "granny smith"
+
+
+[[fruits]]
+name = "banana"
+
+[[fruits.varieties]]
+name = "plantain"
+
+[meta]
+points = [ { x = 1, y = 2, z = 3 },
+           { x = 7, y = 8, z = 9 },
+           { x = 2, y = 4, z = 8 } ]
+`,
+			want: tbl{
+				"products": []map[string]any{
+					{"name": "Hammer", "sku": int64(738594937)},
+					{},
+					{"name": "Nail", "sku": int64(284758393), "color": "gray"},
+				},
+				"fruits": []map[string]any{
+					{
+						"name":      "apple",
+						"physical":  tbl{"color": "red", "shape": "round"},
+						"varieties": []map[string]any{{"name": "red delicious"}, {"name": "granny smith"}},
+					},
+					{
+						"name":      "banana",
+						"varieties": []map[string]any{{"name": "plantain"}},
+					},
+				},
+				"meta": tbl{"points": []any{
+					tbl{"x": int64(1), "y": int64(2), "z": int64(3)},
+					tbl{"x": int64(7), "y": int64(8), "z": int64(9)},
+					tbl{"x": int64(2), "y": int64(4), "z": int64(8)},
+				}},
+			},
+		},
+		{
+			name: "CRLF and BOM",
+			doc:  "\ufeff[a]\r\nb = \"\"\"\r\nc\r\nd\"\"\" # end\r\n",
+			want: tbl{"a": tbl{"b": "c\nd"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeTOML([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTOMLNaN(t *testing.T) {
+	got, err := decodeTOML([]byte("sf4 = nan\nsf5 = +nan\nsf6 = -nan\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"sf4", "sf5", "sf6"} {
+		if f, ok := got[k].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s = %#v, want NaN", k, got[k])
+		}
+	}
+}
+
+func TestDecodeTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		line, col int
+		msg       string
+	}{
+		// from the spec
+		{"missing value", "key = # INVALID", 1, 7, "expected a value"},
+		{"two pairs on a line", `first = "Tom" last = "Preston-Werner" # INVALID`, 1, 15, `expected end of line, found 'l'`},
+		{"missing key", `= "no key name"  # INVALID`, 1, 1, "expected key"},
+		{"duplicate key", "name = \"Tom\"\nname = \"Pradyun\"", 2, 1, "duplicate key name"},
+		{"duplicate quoted key", "spelling = \"favorite\"\n\"spelling\" = \"favourite\"", 2, 1, "duplicate key spelling"},
+		{"value then table", "fruit.apple = 1\nfruit.apple.smooth = true", 2, 1, "key fruit.apple is already defined"},
+		{"three quotes", `str5 = """Here are three quotation marks: """."""  # INVALID`, 1, 46, `found '.'`},
+		{"fifteen apostrophes", `apos15 = '''Here are fifteen apostrophes: ''''''''''''''''''  # INVALID`, 1, 43, "too many quotes"},
+		{"leading dot", "invalid_float_1 = .7", 1, 19, "invalid value .7"},
+		{"trailing dot", "invalid_float_2 = 7.", 1, 19, "invalid number 7."},
+		{"dot before exponent", "invalid_float_3 = 3.e+20", 1, 19, "invalid number 3.e+20"},
+		{"table twice", "[fruit]\napple = \"red\"\n\n[fruit]\norange = \"orange\"", 4, 1, "table fruit is already defined"},
+		{"table over value", "[fruit]\napple = \"red\"\n\n[fruit.apple]\ntexture = \"smooth\"", 4, 1, "key fruit.apple is already defined as a value"},
+		{"table over dotted keys", "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n\n[fruit.apple]", 5, 1, "table fruit.apple is already defined"},
+		{"dotted key into table", "[fruit.apple.taste]\n[fruit]\napple.taste.bitter = false", 3, 1, "table apple is already defined"},
+		{"extend inline table", "[product]\ntype = { name = \"Nail\" }\ntype.edible = false  # INVALID", 3, 1, "table type is already defined"},
+		{"inline table over dotted keys", "[product]\ntype.name = \"Nail\"\ntype = { edible = false }  # INVALID", 3, 1, "duplicate key type"},
+		{"header into inline table", "a = { b = 1 }\n[a.c]", 2, 1, "cannot add to inline table a"},
+		{"array of tables over table", "[fruit.physical]\ncolor = \"red\"\n\n[[fruit]]\nname = \"apple\"", 4, 1, "fruit is already defined and is not an array of tables"},
+		{"table over array of tables", "[[fruits]]\nname = \"apple\"\n\n[[fruits.varieties]]\nname = \"red delicious\"\n\n[fruits.varieties]\nname = \"granny smith\"", 7, 1, "fruits.varieties is already defined as an array of tables"},
+		{"array of tables over array", "fruits = []\n\n[[fruits]] # Not allowed", 3, 1, "fruits is already defined and is not an array of tables"},
+
+		// syntax
+		{"unclosed section header", "[color\nenabled = \"auto\"", 1, 7, "unclosed section header: expected ], found end of line"},
+		{"unclosed array of tables", "[[a]\n", 1, 5, "expected ]]"},
+		{"missing equals", "[color]\nenabled \"auto\"", 2, 9, "expected key = value"},
+		{"unclosed quote", "[color]\nenabled = \"auto\n", 2, 11, "unclosed quote"},
+		{"unclosed literal", "a = 'auto", 1, 5, "unclosed quote"},
+		{"unclosed multi-line string", "a = \"\"\"\nabc", 1, 5, "unclosed multi-line string"},
+		{"unclosed array", "a = [1, 2", 1, 5, "unclosed array"},
+		{"array separator", "a = [1 2]", 1, 8, "expected , or ] after array element"},
+		{"unquoted string", "type = file", 1, 8, `invalid value file: strings must be quoted, as in "file"`},
+		{"newline in inline table", "a = { b = 1,\nc = 2 }", 1, 13, "expected key, found end of line"},
+		{"trailing comma in inline table", "a = { b = 1, }", 1, 14, "trailing comma in inline table"},
+		{"text after header", "[a] b = 1", 1, 5, "expected end of line"},
+		{"bare carriage return", "a = 1\rb = 2", 1, 6, "expected end of line"},
+		{"invalid escape", `a = "\x41"`, 1, 6, `invalid escape sequence \x`},
+		{"surrogate escape", `a = "\uD800"`, 1, 6, "invalid unicode escape"},
+		{"control character", "a = \"b\x01\"", 1, 7, "control character U+0001 in string"},
+		{"control character in comment", "# \x7f", 1, 3, "control character U+007F in comment"},
+		{"invalid UTF-8", "a = \"\xff\"", 1, 6, "invalid UTF-8 in string"},
+		{"column counts characters", `"ʎǝʞ" = 1 2`, 1, 11, "expected end of line"},
+
+		// numbers
+		{"leading zero", "a = 01", 1, 5, "invalid number 01"},
+		{"leading zero with underscore", "a = 0_1", 1, 5, "invalid number 0_1"},
+		{"double underscore", "a = 1__2", 1, 5, "invalid number 1__2"},
+		{"leading underscore", "a = _1", 1, 5, "invalid value _1"},
+		{"trailing underscore", "a = 1_", 1, 5, "invalid number 1_"},
+		{"double sign", "a = +-1", 1, 5, "invalid number +-1"},
+		{"signed hex", "a = +0x1", 1, 5, "invalid number +0x1"},
+		{"bad hex digit", "a = 0xG", 1, 5, "invalid number 0xG"},
+		{"bad octal digit", "a = 0o8", 1, 5, "invalid number 0o8"},
+		{"integer overflow", "a = 9223372036854775808", 1, 5, "integer 9223372036854775808 out of range"},
+		{"float overflow", "a = 1e400", 1, 5, "float 1e400 out of range"},
+
+		// dates and times
+		{"invalid day", "a = 1979-02-30", 1, 5, "invalid date 1979-02-30"},
+		{"invalid month", "a = 1979-13-01", 1, 5, "invalid date 1979-13-01"},
+		{"short date", "a = 1979-5-27", 1, 5, "invalid date, want YYYY-MM-DD"},
+		{"invalid hour", "a = 24:00:00", 1, 5, "invalid time 24:00:00"},
+		{"missing seconds", "a = 1979-05-27T07:32", 1, 16, "invalid time, want HH:MM:SS"},
+		{"empty fraction", "a = 07:32:00.", 1, 14, "expected fractional seconds"},
+		{"invalid offset", "a = 1979-05-27T07:32:00+7", 1, 24, "invalid offset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTOML([]byte(tt.doc))
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want a ParseError", err)
+			}
+			if perr.Line != tt.line || perr.Column != tt.col || !strings.Contains(perr.Msg, tt.msg) {
+				t.Errorf("err = %q, want line %d, column %d: %s", err, tt.line, tt.col, tt.msg)
+			}
+		})
+	}
+}
+
+func TestLocalDateTimeString(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{LocalDate{1979, 5, 27}, "1979-05-27"},
+		{LocalTime{7, 32, 0, 0}, "07:32:00"},
+		{LocalTime{0, 32, 0, 999999000}, "00:32:00.999999"},
+		{LocalDateTime{LocalDate{1979, 5, 27}, LocalTime{7, 32, 5, 0}}, "1979-05-27T07:32:05"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.(interface{ String() string }).String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
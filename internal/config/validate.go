@@ -0,0 +1,301 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Problem is a mistake in a config file.
+type Problem struct {
+	Line    int    // line in the file, or 0 if it has none
+	Key     string // section.name the problem is with, if any
+	Msg     string
+	Warning bool // tsk ignores the mistake and runs anyway
+}
+
+func (p Problem) Error() string {
+	var b strings.Builder
+	if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Key != "" {
+		b.WriteString(p.Key + ": ")
+	}
+	b.WriteString(p.Msg)
+	return b.String()
+}
+
+// enums lists the settings that take one of a fixed set of values.
+var enums = map[string]struct {
+	what   string
+	values []string
+}{
+	"color.enabled": {"color mode", []string{"auto", "always", "never"}},
+	"storage.type":  {"storage type", []string{"file", "gist", "git", "events", "indexed"}},
+}
+
+// checkEnum returns an error if v is not one of the values key takes.
+func checkEnum(key, v string) error {
+	e, ok := enums[key]
+	if !ok || slices.Contains(e.values, v) {
+		return nil
+	}
+	return fmt.Errorf("%q is not a %s%s; use %s", v, e.what, suggest(v, e.values), strings.Join(e.values, ", "))
+}
+
+// Validate checks the contents of a config file and returns every
+// problem in it, in the order they appear. Unknown sections and keys,
+// and unquoted strings from older config files, are warnings; anything
+// that would make tsk misbehave is not. A file that
+// is not valid TOML has just the one problem.
+func Validate(data []byte) []Problem {
+	data, legacy := quoteLegacy(data)
+	doc, entries, err := parseTOML(data)
+	if err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			return []Problem{{Line: perr.Line, Msg: perr.Msg}}
+		}
+		return []Problem{{Msg: err.Error()}}
+	}
+
+	// line finds where path is set: its own line, the inline table
+	// holding it, or for a section, its first header or key
+	line := func(path ...string) int {
+		for _, e := range entries {
+			switch {
+			case slices.Equal(e.path, path),
+				!e.header && len(e.path) < len(path) && slices.Equal(e.path, path[:len(e.path)]),
+				len(path) == 1 && e.path[0] == path[0]:
+				return 1 + bytes.Count(data[:e.start], []byte("\n"))
+			}
+		}
+		return 0
+	}
+
+	var problems []Problem
+	for _, l := range legacy {
+		p := Problem{Line: 1 + bytes.Count(data[:l.offset], []byte("\n")), Msg: "unquoted value; write it as " + encodeString(l.value), Warning: true}
+		for _, e := range entries {
+			if e.value == l.offset {
+				p.Key = dotted(e.path)
+			}
+		}
+		problems = append(problems, p)
+	}
+	for name, v := range doc {
+		tbl, ok := v.(map[string]any)
+		if !sectionKnown(name) {
+			kind := "section"
+			if !ok {
+				kind = "key"
+			}
+			problems = append(problems, unknown(kind, name, line(name), sectionNames()))
+			continue
+		}
+		if !ok {
+			problems = append(problems, Problem{Line: line(name), Key: name, Msg: "expected a table, got " + typeName(v)})
+			continue
+		}
+		for key := range tbl {
+			if _, err := findSetting(name + "." + key); err != nil {
+				problems = append(problems, unknown("key", name+"."+key, line(name, key), Keys()))
+			}
+		}
+	}
+
+	cfg := DefaultConfig()
+	for _, s := range settings {
+		section, name, _ := strings.Cut(s.key, ".")
+		if _, ok := doc[section].(map[string]any); !ok {
+			continue
+		}
+		v, ok, err := lookup(doc, section, name)
+		if err == nil && ok {
+			err = s.set(&cfg, v)
+		}
+		if err != nil {
+			msg := strings.TrimPrefix(err.Error(), s.key+": ")
+			problems = append(problems, Problem{Line: line(section, name), Key: s.key, Msg: msg})
+		}
+	}
+
+	check := func(key, msg string, args ...any) {
+		section, name, _ := strings.Cut(key, ".")
+		problems = append(problems, Problem{Line: line(section, name), Key: key, Msg: fmt.Sprintf(msg, args...)})
+	}
+	for _, key := range Keys() {
+		v, _ := cfg.Get(key)
+		if err := checkEnum(key, v); err != nil {
+			check(key, "%v", err)
+		}
+	}
+
+	// settings the chosen storage cannot do without
+	required := map[string][]string{
+		"file":    {"storage.path"},
+		"git":     {"storage.git_dir"},
+		"events":  {"storage.events_path"},
+		"indexed": {"storage.indexed_path"},
+	}
+	for _, key := range required[cfg.Storage.Type] {
+		if v, _ := cfg.Get(key); v == "" {
+			check(key, "required for %s storage", cfg.Storage.Type)
+		}
+	}
+	if _, _, env := lookupEnv("storage.gist_token"); cfg.Storage.Type == "gist" && cfg.Storage.GistToken == "" && !env {
+		p := Problem{Key: "storage.gist_token", Msg: "required for gist storage (or set TSK_GIST_TOKEN)"}
+		if p.Line = line("storage", "gist_token"); p.Line == 0 {
+			p.Line = line("storage", "type")
+		}
+		problems = append(problems, p)
+	}
+	if cmd := cfg.Storage.PassphraseCmd; cmd != "" && strings.TrimSpace(cmd) == "" {
+		check("storage.passphrase_cmd", "is blank; give a command or remove the setting")
+	}
+	if id := cfg.Storage.GistID; strings.Contains(id, "/") {
+		check("storage.gist_id", "%q is a URL; use the ID at the end of it, %q", id, id[strings.LastIndexByte(strings.TrimRight(id, "/"), '/')+1:])
+	}
+
+	slices.SortStableFunc(problems, func(a, b Problem) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return problems
+}
+
+// CheckFile validates the config file at path. A missing file has no
+// problems.
+func CheckFile(path string) ([]Problem, error) {
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Validate(data), nil
+}
+
+// Check returns the problems in data that are not warnings, joined into
+// one error, or nil if there are none.
+func Check(data []byte) error {
+	var errs []error
+	for _, p := range Validate(data) {
+		if !p.Warning {
+			errs = append(errs, p)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unknown reports an unknown key or section.
+func unknown(kind, key string, line int, known []string) Problem {
+	return Problem{Line: line, Key: key, Msg: "unknown " + kind + suggestKey(key, known), Warning: true}
+}
+
+func sectionKnown(name string) bool {
+	return slices.Contains(sectionNames(), name)
+}
+
+func sectionNames() []string {
+	var names []string
+	for _, s := range settings {
+		section, _, _ := strings.Cut(s.key, ".")
+		if !slices.Contains(names, section) {
+			names = append(names, section)
+		}
+	}
+	return names
+}
+
+// suggestKey suggests the known key or section closest to key: first
+// one in the same section, then one in another section, for keys put
+// under the wrong header.
+func suggestKey(key string, known []string) string {
+	section, name, ok := strings.Cut(key, ".")
+	if !ok {
+		if s := closest(key, known); s != "" {
+			return fmt.Sprintf(" (did you mean %s?)", s)
+		}
+		return ""
+	}
+
+	var same, other []string
+	for _, k := range known {
+		if strings.HasPrefix(k, section+".") {
+			same = append(same, k)
+		} else {
+			other = append(other, k)
+		}
+	}
+	for _, keys := range [][]string{same, other} {
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			_, names[i], _ = strings.Cut(k, ".")
+		}
+		if n := closest(name, names); n != "" {
+			return fmt.Sprintf(" (did you mean %s?)", keys[slices.Index(names, n)])
+		}
+	}
+	return ""
+}
+
+// suggest is suggestKey for values.
+func suggest(v string, known []string) string {
+	if s := closest(v, known); s != "" {
+		return fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	return ""
+}
+
+// closest returns the candidate nearest to word, if any is near enough
+// to be a likely typo of it. Ties go to the longest common prefix.
+func closest(word string, candidates []string) string {
+	word = strings.ToLower(word)
+	best, bestDist, bestPrefix := "", len(word)/3+1, 0
+	for _, c := range candidates {
+		d, prefix := editDistance(word, c), commonPrefix(word, c)
+		if d < bestDist || d == bestDist && best != "" && prefix > bestPrefix {
+			best, bestDist, bestPrefix = c, d, prefix
+		}
+	}
+	return best
+}
+
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// swaps of adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []string // problems, warnings marked with a leading "?"
+	}{
+		{
+			name: "empty",
+			doc:  "",
+		},
+		{
+			name: "template",
+			doc:  Template,
+		},
+		{
+			name: "valid",
+			doc:  "[color]\nenabled = \"never\"\n[storage]\ntype = \"gist\"\ngist_token = \"t\"\ngist_id = \"abc123\"\n",
+		},
+		{
+			name: "unknown keys and sections",
+			doc: `title = "mine"
+[colour]
+enabled = "never"
+
+[storage]
+typ = "file"
+tokens = "x"
+gist_tokn = "t"
+
+[color]
+type = "git"
+`,
+			want: []string{
+				"?line 1: title: unknown key",
+				"?line 2: colour: unknown section (did you mean color?)",
+				"?line 6: storage.typ: unknown key (did you mean storage.type?)",
+				"?line 7: storage.tokens: unknown key",
+				"?line 8: storage.gist_tokn: unknown key (did you mean storage.gist_token?)",
+				"?line 11: color.type: unknown key (did you mean storage.type?)",
+			},
+		},
+		{
+			name: "bad enums",
+			doc:  "[color]\nenabled = false\n\n[storage]\ntype = \"gits\"\n",
+			want: []string{
+				`line 2: color.enabled: "false" is not a color mode; use auto, always, never`,
+				`line 5: storage.type: "gits" is not a storage type (did you mean "git"?); use file, gist, git, events, indexed`,
+			},
+		},
+		{
+			name: "case typo",
+			doc:  "[storage]\ntype = \"Events\"\n",
+			want: []string{
+				`line 2: storage.type: "Events" is not a storage type (did you mean "events"?); use file, gist, git, events, indexed`,
+			},
+		},
+		{
+			name: "wrong types",
+			doc:  "color = \"never\"\n[storage]\ngist_timeout = 30\ngit_dir = [\"a\"]\n[projects]\narchived = \"old\"\n",
+			want: []string{
+				"line 1: color: expected a table, got a string",
+				"line 3: storage.gist_timeout: expected a string, got an integer",
+				"line 4: storage.git_dir: expected a string, got an array",
+				`line 6: projects.archived: expected an array of strings, such as ["a", "b"]`,
+			},
+		},
+		{
+			name: "array of the wrong type",
+			doc:  "[projects]\narchived = [\"old\", 2]\n",
+			want: []string{"line 2: projects.archived: expected an array of strings, got an integer in the array"},
+		},
+		{
+			name: "bad duration",
+			doc:  "[storage]\ngist_timeout = \"soon\"\n",
+			want: []string{`line 2: storage.gist_timeout: invalid duration "soon" (use e.g. "30s" or "2m")`},
+		},
+		{
+			name: "incomplete gist settings",
+			doc:  "[storage]\ntype = \"gist\"\ngist_id = \"https://gist.github.com/me/abc123\"\n",
+			want: []string{
+				"line 2: storage.gist_token: required for gist storage (or set TSK_GIST_TOKEN)",
+				`line 3: storage.gist_id: "https://gist.github.com/me/abc123" is a URL; use the ID at the end of it, "abc123"`,
+			},
+		},
+		{
+			name: "blank passphrase command",
+			doc:  "[storage]\npassphrase_cmd = \"  \"\n",
+			want: []string{"line 2: storage.passphrase_cmd: is blank; give a command or remove the setting"},
+		},
+		{
+			name: "empty path",
+			doc:  "storage.type = \"git\"\nstorage.git_dir = \"\"\n",
+			want: []string{"line 2: storage.git_dir: required for git storage"},
+		},
+		{
+			name: "inline table",
+			doc:  "storage = { type = \"fiel\", colour = \"x\" }\n",
+			want: []string{
+				"?line 1: storage.colour: unknown key",
+				`line 1: storage.type: "fiel" is not a storage type (did you mean "file"?); use file, gist, git, events, indexed`,
+			},
+		},
+		{
+			name: "unquoted values",
+			doc:  "[storage]\ntype = git\ngit_dir = ~/my tasks # notes\n",
+			want: []string{
+				`?line 2: storage.type: unquoted value; write it as "git"`,
+				`?line 3: storage.git_dir: unquoted value; write it as "~/my tasks"`,
+			},
+		},
+		{
+			name: "syntax error",
+			doc:  "[storage\ntype = \"file\"\n",
+			want: []string{"line 1: unclosed section header: expected ], found end of line"},
+		},
+	}
+
+	clearEnv(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range Validate([]byte(tt.doc)) {
+				s := p.Error()
+				if p.Warning {
+					s = "?" + s
+				}
+				got = append(got, s)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateGistTokenFromEnv(t *testing.T) {
+	for _, env := range []string{"TSK_GIST_TOKEN", "TSK_STORAGE_GIST_TOKEN"} {
+		clearEnv(t)
+		t.Setenv(env, "t")
+		if problems := Validate([]byte("[storage]\ntype = \"gist\"\n")); len(problems) != 0 {
+			t.Errorf("%s: problems = %v, want none", env, problems)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check([]byte("[storage]\ntyp = \"gist\"\n")); err != nil {
+		t.Errorf("warnings only: err = %v, want nil", err)
+	}
+	err := Check([]byte("[color]\nenabled = \"sometimes\"\n[storage]\ntype = \"cloud\"\n"))
+	if err == nil || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("err = %v, want both problems", err)
+	}
+}
+
+func TestSetRejectsInvalidValue(t *testing.T) {
+	clearEnv(t)
+	p := writeConfig(t, "[storage]\ntype = \"file\"\n")
+	if err := Set(p, "storage.type", "gits"); err == nil || !strings.Contains(err.Error(), `did you mean "git"?`) {
+		t.Errorf("set storage.type gits: err = %v, want suggestion", err)
+	}
+	// gist settings are completed one at a time
+	if err := Set(p, "storage.type", "gist"); err != nil {
+		t.Errorf("set storage.type gist: %v", err)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"git", "git", 0},
+		{"gits", "git", 1},
+		{"tpye", "type", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
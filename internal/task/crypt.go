@@ -0,0 +1,332 @@
+package task
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sealedPrefix marks a value encrypted by a Cipher.
+const sealedPrefix = "tsk1:"
+
+// kdfIterations is the PBKDF2-HMAC-SHA256 work factor for new values.
+// It is stored with each value, so raising it does not strand old ones.
+var kdfIterations = 600_000
+
+const (
+	saltSize      = 16
+	maxIterations = 1 << 24 // anything above this is a corrupt value
+)
+
+var (
+	// ErrNoPassphrase is returned when tasks are encrypted but no
+	// passphrase was given to decrypt them.
+	ErrNoPassphrase = errors.New("tasks are encrypted but no passphrase is set")
+
+	// ErrWrongPassphrase is returned when a value fails to decrypt.
+	ErrWrongPassphrase = errors.New("cannot decrypt tasks: wrong passphrase or corrupted data")
+)
+
+// Cipher encrypts text with AES-256-GCM under a key derived from a
+// passphrase with PBKDF2. Each encrypted value is self-contained: it
+// carries the salt and work factor its key was derived with, so values
+// written on different machines can be mixed freely.
+//
+// A nil *Cipher encrypts nothing, but refuses to decrypt: its Open
+// methods return ErrNoPassphrase for encrypted values rather than pass
+// them on as text.
+type Cipher struct {
+	passphrase func() (string, error)
+	pass       string
+	passErr    error
+	asked      bool
+
+	keys map[string]cipher.AEAD // by salt and work factor
+	salt []byte                 // for new values; reused from the first one opened
+	iter int
+
+	// sealed maps text opened by OpenTasks back to its encrypted form,
+	// so SealTasks leaves unchanged fields byte-for-byte the same and
+	// stores that diff or merge tasks see only real changes
+	sealed map[string]string
+}
+
+// NewCipher returns a Cipher that calls passphrase the first time it
+// needs a key.
+func NewCipher(passphrase func() (string, error)) *Cipher {
+	return &Cipher{
+		passphrase: passphrase,
+		keys:       make(map[string]cipher.AEAD),
+		sealed:     make(map[string]string),
+	}
+}
+
+// Sealed reports whether s is a value encrypted by a Cipher.
+func Sealed(s string) bool {
+	return strings.HasPrefix(s, sealedPrefix)
+}
+
+// Encrypted reports whether any text in tasks is encrypted.
+func Encrypted(tasks []Task) bool {
+	for _, t := range tasks {
+		if Sealed(t.Title) || Sealed(t.Notes) || Sealed(t.Project) {
+			return true
+		}
+		for _, tag := range t.Tags {
+			if Sealed(tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Seal encrypts text. The empty string stays empty.
+func (c *Cipher) Seal(text string) (string, error) {
+	if c == nil || text == "" {
+		return text, nil
+	}
+	if c.salt == nil {
+		c.salt = make([]byte, saltSize)
+		if _, err := rand.Read(c.salt); err != nil {
+			return "", fmt.Errorf("generate salt: %w", err)
+		}
+		c.iter = kdfIterations
+	}
+	aead, err := c.key(c.salt, c.iter)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	buf := binary.BigEndian.AppendUint32(nil, uint32(c.iter))
+	buf = append(buf, c.salt...)
+	buf = append(buf, nonce...)
+	buf = aead.Seal(buf, nonce, []byte(text), nil)
+	return sealedPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Open decrypts a value made by Seal. Text that is not encrypted is
+// returned as it is.
+func (c *Cipher) Open(s string) (string, error) {
+	if !Sealed(s) {
+		return s, nil
+	}
+	if c == nil {
+		return "", ErrNoPassphrase
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(sealedPrefix):])
+	if err != nil || len(raw) < 4+saltSize {
+		return "", ErrWrongPassphrase
+	}
+	iter := int(binary.BigEndian.Uint32(raw))
+	salt := raw[4 : 4+saltSize]
+	if iter < 1 || iter > maxIterations {
+		return "", ErrWrongPassphrase
+	}
+	aead, err := c.key(salt, iter)
+	if err != nil {
+		return "", err
+	}
+	rest := raw[4+saltSize:]
+	if len(rest) < aead.NonceSize() {
+		return "", ErrWrongPassphrase
+	}
+	text, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+
+	if c.salt == nil {
+		// seal new values with the same key rather than derive another
+		c.salt, c.iter = salt, iter
+	}
+	return string(text), nil
+}
+
+// SealTasks returns a copy of tasks with their text — title, notes,
+// project and tags — encrypted. Text opened by OpenTasks is sealed to
+// the value it was opened from.
+func (c *Cipher) SealTasks(tasks []Task) ([]Task, error) {
+	if c == nil {
+		return tasks, nil
+	}
+	return mapText(tasks, func(text string) (string, error) {
+		if s, ok := c.sealed[text]; ok {
+			return s, nil
+		}
+		s, err := c.Seal(text)
+		if err != nil {
+			return "", err
+		}
+		c.sealed[text] = s
+		return s, nil
+	})
+}
+
+// OpenTasks returns a copy of tasks with their text decrypted.
+func (c *Cipher) OpenTasks(tasks []Task) ([]Task, error) {
+	if c == nil {
+		if Encrypted(tasks) {
+			return nil, ErrNoPassphrase
+		}
+		return tasks, nil
+	}
+	return mapText(tasks, func(s string) (string, error) {
+		text, err := c.Open(s)
+		if err != nil {
+			return "", err
+		}
+		if Sealed(s) {
+			c.sealed[text] = s
+		}
+		return text, nil
+	})
+}
+
+// key returns the AEAD for a salt and work factor, deriving it the
+// first time.
+func (c *Cipher) key(salt []byte, iter int) (cipher.AEAD, error) {
+	id := string(salt) + strconv.Itoa(iter)
+	if aead, ok := c.keys[id]; ok {
+		return aead, nil
+	}
+
+	if !c.asked {
+		c.asked = true
+		c.pass, c.passErr = c.passphrase()
+		if c.passErr == nil && c.pass == "" {
+			c.passErr = errors.New("empty passphrase")
+		}
+	}
+	if c.passErr != nil {
+		return nil, fmt.Errorf("passphrase: %w", c.passErr)
+	}
+
+	block, err := aes.NewCipher(pbkdf2([]byte(c.pass), salt, iter, 32))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c.keys[id] = aead
+	return aead, nil
+}
+
+// mapText returns a copy of tasks with fn applied to their text fields.
+func mapText(tasks []Task, fn func(string) (string, error)) ([]Task, error) {
+	out := Clone(tasks)
+	for i := range out {
+		t := &out[i]
+		for _, field := range []*string{&t.Title, &t.Notes, &t.Project} {
+			v, err := fn(*field)
+			if err != nil {
+				return nil, fmt.Errorf("task %d: %w", t.ID, err)
+			}
+			*field = v
+		}
+		for j, tag := range t.Tags {
+			v, err := fn(tag)
+			if err != nil {
+				return nil, fmt.Errorf("task %d: %w", t.ID, err)
+			}
+			t.Tags[j] = v
+		}
+	}
+	return out, nil
+}
+
+// pbkdf2 derives a key from a password as in RFC 8018, with
+// HMAC-SHA256 as the pseudorandom function. (crypto/pbkdf2 needs a
+// newer Go than this module supports.)
+func pbkdf2(password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	size := prf.Size()
+	blocks := (keyLen + size - 1) / size
+
+	dk := make([]byte, 0, blocks*size)
+	u := make([]byte, size)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, uint32(block)))
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-size:]
+		copy(u, t)
+
+		for range iter - 1 {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+// EncryptedStore wraps a Store so that the text of every task is
+// encrypted before it reaches the wrapped store, and decrypted when it
+// is loaded. Everything else — IDs, dates, status — is stored as it is,
+// so the wrapped store can still diff and merge tasks.
+//
+// Tasks saved unencrypted load as they are, and are encrypted by the
+// next Save. With a nil Cipher nothing is encrypted, and Load fails with
+// ErrNoPassphrase if the tasks were.
+type EncryptedStore struct {
+	Store
+	Cipher *Cipher
+}
+
+// NewEncryptedStore returns a Store that encrypts tasks with c.
+func NewEncryptedStore(store Store, c *Cipher) *EncryptedStore {
+	return &EncryptedStore{Store: store, Cipher: c}
+}
+
+// Load reads tasks from the wrapped store and decrypts them.
+func (s *EncryptedStore) Load(ctx context.Context) ([]Task, error) {
+	tasks, err := s.Store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.Cipher.OpenTasks(tasks)
+}
+
+// Save encrypts tasks and writes them to the wrapped store.
+func (s *EncryptedStore) Save(ctx context.Context, tasks []Task) error {
+	sealed, err := s.Cipher.SealTasks(tasks)
+	if err != nil {
+		return err
+	}
+	return s.Store.Save(ctx, sealed)
+}
+
+// Update runs fn on the decrypted tasks inside the wrapped store's Update.
+func (s *EncryptedStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	return s.Store.Update(ctx, func(tasks []Task) ([]Task, error) {
+		tasks, err := s.Cipher.OpenTasks(tasks)
+		if err != nil {
+			return nil, err
+		}
+		if tasks, err = fn(tasks); err != nil {
+			return nil, err
+		}
+		return s.Cipher.SealTasks(tasks)
+	})
+}
@@ -0,0 +1,226 @@
+package task
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// compile-time check: EncryptedStore implements Store
+var _ Store = (*EncryptedStore)(nil)
+
+// testCipher returns a Cipher for passphrase with a cheap work factor.
+func testCipher(t *testing.T, passphrase string) *Cipher {
+	t.Helper()
+	old := kdfIterations
+	kdfIterations = 10
+	t.Cleanup(func() { kdfIterations = old })
+	return NewCipher(func() (string, error) { return passphrase, nil })
+}
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914, section 11
+	tests := []struct {
+		password, salt string
+		iter           int
+		want           string
+	}{
+		{"passwd", "salt", 1, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2([]byte(tt.password), []byte(tt.salt), tt.iter, 64))
+		if got != tt.want {
+			t.Errorf("pbkdf2(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iter, got, tt.want)
+		}
+	}
+}
+
+func TestCipherTasks(t *testing.T) {
+	plain := []Task{
+		{ID: 1, Title: "call Acme about the invoice", Project: "acme", Tags: []string{"phone"}},
+		{ID: 2, Title: "review", Notes: "Acme wants it by Friday"},
+	}
+	c := testCipher(t, "secret")
+
+	sealed, err := c.SealTasks(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(sealed)
+	for _, word := range []string{"Acme", "acme", "phone", "review"} {
+		if strings.Contains(string(data), word) {
+			t.Errorf("sealed tasks contain %q: %s", word, data)
+		}
+	}
+	if sealed[1].Project != "" {
+		t.Errorf("empty project sealed to %q", sealed[1].Project)
+	}
+	if !Encrypted(sealed) || Encrypted(plain) {
+		t.Error("Encrypted does not tell sealed tasks from plain ones")
+	}
+
+	// a second cipher, as in the next run, opens them and seals
+	// untouched fields to the same values
+	c2 := testCipher(t, "secret")
+	opened, err := c2.OpenTasks(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameTasks(opened, plain) {
+		t.Fatalf("opened = %+v, want %+v", opened, plain)
+	}
+	opened[1].Title = "review again"
+	resealed, err := c2.SealTasks(opened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed[0].Title != sealed[0].Title || resealed[1].Notes != sealed[1].Notes {
+		t.Error("unchanged fields were sealed to new values")
+	}
+	if resealed[1].Title == sealed[1].Title {
+		t.Error("changed title kept its old value")
+	}
+
+	if _, err := testCipher(t, "wrong").OpenTasks(sealed); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase: err = %v, want ErrWrongPassphrase", err)
+	}
+	var none *Cipher
+	if _, err := none.OpenTasks(sealed); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("nil cipher: err = %v, want ErrNoPassphrase", err)
+	}
+	if got, err := none.SealTasks(plain); err != nil || !sameTasks(got, plain) {
+		t.Errorf("nil cipher sealed tasks: %+v, %v", got, err)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	var mu sync.Mutex
+	var content string
+	srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPatch {
+			var body gistRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			content = body.Files[gistFilename].Content
+		}
+		json.NewEncoder(w).Encode(gistResponse{
+			ID:      "abc123",
+			Files:   map[string]gistFileContent{gistFilename: {Content: content}},
+			History: []gistVersion{{Version: strings.Repeat("v", len(content)%7+1)}},
+		})
+	})
+	old := gistAPIBase
+	gistAPIBase = srv.URL
+	t.Cleanup(func() { gistAPIBase = old })
+
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		store Store
+		raw   func() string
+	}{
+		{"file", NewFileStore(filepath.Join(dir, "tasks.json")), func() string {
+			data, _ := os.ReadFile(filepath.Join(dir, "tasks.json"))
+			return string(data)
+		}},
+		{"gist", NewGistStore("token", "abc123"), func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return content
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewEncryptedStore(tt.store, testCipher(t, "secret"))
+			update(t, s, func(tasks []Task) []Task {
+				tasks = Add(tasks, "call Acme", PriorityHigh)
+				return Add(tasks, "invoice Acme", PriorityNone)
+			})
+			update(t, s, func(tasks []Task) []Task {
+				tasks, _, _ = Done(tasks, 1)
+				return tasks
+			})
+
+			raw := tt.raw()
+			if raw == "" || strings.Contains(raw, "Acme") {
+				t.Errorf("stored tasks are not encrypted: %s", raw)
+			}
+
+			tasks, err := NewEncryptedStore(tt.store, testCipher(t, "secret")).Load(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(tasks); got != "1:call Acme 2:invoice Acme" || !tasks[0].Done {
+				t.Errorf("loaded %s (task 1 done: %v)", got, len(tasks) > 0 && tasks[0].Done)
+			}
+			if _, err := NewEncryptedStore(tt.store, nil).Load(ctx); !errors.Is(err, ErrNoPassphrase) {
+				t.Errorf("load without passphrase: err = %v, want ErrNoPassphrase", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStorePlainTasks(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	update(t, NewFileStore(path), func(tasks []Task) []Task { return Add(tasks, "call Acme", PriorityNone) })
+
+	// unencrypted tasks load as they are, and the next save encrypts them
+	s := NewEncryptedStore(NewFileStore(path), testCipher(t, "secret"))
+	update(t, s, func(tasks []Task) []Task {
+		if got := summary(tasks); got != "1:call Acme" {
+			t.Errorf("loaded %s", got)
+		}
+		return Add(tasks, "other", PriorityNone)
+	})
+	tasks, err := NewFileStore(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || !Sealed(tasks[0].Title) || !Sealed(tasks[1].Title) {
+		t.Errorf("stored tasks = %+v, want both encrypted", tasks)
+	}
+}
+
+func TestJournalEncrypted(t *testing.T) {
+	ctx := context.Background()
+	c := testCipher(t, "secret")
+	store := NewEncryptedStore(tempStore(t), c)
+	journal := tempJournal(t)
+	journal.Cipher = c
+
+	run(t, store, journal, "add call Acme", func(ts []Task) []Task { return Add(ts, "call Acme", "") })
+	data, err := os.ReadFile(journal.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "Acme") {
+		t.Errorf("journal is not encrypted: %s", data)
+	}
+
+	if _, _, err := NewJournal(journal.Path).Recent(1); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("recent without passphrase: err = %v, want ErrNoPassphrase", err)
+	}
+	if op, err := journal.Undo(ctx, store); err != nil || op.Command != "add call Acme" {
+		t.Errorf("undo = %q, %v", op.Command, err)
+	}
+
+	if err := journal.SetCipher(nil); err != nil {
+		t.Fatal(err)
+	}
+	ops, _, err := NewJournal(journal.Path).Recent(1)
+	if err != nil || len(ops) != 1 {
+		t.Errorf("recent after decrypting = %+v, %v", ops, err)
+	}
+}
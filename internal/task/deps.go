@@ -0,0 +1,144 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Completion describes the effect of marking tasks done.
+type Completion struct {
+	Completed []int // tasks marked done, subtasks before parents
+	Unblocked []int // pending tasks whose last open dependency was completed
+	Spawned   []int // next occurrences created for recurring tasks
+}
+
+// Block records that the task with the given ID cannot start until
+// task on is done. Returns an error if either task is not found or the
+// dependency would create a cycle.
+func Block(tasks []Task, id, on int) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	if Find(tasks, on) == nil {
+		return fmt.Errorf("task %d: not found", on)
+	}
+	if id == on || dependsOn(tasks, on, id, make(map[int]bool)) {
+		return fmt.Errorf("task %d: cannot depend on %d (cycle)", id, on)
+	}
+	if !slices.Contains(t.DependsOn, on) {
+		t.DependsOn = append(t.DependsOn, on)
+	}
+	return nil
+}
+
+// Unblock removes the dependency of the given task on task on.
+// An on of 0 removes all of its dependencies.
+// Returns an error if the task is not found or has no such dependency.
+func Unblock(tasks []Task, id, on int) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	if on == 0 {
+		t.DependsOn = nil
+		return nil
+	}
+	if !slices.Contains(t.DependsOn, on) {
+		return fmt.Errorf("task %d: does not depend on %d", id, on)
+	}
+	t.DependsOn = slices.DeleteFunc(t.DependsOn, func(d int) bool { return d == on })
+	if len(t.DependsOn) == 0 {
+		t.DependsOn = nil
+	}
+	return nil
+}
+
+// dependsOn reports whether task id transitively depends on target.
+func dependsOn(tasks []Task, id, target int, seen map[int]bool) bool {
+	if seen[id] {
+		return false
+	}
+	seen[id] = true
+	t := Find(tasks, id)
+	if t == nil {
+		return false
+	}
+	for _, d := range t.DependsOn {
+		if d == target || dependsOn(tasks, d, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenDependencies returns the IDs of pending tasks that t waits on.
+func OpenDependencies(tasks []Task, t Task) []int {
+	var out []int
+	for _, d := range t.DependsOn {
+		if dep := Find(tasks, d); dep != nil && !dep.Done {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Dependents returns the tasks that depend directly on the given task.
+func Dependents(tasks []Task, id int) []Task {
+	var out []Task
+	for _, t := range tasks {
+		if slices.Contains(t.DependsOn, id) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Blocked matches pending tasks that wait on at least one pending task.
+// tasks is the full list used to resolve dependencies.
+func Blocked(tasks []Task) Filter {
+	return func(t Task) bool {
+		return !t.Done && len(OpenDependencies(tasks, t)) > 0
+	}
+}
+
+// Ready matches pending tasks with no pending dependencies.
+// tasks is the full list used to resolve dependencies.
+func Ready(tasks []Task) Filter {
+	return func(t Task) bool {
+		return !t.Done && len(OpenDependencies(tasks, t)) == 0
+	}
+}
+
+// unblockedBy returns pending tasks that depend on one of the completed
+// IDs and no longer wait on anything.
+func unblockedBy(tasks []Task, completed []int) []int {
+	var out []int
+	for _, t := range tasks {
+		if t.Done || len(OpenDependencies(tasks, t)) > 0 {
+			continue
+		}
+		for _, d := range t.DependsOn {
+			if slices.Contains(completed, d) {
+				out = append(out, t.ID)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// dropReferences removes dependencies on tasks that no longer exist.
+func dropReferences(tasks []Task) {
+	for i := range tasks {
+		if len(tasks[i].DependsOn) == 0 {
+			continue
+		}
+		tasks[i].DependsOn = slices.DeleteFunc(tasks[i].DependsOn, func(d int) bool {
+			return Find(tasks, d) == nil
+		})
+		if len(tasks[i].DependsOn) == 0 {
+			tasks[i].DependsOn = nil
+		}
+	}
+}
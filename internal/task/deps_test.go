@@ -0,0 +1,142 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		deps     map[int][]int
+		id, on   int
+		wantErr  bool
+		wantDeps []int
+	}{
+		{"simple", nil, 2, 1, false, []int{1}},
+		{"duplicate", map[int][]int{2: {1}}, 2, 1, false, []int{1}},
+		{"second dependency", map[int][]int{3: {1}}, 3, 2, false, []int{1, 2}},
+		{"self", nil, 1, 1, true, nil},
+		{"direct cycle", map[int][]int{1: {2}}, 2, 1, true, nil},
+		{"transitive cycle", map[int][]int{1: {2}, 2: {3}}, 3, 1, true, nil},
+		{"missing task", nil, 99, 1, true, nil},
+		{"missing dependency", nil, 1, 99, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []Task{{ID: 1}, {ID: 2}, {ID: 3}}
+			for id, deps := range tt.deps {
+				Find(tasks, id).DependsOn = deps
+			}
+
+			err := Block(tasks, tt.id, tt.on)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := Find(tasks, tt.id).DependsOn; !slices.Equal(got, tt.wantDeps) {
+				t.Errorf("DependsOn = %v, want %v", got, tt.wantDeps)
+			}
+		})
+	}
+}
+
+func TestUnblock(t *testing.T) {
+	tasks := []Task{{ID: 1}, {ID: 2}, {ID: 3, DependsOn: []int{1, 2}}}
+
+	if err := Unblock(tasks, 3, 1); err != nil {
+		t.Fatalf("unblock: %v", err)
+	}
+	if got := tasks[2].DependsOn; !slices.Equal(got, []int{2}) {
+		t.Errorf("DependsOn = %v, want [2]", got)
+	}
+
+	if err := Unblock(tasks, 3, 1); err == nil {
+		t.Error("expected error for missing dependency")
+	}
+
+	tasks[2].DependsOn = []int{1, 2}
+	if err := Unblock(tasks, 3, 0); err != nil {
+		t.Fatalf("unblock all: %v", err)
+	}
+	if tasks[2].DependsOn != nil {
+		t.Errorf("DependsOn = %v, want nil", tasks[2].DependsOn)
+	}
+}
+
+func TestBlockedAndReady(t *testing.T) {
+	tasks := []Task{
+		{ID: 1},
+		{ID: 2, Done: true},
+		{ID: 3, DependsOn: []int{1}},
+		{ID: 4, DependsOn: []int{2}},
+		{ID: 5, DependsOn: []int{1}, Done: true},
+	}
+
+	ids := func(ts []Task) []int {
+		var out []int
+		for _, t := range ts {
+			out = append(out, t.ID)
+		}
+		return out
+	}
+
+	if got := ids(List(tasks, Blocked(tasks))); !slices.Equal(got, []int{3}) {
+		t.Errorf("blocked = %v, want [3]", got)
+	}
+	if got := ids(List(tasks, Ready(tasks))); !slices.Equal(got, []int{1, 4}) {
+		t.Errorf("ready = %v, want [1 4]", got)
+	}
+	if got := ids(Dependents(tasks, 1)); !slices.Equal(got, []int{3, 5}) {
+		t.Errorf("dependents = %v, want [3 5]", got)
+	}
+}
+
+func TestDoneReportsUnblocked(t *testing.T) {
+	tasks := []Task{
+		{ID: 1},
+		{ID: 2},
+		{ID: 3, DependsOn: []int{1}},
+		{ID: 4, DependsOn: []int{1, 2}},
+	}
+
+	tasks, c, err := Done(tasks, 1)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if !slices.Equal(c.Unblocked, []int{3}) {
+		t.Errorf("unblocked = %v, want [3]", c.Unblocked)
+	}
+
+	tasks, c, err = Done(tasks, 2)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if !slices.Equal(c.Unblocked, []int{4}) {
+		t.Errorf("unblocked = %v, want [4]", c.Unblocked)
+	}
+}
+
+func TestRemoveDropsDependencies(t *testing.T) {
+	tasks := []Task{
+		{ID: 1},
+		{ID: 2, DependsOn: []int{1, 3}},
+		{ID: 3},
+	}
+
+	tasks, err := Remove(tasks, 1)
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if got := Find(tasks, 2).DependsOn; !slices.Equal(got, []int{3}) {
+		t.Errorf("DependsOn = %v, want [3]", got)
+	}
+
+	_, remaining := ClearDone([]Task{{ID: 1, Done: true}, {ID: 2, DependsOn: []int{1}}})
+	if remaining[0].DependsOn != nil {
+		t.Errorf("DependsOn after clear = %v, want nil", remaining[0].DependsOn)
+	}
+}
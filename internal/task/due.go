@@ -0,0 +1,100 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDue parses a due date relative to now. Accepted forms are
+// YYYY-MM-DD, "today", "tomorrow", a weekday name ("friday", "fri"),
+// or an offset like "3d" / "+2w". The result is midnight local time.
+func ParseDue(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := startOfDay(now)
+
+	switch s {
+	case "":
+		return time.Time{}, fmt.Errorf("empty due date")
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	if wd, ok := parseWeekday(s); ok {
+		diff := (int(wd) - int(today.Weekday()) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return today.AddDate(0, 0, diff), nil
+	}
+
+	if n, unit, ok := parseOffset(s); ok {
+		switch unit {
+		case 'd':
+			return today.AddDate(0, 0, n), nil
+		case 'w':
+			return today.AddDate(0, 0, 7*n), nil
+		case 'm':
+			return today.AddDate(0, n, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid due date: %s (use YYYY-MM-DD, today, tomorrow, a weekday or 3d/2w/1m)", s)
+}
+
+// parseWeekday matches full or three-letter weekday names.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseOffset parses "3d", "+2w" or "1m" into a count and unit.
+func parseOffset(s string) (int, byte, bool) {
+	s = strings.TrimPrefix(s, "+")
+	if len(s) < 2 {
+		return 0, 0, false
+	}
+	unit := s[len(s)-1]
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n < 0 {
+		return 0, 0, false
+	}
+	return n, unit, true
+}
+
+// startOfDay returns midnight of t's day in t's location.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// DaysUntil returns the number of calendar days from now until due.
+// Negative values mean the due date has passed.
+func DaysUntil(due, now time.Time) int {
+	a := startOfDay(now)
+	b := startOfDay(due.In(now.Location()))
+	return int(b.Sub(a).Round(24*time.Hour) / (24 * time.Hour))
+}
+
+// SetDue sets or clears (nil) the due date of the task with the given ID.
+// Returns an error if the ID is not found.
+func SetDue(tasks []Task, id int, due *time.Time) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	t.Due = due
+	return nil
+}
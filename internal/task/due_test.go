@@ -0,0 +1,133 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDue(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 11, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"today", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), false},
+		{"tomorrow", time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), false},
+		{"2026-04-01", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), false},
+		{"friday", time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC), false},
+		{"fri", time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC), false},
+		{"wednesday", time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC), false},
+		{"3d", time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC), false},
+		{"+2w", time.Date(2026, 3, 25, 0, 0, 0, 0, time.UTC), false},
+		{"1m", time.Date(2026, 4, 11, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"someday", time.Time{}, true},
+		{"3y", time.Time{}, true},
+		{"2026-13-01", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDue(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseDue(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysUntil(t *testing.T) {
+	now := time.Date(2026, 3, 11, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		due  time.Time
+		want int
+	}{
+		{"today", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC), 0},
+		{"tomorrow", time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), 1},
+		{"in a week", time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC), 7},
+		{"yesterday", time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), -1},
+		{"three days ago", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), -3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DaysUntil(tt.due, now); got != tt.want {
+				t.Errorf("DaysUntil = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDue(t *testing.T) {
+	due := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "a"}}
+
+	if err := SetDue(tasks, 1, &due); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if tasks[0].Due == nil || !tasks[0].Due.Equal(due) {
+		t.Errorf("Due = %v, want %v", tasks[0].Due, due)
+	}
+
+	if err := SetDue(tasks, 1, nil); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if tasks[0].Due != nil {
+		t.Errorf("Due = %v, want nil", tasks[0].Due)
+	}
+
+	if err := SetDue(tasks, 99, &due); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
+
+func TestListDueFilters(t *testing.T) {
+	now := time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC)
+	past := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)
+	today := time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	future := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	tasks := []Task{
+		{ID: 1, Title: "overdue", Due: &past},
+		{ID: 2, Title: "today", Due: &today},
+		{ID: 3, Title: "later", Due: &future},
+		{ID: 4, Title: "no due"},
+		{ID: 5, Title: "overdue but done", Done: true, Due: &past},
+	}
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		wantIDs []int
+	}{
+		{"overdue", []Filter{Overdue(now)}, []int{1}},
+		{"due before tomorrow", []Filter{DueBefore(today.AddDate(0, 0, 1))}, []int{1, 2, 5}},
+		{"pending due before tomorrow", []Filter{FilterPending, DueBefore(today.AddDate(0, 0, 1))}, []int{1, 2}},
+		{"no filters", nil, []int{1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := List(tasks, tt.filters...)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("len = %d, want %d", len(got), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got[i].ID != id {
+					t.Errorf("result[%d].ID = %d, want %d", i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// snapshotEvery is how many events EventStore appends before it
+// compacts the log, so Load never has to replay more than this many.
+const snapshotEvery = 200
+
+// EventType names what happened to a task in an Event.
+type EventType string
+
+const (
+	EventCreated   EventType = "created"
+	EventCompleted EventType = "completed"
+	EventEdited    EventType = "edited"
+	EventRemoved   EventType = "removed"
+	EventTagged    EventType = "tagged"
+	EventSnapshot  EventType = "snapshot"
+)
+
+// Event is one line of an EventStore log.
+type Event struct {
+	Time time.Time `json:"time"` // for completed events, the completion time
+	Type EventType `json:"type"`
+	ID   int       `json:"id"`
+
+	// Task is the task afterwards, for created and edited events, and for
+	// completed events that changed more than Done.
+	Task *Task    `json:"task,omitempty"`
+	Tags []string `json:"tags,omitempty"` // tagged: the tags afterwards
+
+	// Tasks is every task as of a snapshot, which starts a compacted log.
+	Tasks []Task `json:"tasks,omitempty"`
+}
+
+// Change is an Event together with the task before and after it.
+type Change struct {
+	Event
+	Before *Task // nil for created and snapshot
+	After  *Task // nil for removed
+}
+
+// Fields lists the fields the change touched. In each FieldDiff, Ours
+// holds the value before the change and Theirs the value after it.
+func (c Change) Fields() []FieldDiff {
+	return diffFields(c.Before, c.After)
+}
+
+// EventStore persists tasks as an append-only log of JSON lines, one
+// Event per change, and folds the log back into tasks on Load. Every
+// snapshotEvery events the log is compacted: it is replaced by a single
+// snapshot event, so it stays small and Load never replays much of it,
+// and the events since then serve as an audit trail.
+type EventStore struct {
+	Path string
+
+	tasks   []Task // folded state as of end
+	size    int64  // log size seen by the last Load or Save
+	end     int64  // offset just past the last complete event
+	pending int    // events after the last snapshot
+	loaded  bool
+}
+
+// NewEventStore returns an EventStore that appends to the given path.
+func NewEventStore(path string) *EventStore {
+	return &EventStore{Path: path}
+}
+
+// Load folds the log into tasks. Returns an empty slice if the log does
+// not exist.
+func (s *EventStore) Load(ctx context.Context) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events, end, size, err := s.readEvents(0)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	pending := 0
+	for _, e := range events {
+		tasks = applyEvent(tasks, e)
+		pending++
+		if e.Type == EventSnapshot {
+			pending = 0
+		}
+	}
+	s.tasks, s.size, s.end, s.pending, s.loaded = Clone(tasks), size, end, pending, true
+	return tasks, nil
+}
+
+// Save appends an event for every task that changed since the last Load
+// or Save. If the log has grown since then, Save returns a
+// *ConflictError. Every snapshotEvery events the log is compacted.
+func (s *EventStore) Save(ctx context.Context, tasks []Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.loaded {
+		if _, err := s.Load(ctx); err != nil {
+			return err
+		}
+	} else {
+		size, err := s.logSize()
+		if err != nil {
+			return err
+		}
+		if size != s.size {
+			return &ConflictError{Loaded: strconv.FormatInt(s.size, 10), Current: strconv.FormatInt(size, 10)}
+		}
+	}
+
+	events := diffEvents(s.tasks, tasks, time.Now())
+	if len(events) == 0 {
+		return nil
+	}
+	if err := s.append(events); err != nil {
+		return fmt.Errorf("write %s: %w", s.Path, err)
+	}
+	s.tasks = Clone(tasks)
+	s.pending += len(events)
+
+	if s.pending >= snapshotEvery {
+		return s.Compact()
+	}
+	return nil
+}
+
+// Update runs a load-modify-save cycle under the same lock as FileStore.
+func (s *EventStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	return lockedUpdate(ctx, s.Path+".lock", s, fn)
+}
+
+// Compact replaces the log with a snapshot of the tasks as of the last
+// Load or Save, dropping the events before it. If the log has grown
+// since then, Compact returns a *ConflictError, like Save.
+func (s *EventStore) Compact() error {
+	if !s.loaded {
+		return nil
+	}
+	size, err := s.logSize()
+	if err != nil {
+		return err
+	}
+	if size != s.size {
+		return &ConflictError{Loaded: strconv.FormatInt(s.size, 10), Current: strconv.FormatInt(size, 10)}
+	}
+
+	data, err := json.Marshal(Event{Time: time.Now(), Type: EventSnapshot, Tasks: s.tasks})
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	data = append(data, '\n')
+	if err := writeFileAtomic(s.Path, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", s.Path, err)
+	}
+	s.size, s.end, s.pending = int64(len(data)), int64(len(data)), 0
+	return nil
+}
+
+// History replays the log and returns every change to task id, oldest
+// first. History before the last compaction is gone: if the task existed
+// then, the first change is the snapshot, with the task as it was.
+func (s *EventStore) History(ctx context.Context, id int) ([]Change, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	events, _, _, err := s.readEvents(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	var current []Task
+	for _, e := range events {
+		if e.Type == EventSnapshot {
+			current = applyEvent(current, e)
+			if t := taskPtr(current, id); t != nil {
+				changes = append(changes, Change{Event: Event{Time: e.Time, Type: e.Type, ID: id}, After: t})
+			}
+			continue
+		}
+		if e.ID != id {
+			continue
+		}
+		c := Change{Event: e, Before: taskPtr(current, id)}
+		current = applyEvent(current, e)
+		c.After = taskPtr(current, id)
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// readEvents decodes the events from offset to the end of the log. It
+// returns the offset just past the last complete line and the log size;
+// an unterminated last line, left by a crash mid-append, is skipped.
+// If the log is shorter than offset, end is 0.
+func (s *EventStore) readEvents(offset int64) (events []Event, end, size int64, err error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, 0, nil
+		}
+		return nil, 0, 0, fmt.Errorf("open %s: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("stat %s: %w", s.Path, err)
+	}
+	size = info.Size()
+	if size < offset {
+		return nil, 0, size, nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, 0, fmt.Errorf("seek %s: %w", s.Path, err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("read %s: %w", s.Path, err)
+	}
+
+	end = offset
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if raw := bytes.TrimSpace(data[:i]); len(raw) > 0 {
+			var e Event
+			if err := json.Unmarshal(raw, &e); err != nil {
+				return nil, 0, 0, fmt.Errorf("%s: bad event at byte %d: %w", s.Path, end, err)
+			}
+			events = append(events, e)
+		}
+		end += int64(i + 1)
+		data = data[i+1:]
+	}
+	return events, end, size, nil
+}
+
+// append writes events to the log, first dropping any torn line left
+// after the last complete event.
+func (s *EventStore) append(events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if s.size != s.end {
+		if err := f.Truncate(s.end); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	s.end += int64(buf.Len())
+	s.size = s.end
+	return nil
+}
+
+func (s *EventStore) logSize() (int64, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("stat %s: %w", s.Path, err)
+	}
+	return info.Size(), nil
+}
+
+// diffEvents returns the events that turn before into after, in ID
+// order. Completing a task and changing only its tags get their own
+// event types; any other change is recorded as an edit of the whole task.
+// A completion that changed more than Done and CompletedAt, such as
+// stopping the task's timer, carries the whole task as well.
+func diffEvents(before, after []Task, now time.Time) []Event {
+	old, cur := byID(before), byID(after)
+	ids := make(map[int]bool, len(cur))
+	for id := range old {
+		ids[id] = true
+	}
+	for id := range cur {
+		ids[id] = true
+	}
+
+	var events []Event
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		o, inOld := old[id]
+		c, inCur := cur[id]
+		switch {
+		case !inOld:
+			t := c
+			events = append(events, Event{Time: now, Type: EventCreated, ID: id, Task: &t})
+		case !inCur:
+			events = append(events, Event{Time: now, Type: EventRemoved, ID: id})
+		case sameTask(o, c):
+		case !o.Done && c.Done:
+			e := Event{Time: now, Type: EventCompleted, ID: id}
+			if c.CompletedAt != nil {
+				e.Time = *c.CompletedAt
+			}
+			if c.CompletedAt == nil || !sameTask(completed(o, *c.CompletedAt), c) {
+				t := c
+				e.Task = &t
+			}
+			events = append(events, e)
+		case sameTask(withTags(o, c.Tags), c):
+			events = append(events, Event{Time: now, Type: EventTagged, ID: id, Tags: c.Tags})
+		default:
+			t := c
+			events = append(events, Event{Time: now, Type: EventEdited, ID: id, Task: &t})
+		}
+	}
+	return events
+}
+
+// applyEvent folds one event into tasks. Events for tasks that do not
+// exist are ignored, except those carrying the whole task, which
+// recreate it.
+func applyEvent(tasks []Task, e Event) []Task {
+	if e.Type == EventSnapshot {
+		return Clone(e.Tasks)
+	}
+	i := slices.IndexFunc(tasks, func(t Task) bool { return t.ID == e.ID })
+	if e.Type == EventCompleted && e.Task != nil {
+		e.Type = EventEdited
+	}
+	switch e.Type {
+	case EventCreated, EventEdited:
+		if e.Task == nil {
+			return tasks
+		}
+		if i < 0 {
+			return append(tasks, Clone([]Task{*e.Task})...)
+		}
+		tasks[i] = Clone([]Task{*e.Task})[0]
+	case EventRemoved:
+		if i >= 0 {
+			return slices.Delete(tasks, i, i+1)
+		}
+	case EventCompleted:
+		if i >= 0 {
+			tasks[i] = completed(tasks[i], e.Time)
+		}
+	case EventTagged:
+		if i >= 0 {
+			tasks[i] = withTags(tasks[i], slices.Clone(e.Tags))
+		}
+	}
+	return tasks
+}
+
+// completed returns a copy of t marked done at the given time.
+func completed(t Task, at time.Time) Task {
+	t.Done = true
+	t.CompletedAt = &at
+	return t
+}
+
+// taskPtr returns a copy of the task with the given ID, or nil.
+func taskPtr(tasks []Task, id int) *Task {
+	for _, t := range tasks {
+		if t.ID == id {
+			t := Clone([]Task{t})[0]
+			return &t
+		}
+	}
+	return nil
+}
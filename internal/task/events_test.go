@@ -0,0 +1,328 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// compile-time check: EventStore implements Store
+var _ Store = (*EventStore)(nil)
+
+func eventTypes(t *testing.T, path string) string {
+	t.Helper()
+	events, _, _, err := NewEventStore(path).readEvents(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, e := range events {
+		types = append(types, string(e.Type))
+	}
+	return strings.Join(types, " ")
+}
+
+func TestEventStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventStore(path)
+
+	update(t, s, func(tasks []Task) []Task {
+		tasks = Add(tasks, "buy milk", PriorityNone)
+		tasks = Add(tasks, "call mum", PriorityHigh)
+		return Add(tasks, "write report", PriorityNone)
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 1)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		Tag(tasks, 2, []string{"home"}, nil)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		Edit(tasks, 3, "write the report")
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _ = Remove(tasks, 1)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task { return tasks }) // nothing to append
+
+	if got, want := eventTypes(t, path), "created created created completed tagged edited removed"; got != want {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	tasks, err := NewEventStore(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(tasks); got != "2:call mum 3:write the report" {
+		t.Errorf("folded tasks = %s", got)
+	}
+	if len(tasks) == 2 && (tasks[0].Priority != PriorityHigh || len(tasks[0].Tags) != 1) {
+		t.Errorf("task 2 = %+v, want high priority with one tag", tasks[0])
+	}
+}
+
+func TestEventStoreCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventStore(path)
+
+	update(t, s, func(tasks []Task) []Task {
+		for range snapshotEvery - 1 {
+			tasks = Add(tasks, "x", PriorityNone)
+		}
+		return tasks
+	})
+	before := fileSize(t, path)
+	update(t, s, func(tasks []Task) []Task { return Add(tasks, "x", PriorityNone) })
+	if after := fileSize(t, path); after >= before {
+		t.Errorf("log is %d bytes after compaction, was %d before the last event", after, before)
+	}
+	if got := eventTypes(t, path); got != "snapshot" {
+		t.Fatalf("compacted log = %q, want a single snapshot", got)
+	}
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 7)
+		return tasks
+	})
+
+	fresh := NewEventStore(path)
+	tasks, err := fresh.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != snapshotEvery || !tasks[6].Done {
+		t.Errorf("loaded %d tasks (task 7 done: %v), want %d with task 7 done", len(tasks), len(tasks) > 6 && tasks[6].Done, snapshotEvery)
+	}
+	if fresh.pending != 1 {
+		t.Errorf("replayed %d events after the snapshot, want 1", fresh.pending)
+	}
+
+	// history starts at the snapshot
+	changes, err := fresh.History(ctx, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Type != EventSnapshot || changes[0].After == nil || changes[1].Type != EventCompleted {
+		t.Errorf("history = %+v, want the snapshot and the completion", changes)
+	}
+
+	// compacting over changes it has not seen is a conflict
+	update(t, NewEventStore(path), func(tasks []Task) []Task { return Add(tasks, "y", PriorityNone) })
+	var conflict *ConflictError
+	if err := s.Compact(); !errors.As(err, &conflict) {
+		t.Errorf("Compact after another write: err = %v, want *ConflictError", err)
+	}
+}
+
+func fileSize(t testing.TB, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestEventStoreCompleteRunningTask(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	s := NewEventStore(path)
+
+	update(t, s, func(tasks []Task) []Task {
+		tasks = Add(tasks, "a", PriorityNone)
+		if _, err := Start(tasks, 1, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 1)
+		return tasks
+	})
+
+	if got, want := eventTypes(t, path), "created completed"; got != want {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+	tasks, err := NewEventStore(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || !tasks[0].Done || tasks[0].Running() {
+		t.Errorf("tasks = %+v, want task 1 done with its timer stopped", tasks)
+	}
+}
+
+func TestEventStoreTornWrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	update(t, NewEventStore(path), func(tasks []Task) []Task { return Add(tasks, "a", PriorityNone) })
+
+	// a crash mid-append leaves half a line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2026-01-01T00:00:00Z","type":"crea`)
+	f.Close()
+
+	s := NewEventStore(path)
+	update(t, s, func(tasks []Task) []Task {
+		if len(tasks) != 1 {
+			t.Errorf("loaded %d tasks past a torn line, want 1", len(tasks))
+		}
+		return Add(tasks, "b", PriorityNone)
+	})
+
+	tasks, err := NewEventStore(path).Load(ctx)
+	if err != nil {
+		t.Fatalf("load after repair: %v", err)
+	}
+	if got := summary(tasks); got != "1:a 2:b" {
+		t.Errorf("tasks = %s", got)
+	}
+}
+
+func TestEventStoreConflict(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	a, b := NewEventStore(path), NewEventStore(path)
+
+	tasksA, err := a.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasksB, err := b.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Save(ctx, Add(tasksA, "from a", PriorityNone)); err != nil {
+		t.Fatal(err)
+	}
+
+	var conflict *ConflictError
+	if err := b.Save(ctx, Add(tasksB, "from b", PriorityNone)); !errors.As(err, &conflict) {
+		t.Errorf("err = %v, want *ConflictError", err)
+	}
+}
+
+func TestEventStoreHistory(t *testing.T) {
+	ctx := context.Background()
+	s := NewEventStore(filepath.Join(t.TempDir(), "tasks.jsonl"))
+
+	update(t, s, func(tasks []Task) []Task {
+		tasks = Add(tasks, "buy milk", PriorityNone)
+		return Add(tasks, "other", PriorityNone)
+	})
+	update(t, s, func(tasks []Task) []Task {
+		Edit(tasks, 1, "buy oat milk")
+		Edit(tasks, 2, "other task")
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 1)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _ = Remove(tasks, 1)
+		return tasks
+	})
+
+	changes, err := s.History(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, c := range changes {
+		types = append(types, string(c.Type))
+	}
+	if got, want := strings.Join(types, " "), "created edited completed removed"; got != want {
+		t.Fatalf("history = %q, want %q", got, want)
+	}
+
+	if c := changes[0]; c.Before != nil || c.After == nil || c.After.Title != "buy milk" {
+		t.Errorf("created: before = %v, after = %v", c.Before, c.After)
+	}
+	fields := changes[1].Fields()
+	if len(fields) != 1 || fields[0].Field != "title" || fields[0].Ours != `"buy milk"` || fields[0].Theirs != `"buy oat milk"` {
+		t.Errorf("edited fields = %+v, want the title change", fields)
+	}
+	if c := changes[3]; c.Before == nil || !c.Before.Done || c.After != nil {
+		t.Errorf("removed: before = %v, after = %v", c.Before, c.After)
+	}
+
+	if changes, err := s.History(ctx, 99); err != nil || len(changes) != 0 {
+		t.Errorf("History(99) = %v, %v; want empty", changes, err)
+	}
+}
+
+func TestDiffEvents(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	done := now.Add(-time.Hour)
+	base := []Task{
+		{ID: 1, Title: "a"},
+		{ID: 2, Title: "b", Tags: []string{"x"}},
+	}
+	with := func(fn func(ts []Task) []Task) []Task {
+		return fn(Clone(base))
+	}
+
+	tests := []struct {
+		name  string
+		after []Task
+		want  string
+	}{
+		{"unchanged", base, ""},
+		{"created", with(func(ts []Task) []Task { return append(ts, Task{ID: 3, Title: "c"}) }), "created 3"},
+		{"removed", with(func(ts []Task) []Task { return ts[1:] }), "removed 1"},
+		{"completed", with(func(ts []Task) []Task { ts[0] = completed(ts[0], done); return ts }), "completed 1"},
+		{"tagged", with(func(ts []Task) []Task { ts[1].Tags = []string{"x", "y"}; return ts }), "tagged 2"},
+		{"edited", with(func(ts []Task) []Task { ts[0].Title = "A"; return ts }), "edited 1"},
+		{"completed and renamed", with(func(ts []Task) []Task {
+			ts[0] = completed(ts[0], done)
+			ts[0].Title = "A"
+			return ts
+		}), "completed 1"},
+		{"completed without a time", with(func(ts []Task) []Task { ts[0].Done = true; return ts }), "completed 1"},
+		{"several", with(func(ts []Task) []Task {
+			ts[1].Title = "B"
+			return append(ts[1:], Task{ID: 3})
+		}), "removed 1, edited 2, created 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := diffEvents(base, tt.after, now)
+			var got []string
+			for _, e := range events {
+				got = append(got, string(e.Type)+" "+strconv.Itoa(e.ID))
+			}
+			if s := strings.Join(got, ", "); s != tt.want {
+				t.Errorf("events = %q, want %q", s, tt.want)
+			}
+
+			// folding the events reproduces the target
+			folded := Clone(base)
+			for _, e := range events {
+				folded = applyEvent(folded, e)
+			}
+			if !sameTasks(sortedByID(folded), sortedByID(tt.after)) {
+				t.Errorf("folded = %+v, want %+v", folded, tt.after)
+			}
+		})
+	}
+}
+
+func sortedByID(tasks []Task) []Task {
+	return slices.SortedFunc(slices.Values(tasks), func(a, b Task) int { return a.ID - b.ID })
+}
@@ -2,11 +2,13 @@ package task
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"time"
 )
 
 // gistAPIBase is the GitHub API base URL. Overridden in tests.
@@ -17,6 +19,26 @@ type GistStore struct {
 	Token  string // GitHub personal access token
 	GistID string // existing gist ID (empty = create new on first save)
 	client *http.Client
+
+	// ConflictsPath is where unresolved merge conflicts are recorded.
+	// If empty, a save with conflicting changes fails with *ConflictError.
+	ConflictsPath string
+
+	// CachePath is a local copy of the gist used when GitHub cannot be
+	// reached; saves made while offline are queued there. If empty,
+	// network errors are returned as-is.
+	CachePath string
+
+	rev     string // gist revision seen by the last Load or Save
+	base    []Task // tasks as of rev, the common ancestor for merges
+	loaded  bool
+	offline error // network error that sent the store offline, if any
+}
+
+// SetTimeout sets the time limit for each request to the Gist API,
+// including reading the response body.
+func (s *GistStore) SetTimeout(d time.Duration) {
+	s.client = &http.Client{Timeout: d}
 }
 
 // NewGistStore returns a GistStore that syncs tasks via the GitHub Gist API.
@@ -24,7 +46,7 @@ func NewGistStore(token, gistID string) *GistStore {
 	return &GistStore{
 		Token:  token,
 		GistID: gistID,
-		client: http.DefaultClient,
+		client: &http.Client{Timeout: DefaultGistTimeout},
 	}
 }
 
@@ -43,58 +65,191 @@ type gistFile struct {
 
 // gistResponse is the relevant subset of the Gist API response.
 type gistResponse struct {
-	ID    string                     `json:"id"`
-	Files map[string]gistFileContent `json:"files"`
+	ID        string                     `json:"id"`
+	Files     map[string]gistFileContent `json:"files"`
+	UpdatedAt string                     `json:"updated_at"`
+	History   []gistVersion              `json:"history"`
+}
+
+// gistVersion is one entry of a gist's revision history, newest first.
+type gistVersion struct {
+	Version string `json:"version"`
 }
 
-// gistFileContent is the file content returned by the Gist API.
+// revision identifies the gist's current contents: the newest history
+// version, or updated_at when the history is not included.
+func (g gistResponse) revision() string {
+	if len(g.History) > 0 {
+		return g.History[0].Version
+	}
+	return g.UpdatedAt
+}
+
+// gistFileContent is the file content returned by the Gist API. Content
+// over about 1 MB is cut short and marked Truncated; the full file is
+// then available from RawURL.
 type gistFileContent struct {
-	Content string `json:"content"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"`
+	RawURL    string `json:"raw_url,omitempty"`
 }
 
-// Load reads tasks from the gist. Returns an empty slice if no gist ID is set.
-func (s *GistStore) Load() ([]Task, error) {
+// Load reads tasks from the gist and records its revision. Changes
+// queued while offline are pushed first. If GitHub cannot be reached,
+// Load falls back to the local cache.
+// Returns an empty slice if no gist ID is set.
+func (s *GistStore) Load(ctx context.Context) ([]Task, error) {
+	cache, err := s.readCache()
+	if err != nil {
+		return nil, err
+	}
+
+	if cache.Pending {
+		// push against the state the queued save was based on
+		s.rev, s.base, s.loaded = cache.Rev, cache.Base, true
+		if err := s.push(ctx, cache.Tasks); err != nil {
+			if isNetworkError(ctx, err) {
+				return s.goOffline(cache, err), nil
+			}
+			return nil, err
+		}
+		fmt.Fprintln(os.Stderr, "gist: pushed changes queued while offline")
+		return Clone(s.base), nil
+	}
+
 	if s.GistID == "" {
 		return nil, nil
 	}
 
+	gist, err := s.fetch(ctx)
+	if err != nil {
+		if isNetworkError(ctx, err) && cache.GistID != "" {
+			return s.goOffline(cache, err), nil
+		}
+		return nil, err
+	}
+	tasks, err := gist.tasks()
+	if err != nil {
+		return nil, err
+	}
+	s.rev, s.base, s.loaded = gist.revision(), Clone(tasks), true
+	if err := s.writeCache(tasks, false); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// tasks decodes the task list from the gist's file.
+func (g gistResponse) tasks() ([]Task, error) {
+	f, ok := g.Files[gistFilename]
+	if !ok || f.Content == "" {
+		return nil, nil
+	}
+
+	var tasks []Task
+	if err := json.Unmarshal([]byte(f.Content), &tasks); err != nil {
+		return nil, fmt.Errorf("gist: unmarshal tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// fetch retrieves the gist.
+func (s *GistStore) fetch(ctx context.Context) (gistResponse, error) {
+	var gist gistResponse
+
 	url := fmt.Sprintf("%s/gists/%s", gistAPIBase, s.GistID)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("gist: build request: %w", err)
+		return gist, fmt.Errorf("gist: build request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+s.Token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("gist: network error: %w", err)
+		return gist, fmt.Errorf("gist: network error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if err := checkResponse(resp); err != nil {
-		return nil, err
+		return gist, err
 	}
 
-	var gist gistResponse
 	if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil {
-		return nil, fmt.Errorf("gist: decode response: %w", err)
+		return gist, fmt.Errorf("gist: decode response: %w", err)
 	}
 
-	f, ok := gist.Files[gistFilename]
-	if !ok || f.Content == "" {
-		return nil, nil
+	if f, ok := gist.Files[gistFilename]; ok && f.Truncated {
+		content, err := s.fetchRaw(ctx, f.RawURL)
+		if err != nil {
+			return gist, err
+		}
+		gist.Files[gistFilename] = gistFileContent{Content: content, RawURL: f.RawURL}
 	}
+	return gist, nil
+}
 
-	var tasks []Task
-	if err := json.Unmarshal([]byte(f.Content), &tasks); err != nil {
-		return nil, fmt.Errorf("gist: unmarshal tasks: %w", err)
+// fetchRaw downloads the full content of a truncated gist file.
+func (s *GistStore) fetchRaw(ctx context.Context, url string) (string, error) {
+	if url == "" {
+		return "", fmt.Errorf("gist: %s is truncated and has no raw_url", gistFilename)
 	}
-	return tasks, nil
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("gist: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.Token)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return "", fmt.Errorf("gist: network error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("gist: read %s: %w", gistFilename, err)
+	}
+	return string(data), nil
 }
 
 // Save writes tasks to the gist. Creates a new private gist if GistID is empty.
-func (s *GistStore) Save(tasks []Task) error {
+// If the gist has changed since the last Load, the remote changes are
+// merged in first (see Merge); tasks changed on both sides are recorded
+// in ConflictsPath for `tsk conflicts`. While offline, the save is
+// queued in the cache and pushed by the next Load or Sync.
+func (s *GistStore) Save(ctx context.Context, tasks []Task) error {
+	if s.offline != nil {
+		return s.queue(tasks)
+	}
+	err := s.push(ctx, tasks)
+	if isNetworkError(ctx, err) && s.CachePath != "" {
+		s.offline = err
+		return s.queue(tasks)
+	}
+	return err
+}
+
+// push writes tasks to the gist, merging in remote changes if needed.
+func (s *GistStore) push(ctx context.Context, tasks []Task) error {
+	if s.loaded && s.GistID != "" {
+		current, err := s.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		if rev := current.revision(); rev != s.rev {
+			tasks, err = s.merge(tasks, current)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	data, err := json.MarshalIndent(tasks, "", "  ")
 	if err != nil {
 		return fmt.Errorf("gist: marshal tasks: %w", err)
@@ -121,7 +276,7 @@ func (s *GistStore) Save(tasks []Task) error {
 		url = fmt.Sprintf("%s/gists/%s", gistAPIBase, s.GistID)
 	}
 
-	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
 	if err != nil {
 		return fmt.Errorf("gist: build request: %w", err)
 	}
@@ -129,7 +284,7 @@ func (s *GistStore) Save(tasks []Task) error {
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if err != nil {
 		return fmt.Errorf("gist: network error: %w", err)
 	}
@@ -139,17 +294,60 @@ func (s *GistStore) Save(tasks []Task) error {
 		return err
 	}
 
+	var gist gistResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil {
+		return fmt.Errorf("gist: decode response: %w", err)
+	}
+	s.rev, s.base = gist.revision(), Clone(tasks)
+
 	// on create, capture the new gist ID
 	if s.GistID == "" {
-		var gist gistResponse
-		if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil {
-			return fmt.Errorf("gist: decode response: %w", err)
-		}
 		s.GistID = gist.ID
 		fmt.Fprintf(os.Stderr, "created gist: %s — add to config to persist\n", s.GistID)
 	}
 
-	return nil
+	return s.writeCache(tasks, false)
+}
+
+// merge folds the changes in current, made since our Load, into tasks.
+func (s *GistStore) merge(tasks []Task, current gistResponse) ([]Task, error) {
+	theirs, err := current.tasks()
+	if err != nil {
+		return nil, err
+	}
+
+	merged, conflicts := Merge(s.base, tasks, theirs)
+	if len(conflicts) > 0 {
+		if s.ConflictsPath == "" {
+			return nil, &ConflictError{Loaded: s.rev, Current: current.revision()}
+		}
+		if err := addConflicts(s.ConflictsPath, conflicts); err != nil {
+			return nil, fmt.Errorf("gist: record conflicts: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "gist: merged changes from another machine with %d conflicts — run tsk conflicts to resolve\n",
+			len(conflicts))
+	} else {
+		fmt.Fprintln(os.Stderr, "gist: merged changes from another machine")
+	}
+	return merged, nil
+}
+
+// Revision returns the gist revision seen by the last Load or Save.
+func (s *GistStore) Revision() string {
+	return s.rev
+}
+
+// Update loads tasks from the gist, applies fn and saves the result.
+func (s *GistStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	tasks, err := s.Load(ctx)
+	if err != nil {
+		return err
+	}
+	tasks, err = fn(tasks)
+	if err != nil {
+		return err
+	}
+	return s.Save(ctx, tasks)
 }
 
 // checkResponse maps HTTP error statuses to clear error messages.
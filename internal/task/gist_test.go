@@ -1,10 +1,15 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -17,12 +22,17 @@ func testServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
 	t.Helper()
 	srv := httptest.NewServer(handler)
 	t.Cleanup(srv.Close)
+
+	// retries happen, but without real waiting
+	oldSleep := gistSleep
+	gistSleep = func(context.Context, time.Duration) error { return nil }
+	t.Cleanup(func() { gistSleep = oldSleep })
 	return srv
 }
 
 func TestGistLoadEmptyID(t *testing.T) {
 	store := NewGistStore("token", "")
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -68,7 +78,7 @@ func TestGistLoadSuccess(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("test-token", "abc123")
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -105,7 +115,7 @@ func TestGistLoadMissingFile(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "abc123")
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -140,7 +150,7 @@ func TestGistSaveUpdate(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "existing-id")
-	if err := store.Save(tasks); err != nil {
+	if err := store.Save(context.Background(), tasks); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -188,7 +198,7 @@ func TestGistSaveCreateNew(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "")
-	if err := store.Save([]Task{{ID: 1, Title: "first"}}); err != nil {
+	if err := store.Save(context.Background(), []Task{{ID: 1, Title: "first"}}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
@@ -234,7 +244,7 @@ func TestGistSaveCreateThenUpdate(t *testing.T) {
 	store := NewGistStore("token", "")
 
 	// first save creates
-	if err := store.Save([]Task{{ID: 1, Title: "first"}}); err != nil {
+	if err := store.Save(context.Background(), []Task{{ID: 1, Title: "first"}}); err != nil {
 		t.Fatalf("first save: %v", err)
 	}
 	if store.GistID != "created-id" {
@@ -242,7 +252,7 @@ func TestGistSaveCreateThenUpdate(t *testing.T) {
 	}
 
 	// second save updates
-	if err := store.Save([]Task{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}}); err != nil {
+	if err := store.Save(context.Background(), []Task{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}}); err != nil {
 		t.Fatalf("second save: %v", err)
 	}
 
@@ -261,7 +271,7 @@ func TestGistErrorUnauthorized(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("bad-token", "abc123")
-	_, err := store.Load()
+	_, err := store.Load(context.Background())
 	if err == nil {
 		t.Fatal("expected error for 401")
 	}
@@ -280,7 +290,7 @@ func TestGistErrorNotFound(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "nonexistent")
-	_, err := store.Load()
+	_, err := store.Load(context.Background())
 	if err == nil {
 		t.Fatal("expected error for 404")
 	}
@@ -299,7 +309,7 @@ func TestGistErrorRateLimited(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "abc123")
-	_, err := store.Load()
+	_, err := store.Load(context.Background())
 	if err == nil {
 		t.Fatal("expected error for 429")
 	}
@@ -318,7 +328,7 @@ func TestGistErrorForbidden(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "abc123")
-	err := store.Save([]Task{{ID: 1, Title: "test"}})
+	err := store.Save(context.Background(), []Task{{ID: 1, Title: "test"}})
 	if err == nil {
 		t.Fatal("expected error for 403")
 	}
@@ -338,7 +348,7 @@ func TestGistErrorServerError(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("token", "abc123")
-	_, err := store.Load()
+	_, err := store.Load(context.Background())
 	if err == nil {
 		t.Fatal("expected error for 500")
 	}
@@ -363,7 +373,7 @@ func TestGistSaveRequestBodyStructure(t *testing.T) {
 	tasks := []Task{
 		{ID: 1, Title: "buy milk", Done: false, CreatedAt: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)},
 	}
-	if err := store.Save(tasks); err != nil {
+	if err := store.Save(context.Background(), tasks); err != nil {
 		t.Fatal(err)
 	}
 
@@ -416,9 +426,527 @@ func TestGistAuthHeader(t *testing.T) {
 	t.Cleanup(func() { gistAPIBase = old })
 
 	store := NewGistStore("ghp_mytoken123", "abc123")
-	store.Load()
+	store.Load(context.Background())
 
 	if authHeader != "Bearer ghp_mytoken123" {
 		t.Errorf("Authorization = %q, want %q", authHeader, "Bearer ghp_mytoken123")
 	}
 }
+
+func TestGistSaveMerge(t *testing.T) {
+	base := []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}}
+	ours := []Task{{ID: 1, Title: "a (laptop)"}, {ID: 2, Title: "b"}}
+
+	tests := []struct {
+		name          string
+		remote        []Task
+		remoteRev     string
+		conflictsFile bool
+		wantTitles    string // saved titles; "" when nothing is saved
+		wantConflicts int
+		wantErr       bool
+	}{
+		{
+			name:       "unchanged remotely",
+			remote:     base,
+			remoteRev:  "v1",
+			wantTitles: "a (laptop),b",
+		},
+		{
+			name:       "other task changed remotely",
+			remote:     []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b (desktop)"}},
+			remoteRev:  "v2",
+			wantTitles: "a (laptop),b (desktop)",
+		},
+		{
+			name:          "same task changed remotely",
+			remote:        []Task{{ID: 1, Title: "a (desktop)"}, {ID: 2, Title: "b"}},
+			remoteRev:     "v2",
+			conflictsFile: true,
+			wantTitles:    "a (laptop),b",
+			wantConflicts: 1,
+		},
+		{
+			name:      "same task changed, nowhere to record conflicts",
+			remote:    []Task{{ID: 1, Title: "a (desktop)"}, {ID: 2, Title: "b"}},
+			remoteRev: "v2",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseJSON, _ := json.Marshal(base)
+			remoteJSON, _ := json.Marshal(tt.remote)
+
+			gets := 0
+			var saved []Task
+			srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				resp := gistResponse{ID: "abc123", History: []gistVersion{{Version: "v1"}}}
+				content := string(baseJSON)
+				switch r.Method {
+				case http.MethodGet:
+					gets++
+					if gets > 1 {
+						content = string(remoteJSON)
+						resp.History[0].Version = tt.remoteRev
+					}
+				case http.MethodPatch:
+					var body gistRequest
+					json.NewDecoder(r.Body).Decode(&body)
+					content = body.Files[gistFilename].Content
+					json.Unmarshal([]byte(content), &saved)
+					resp.History[0].Version = "v3"
+				}
+				resp.Files = map[string]gistFileContent{gistFilename: {Content: content}}
+				json.NewEncoder(w).Encode(resp)
+			})
+
+			old := gistAPIBase
+			gistAPIBase = srv.URL
+			t.Cleanup(func() { gistAPIBase = old })
+
+			store := NewGistStore("token", "abc123")
+			if tt.conflictsFile {
+				store.ConflictsPath = filepath.Join(t.TempDir(), "conflicts.json")
+			}
+			if _, err := store.Load(context.Background()); err != nil {
+				t.Fatalf("load: %v", err)
+			}
+
+			err := store.Save(context.Background(), Clone(ours))
+			if tt.wantErr {
+				var conflict *ConflictError
+				if !errors.As(err, &conflict) {
+					t.Fatalf("err = %v, want *ConflictError", err)
+				}
+				if saved != nil {
+					t.Error("conflicting save should not send a PATCH")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			var got []string
+			for _, tk := range saved {
+				got = append(got, tk.Title)
+			}
+			if strings.Join(got, ",") != tt.wantTitles {
+				t.Errorf("saved %q, want %q", strings.Join(got, ","), tt.wantTitles)
+			}
+			if store.Revision() != "v3" {
+				t.Errorf("Revision() = %q after save, want v3", store.Revision())
+			}
+
+			if tt.conflictsFile {
+				conflicts, err := LoadConflicts(store.ConflictsPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(conflicts) != tt.wantConflicts {
+					t.Errorf("recorded %d conflicts, want %d", len(conflicts), tt.wantConflicts)
+				}
+			}
+		})
+	}
+}
+
+func TestGistRevisionFallback(t *testing.T) {
+	g := gistResponse{UpdatedAt: "2026-01-02T03:04:05Z"}
+	if got := g.revision(); got != g.UpdatedAt {
+		t.Errorf("revision() = %q, want updated_at %q", got, g.UpdatedAt)
+	}
+}
+
+func TestGistOffline(t *testing.T) {
+	remote := `[{"id":1,"title":"a","done":false,"created_at":"2026-01-01T00:00:00Z"}]`
+	rev := "v1"
+	patches := 0
+	srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			patches++
+			var body gistRequest
+			json.NewDecoder(r.Body).Decode(&body)
+			remote = body.Files[gistFilename].Content
+			rev = fmt.Sprintf("v%d", patches+1)
+		}
+		json.NewEncoder(w).Encode(gistResponse{
+			ID:      "abc123",
+			Files:   map[string]gistFileContent{gistFilename: {Content: remote}},
+			History: []gistVersion{{Version: rev}},
+		})
+	})
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	old := gistAPIBase
+	t.Cleanup(func() { gistAPIBase = old })
+	cachePath := filepath.Join(t.TempDir(), "cache.json")
+	newStore := func() *GistStore {
+		s := NewGistStore("token", "abc123")
+		s.CachePath = cachePath
+		return s
+	}
+
+	// online load fills the cache
+	gistAPIBase = srv.URL
+	if _, err := newStore().Load(context.Background()); err != nil {
+		t.Fatalf("online load: %v", err)
+	}
+
+	// offline: load from cache, queue a save
+	gistAPIBase = down.URL
+	err := newStore().Update(context.Background(), func(tasks []Task) ([]Task, error) {
+		if len(tasks) != 1 {
+			t.Errorf("offline load: got %d tasks, want 1 from cache", len(tasks))
+		}
+		return Add(tasks, "b", PriorityNone), nil
+	})
+	if err != nil {
+		t.Fatalf("offline update: %v", err)
+	}
+
+	st, err := newStore().Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if st.Online || !st.Pending || st.Ahead != 1 {
+		t.Errorf("offline status = %+v, want pending, 1 ahead, not online", st)
+	}
+	if err := newStore().Sync(context.Background()); !isNetworkError(context.Background(), err) {
+		t.Errorf("offline sync: err = %v, want network error", err)
+	}
+
+	// back online: the queued save is pushed
+	gistAPIBase = srv.URL
+	if err := newStore().Sync(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if patches != 1 || !strings.Contains(remote, `"b"`) {
+		t.Errorf("patches = %d, remote = %s; want queued task pushed once", patches, remote)
+	}
+
+	st, err = newStore().Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !st.Online || st.Pending || st.Ahead != 0 || st.Behind != 0 {
+		t.Errorf("synced status = %+v, want online and even", st)
+	}
+}
+
+func TestGistLoadCancelled(t *testing.T) {
+	srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request sent with a cancelled context")
+	})
+	old := gistAPIBase
+	gistAPIBase = srv.URL
+	t.Cleanup(func() { gistAPIBase = old })
+
+	store := NewGistStore("token", "abc123")
+	store.CachePath = filepath.Join(t.TempDir(), "cache.json")
+	if err := store.writeCache(nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Load(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled rather than an offline fallback", err)
+	}
+}
+
+func TestGistOfflineWithoutCache(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	old := gistAPIBase
+	gistAPIBase = down.URL
+	t.Cleanup(func() { gistAPIBase = old })
+
+	store := NewGistStore("token", "abc123")
+	store.CachePath = filepath.Join(t.TempDir(), "cache.json")
+	if _, err := store.Load(context.Background()); !isNetworkError(context.Background(), err) {
+		t.Errorf("err = %v, want network error when nothing is cached", err)
+	}
+}
+
+func TestGistRetry(t *testing.T) {
+	ok := func(w http.ResponseWriter) {
+		json.NewEncoder(w).Encode(gistResponse{ID: "abc123"})
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		failures  int // responses before success
+		fail      func(w http.ResponseWriter)
+		wantCalls int
+		wantErr   string
+	}{
+		{
+			name:     "server error then success",
+			method:   http.MethodGet,
+			failures: 2,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantCalls: 3,
+		},
+		{
+			name:     "server error on every attempt",
+			method:   http.MethodGet,
+			failures: 10,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantCalls: 4, // first try plus gistMaxRetries
+			wantErr:   "unexpected status 503",
+		},
+		{
+			name:     "rate limited with Retry-After",
+			method:   http.MethodPatch,
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "2")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantCalls: 2,
+		},
+		{
+			name:     "forbidden with no remaining quota",
+			method:   http.MethodGet,
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(5*time.Second).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wantCalls: 2,
+		},
+		{
+			name:     "reset too far away",
+			method:   http.MethodGet,
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			wantCalls: 1,
+			wantErr:   "rate limited",
+		},
+		{
+			name:     "forbidden is not retried",
+			method:   http.MethodGet,
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			wantCalls: 1,
+			wantErr:   "rate limited",
+		},
+		{
+			name:     "create is not retried on server error",
+			method:   http.MethodPost,
+			failures: 1,
+			fail: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			wantCalls: 1,
+			wantErr:   "unexpected status 502",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			var bodies []string
+			srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				if calls <= tt.failures {
+					tt.fail(w)
+					return
+				}
+				ok(w)
+			})
+
+			var waits []time.Duration
+			gistSleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			old := gistAPIBase
+			gistAPIBase = srv.URL
+			t.Cleanup(func() { gistAPIBase = old })
+
+			var err error
+			switch tt.method {
+			case http.MethodGet:
+				_, err = NewGistStore("token", "abc123").Load(context.Background())
+			case http.MethodPatch:
+				err = NewGistStore("token", "abc123").Save(context.Background(), []Task{{ID: 1, Title: "x"}})
+			case http.MethodPost:
+				err = NewGistStore("token", "").Save(context.Background(), []Task{{ID: 1, Title: "x"}})
+			}
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if len(waits) != calls-1 {
+				t.Errorf("slept %d times, want %d", len(waits), calls-1)
+			}
+			// retried requests resend the full body
+			for i, b := range bodies[1:] {
+				if b != bodies[0] {
+					t.Errorf("attempt %d body differs from the first", i+2)
+				}
+			}
+		})
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Duration
+		ok     bool
+	}{
+		{"none", nil, 0, false},
+		{"retry-after seconds", map[string]string{"Retry-After": "7"}, 7 * time.Second, true},
+		{"retry-after date", map[string]string{"Retry-After": now.Add(30 * time.Second).Format(http.TimeFormat)}, 30 * time.Second, true},
+		{"rate limit reset", map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}, time.Minute, true},
+		{"reset in the past", map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)}, 0, true},
+		{"retry-after wins", map[string]string{"Retry-After": "1", "X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}, time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.header {
+				h.Set(k, v)
+			}
+			got, ok := serverDelay(h, now)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("serverDelay = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := range 6 {
+		full := min(gistRetryBase<<attempt, gistMaxBackoff)
+		for range 20 {
+			d := backoff(attempt)
+			if d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, full/2, full)
+			}
+		}
+	}
+}
+
+func TestGistTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := testServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+
+	old := gistAPIBase
+	gistAPIBase = srv.URL
+	t.Cleanup(func() { gistAPIBase = old })
+
+	store := NewGistStore("token", "abc123")
+	store.SetTimeout(20 * time.Millisecond)
+	start := time.Now()
+	_, err := store.Load(context.Background())
+	if err == nil || !isNetworkError(context.Background(), err) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("load took %v despite timeout", elapsed)
+	}
+}
+
+func TestGistLoadTruncated(t *testing.T) {
+	want := []Task{{ID: 1, Title: "first"}, {ID: 2, Title: "second"}}
+	full, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		rawPath string // "" means the API omits raw_url
+		rawCode int
+		wantErr string
+	}{
+		{name: "fetches raw_url", rawPath: "/raw/abc123/tasks.json", rawCode: http.StatusOK},
+		{name: "raw fetch fails", rawPath: "/raw/abc123/tasks.json", rawCode: http.StatusNotFound, wantErr: "not found"},
+		{name: "no raw_url", wantErr: "truncated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rawAuth string
+			var srv *httptest.Server
+			srv = testServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/raw/") {
+					rawAuth = r.Header.Get("Authorization")
+					w.WriteHeader(tt.rawCode)
+					w.Write(full)
+					return
+				}
+				f := gistFileContent{
+					Content:   string(full[:len(full)/2]), // cut mid-JSON, as the API does
+					Truncated: true,
+				}
+				if tt.rawPath != "" {
+					f.RawURL = srv.URL + tt.rawPath
+				}
+				json.NewEncoder(w).Encode(gistResponse{
+					ID:    "abc123",
+					Files: map[string]gistFileContent{gistFilename: f},
+				})
+			})
+
+			old := gistAPIBase
+			gistAPIBase = srv.URL
+			t.Cleanup(func() { gistAPIBase = old })
+
+			tasks, err := NewGistStore("test-token", "abc123").Load(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if summary(tasks) != summary(want) {
+				t.Errorf("tasks = %q, want %q", summary(tasks), summary(want))
+			}
+			if rawAuth != "Bearer test-token" {
+				t.Errorf("raw auth = %q, want bearer token", rawAuth)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gitFilename = "tasks.json"
+
+// GitStore persists tasks as JSON in a local git repository and commits
+// every Save, so the task history can be browsed, pushed and pulled like
+// code. It shells out to the git binary.
+type GitStore struct {
+	Dir           string // repository directory; created on first Save
+	Remote        string // URL `tsk sync` pulls from and pushes to; "" uses the existing origin
+	ConflictsPath string // where Sync records tasks edited on both sides
+
+	file   *FileStore
+	base   []Task // tasks as of the last Load or Save, to describe commits
+	loaded bool
+}
+
+// Commit is one entry in the task history of a GitStore.
+type Commit struct {
+	Hash    string
+	Time    time.Time
+	Subject string
+}
+
+// GitStatus describes how the local repository relates to its remote.
+type GitStatus struct {
+	Remote string
+	Branch string
+	Ahead  int // local commits not yet pushed
+	Behind int // remote commits not yet pulled
+}
+
+// NewGitStore returns a GitStore that keeps tasks in dir.
+func NewGitStore(dir, remote string) *GitStore {
+	return &GitStore{
+		Dir:    dir,
+		Remote: remote,
+		file:   NewFileStore(filepath.Join(dir, gitFilename)),
+	}
+}
+
+// Load reads tasks from the working tree. Returns an empty slice if the
+// repository has no tasks yet.
+func (s *GitStore) Load(ctx context.Context) ([]Task, error) {
+	tasks, err := s.file.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.base, s.loaded = Clone(tasks), true
+	return tasks, nil
+}
+
+// Save writes tasks and commits them with a message describing what
+// changed, such as "done 3: buy milk". The repository is initialised on
+// first use. Saving unchanged tasks makes no commit.
+func (s *GitStore) Save(ctx context.Context, tasks []Task) error {
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+
+	before := s.base
+	if !s.loaded {
+		var err error
+		if before, err = NewFileStore(s.file.Path).Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := s.file.Save(ctx, tasks); err != nil {
+		return err
+	}
+	if err := s.commit(ctx, describeChange(before, tasks)); err != nil {
+		return err
+	}
+	s.base, s.loaded = Clone(tasks), true
+	return nil
+}
+
+// Update runs a load-modify-save cycle under the same lock as FileStore.
+func (s *GitStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	if err := s.init(ctx); err != nil {
+		return err
+	}
+	return lockedUpdate(ctx, s.file.Path+".lock", s, fn)
+}
+
+// Log returns up to n commits from the task history, newest first.
+// n <= 0 returns them all.
+func (s *GitStore) Log(ctx context.Context, n int) ([]Commit, error) {
+	if !s.hasCommits(ctx) {
+		return nil, nil
+	}
+
+	args := []string{"log", "--format=%h%x1f%at%x1f%s"}
+	if n > 0 {
+		args = append(args, "-n", strconv.Itoa(n))
+	}
+	out, err := s.git(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(out, "\n") {
+		hash, rest, _ := strings.Cut(line, "\x1f")
+		at, subject, _ := strings.Cut(rest, "\x1f")
+		sec, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("git log: unexpected output %q", line)
+		}
+		commits = append(commits, Commit{Hash: hash, Time: time.Unix(sec, 0), Subject: subject})
+	}
+	return commits, nil
+}
+
+// Sync pulls from the remote, rebasing local commits on top, and pushes
+// the result. If the rebase fails, or leaves tasks.json unreadable, the
+// two histories are merged task by task instead (see Merge); tasks
+// changed on both sides are recorded in ConflictsPath. Sync holds the
+// Update lock throughout, so no command saves while it rewrites
+// tasks.json.
+func (s *GitStore) Sync(ctx context.Context) error {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	branch, err := s.prepareRemote(ctx)
+	if err != nil {
+		return err
+	}
+
+	remoteRef := "refs/remotes/origin/" + branch
+	if _, err := s.git(ctx, "rev-parse", "-q", "--verify", remoteRef); err == nil {
+		if err := s.pull(ctx, remoteRef); err != nil {
+			return err
+		}
+	}
+
+	if !s.hasCommits(ctx) {
+		return nil
+	}
+	_, err = s.git(ctx, "push", "-q", "origin", "HEAD:refs/heads/"+branch)
+	return err
+}
+
+// Status fetches from the remote and counts the commits each side has
+// that the other does not.
+func (s *GitStore) Status(ctx context.Context) (GitStatus, error) {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return GitStatus{}, err
+	}
+	defer unlock()
+
+	branch, err := s.prepareRemote(ctx)
+	if err != nil {
+		return GitStatus{}, err
+	}
+	remote, err := s.git(ctx, "remote", "get-url", "origin")
+	if err != nil {
+		return GitStatus{}, err
+	}
+	st := GitStatus{Remote: remote, Branch: branch}
+
+	remoteRef := "refs/remotes/origin/" + branch
+	_, err = s.git(ctx, "rev-parse", "-q", "--verify", remoteRef)
+	hasRemote := err == nil
+	switch {
+	case !s.hasCommits(ctx) && !hasRemote:
+	case !hasRemote:
+		st.Ahead, err = s.count(ctx, "HEAD")
+	case !s.hasCommits(ctx):
+		st.Behind, err = s.count(ctx, remoteRef)
+	default:
+		var out string
+		out, err = s.git(ctx, "rev-list", "--left-right", "--count", "HEAD..."+remoteRef)
+		if err == nil {
+			_, err = fmt.Sscan(out, &st.Ahead, &st.Behind)
+		}
+	}
+	if err != nil {
+		return GitStatus{}, err
+	}
+	return st, nil
+}
+
+// prepareRemote initialises the repository, points origin at Remote,
+// fetches, and returns the current branch.
+func (s *GitStore) prepareRemote(ctx context.Context) (string, error) {
+	if err := s.init(ctx); err != nil {
+		return "", err
+	}
+
+	current, _ := s.git(ctx, "remote", "get-url", "origin")
+	switch {
+	case s.Remote == "" && current == "":
+		return "", errors.New("git: no remote to sync with (set storage.git_remote in config)")
+	case s.Remote != "" && current == "":
+		if _, err := s.git(ctx, "remote", "add", "origin", s.Remote); err != nil {
+			return "", err
+		}
+	case s.Remote != "" && current != s.Remote:
+		if _, err := s.git(ctx, "remote", "set-url", "origin", s.Remote); err != nil {
+			return "", err
+		}
+	}
+
+	branch, err := s.git(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.git(ctx, "fetch", "-q", "origin"); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// pull brings in the commits from remoteRef.
+func (s *GitStore) pull(ctx context.Context, remoteRef string) error {
+	if !s.hasCommits(ctx) {
+		_, err := s.git(ctx, "reset", "-q", "--hard", remoteRef)
+		return err
+	}
+
+	head, err := s.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "rebase", "-q", remoteRef); err == nil {
+		tasks, err := s.file.Load(ctx)
+		if err == nil && len(byID(tasks)) == len(tasks) {
+			return nil
+		}
+		// the rebase applied cleanly but left broken JSON or duplicate
+		// IDs; start over
+		if _, err := s.git(ctx, "reset", "-q", "--hard", head); err != nil {
+			return err
+		}
+	} else if _, err := s.git(ctx, "rebase", "--abort"); err != nil {
+		return err
+	}
+	return s.merge(ctx, remoteRef)
+}
+
+// merge records a merge commit with remoteRef whose tasks are the
+// three-way merge of both sides.
+func (s *GitStore) merge(ctx context.Context, remoteRef string) error {
+	baseRev, err := s.git(ctx, "merge-base", "HEAD", remoteRef)
+	if err != nil {
+		baseRev = "" // unrelated histories: everything is an addition
+	}
+	base, err := s.tasksAt(ctx, baseRev)
+	if err != nil {
+		return err
+	}
+	ours, err := s.tasksAt(ctx, "HEAD")
+	if err != nil {
+		return err
+	}
+	theirs, err := s.tasksAt(ctx, remoteRef)
+	if err != nil {
+		return err
+	}
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) > 0 {
+		if s.ConflictsPath == "" {
+			return fmt.Errorf("git: %d tasks were changed on both sides", len(conflicts))
+		}
+		if err := addConflicts(s.ConflictsPath, conflicts); err != nil {
+			return fmt.Errorf("git: record conflicts: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "git: merged changes from another machine with %d conflicts — run tsk conflicts to resolve\n",
+			len(conflicts))
+	} else {
+		fmt.Fprintln(os.Stderr, "git: merged changes from another machine")
+	}
+
+	// keep our tree so nothing conflicts, then replace tasks.json
+	if _, err := s.git(ctx, "merge", "-q", "--no-commit", "--allow-unrelated-histories", "-s", "ours", remoteRef); err != nil {
+		return err
+	}
+	if err := NewFileStore(s.file.Path).Save(ctx, merged); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "add", "--", gitFilename); err != nil {
+		return err
+	}
+	args := append(s.identity(ctx), "commit", "-q", "-m", "merge changes from origin")
+	_, err = s.git(ctx, args...)
+	return err
+}
+
+// tasksAt reads tasks.json as of rev. An empty rev, or a commit without
+// the file, has no tasks.
+func (s *GitStore) tasksAt(ctx context.Context, rev string) ([]Task, error) {
+	if rev == "" {
+		return nil, nil
+	}
+	if _, err := s.git(ctx, "cat-file", "-e", rev+":"+gitFilename); err != nil {
+		return nil, nil
+	}
+	data, err := s.git(ctx, "show", rev+":"+gitFilename)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []Task
+	if err := json.Unmarshal([]byte(data), &tasks); err != nil {
+		return nil, fmt.Errorf("unmarshal tasks at %s: %w", rev, err)
+	}
+	return tasks, nil
+}
+
+// lock initialises the repository and takes the lock Update holds
+// while it loads and saves.
+func (s *GitStore) lock(ctx context.Context) (unlock func(), err error) {
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+	return lockFile(ctx, s.file.Path+".lock")
+}
+
+// init creates the repository if it does not exist yet.
+func (s *GitStore) init(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("create %s: %w", s.Dir, err)
+	}
+	if _, err := s.git(ctx, "init", "-q"); err != nil {
+		return err
+	}
+	// keep the lock file out of `git status`
+	exclude := filepath.Join(s.Dir, ".git", "info", "exclude")
+	if err := os.MkdirAll(filepath.Dir(exclude), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(exclude, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s.lock\n", gitFilename)
+	return err
+}
+
+// commit commits tasks.json if it changed.
+func (s *GitStore) commit(ctx context.Context, message string) error {
+	if _, err := s.git(ctx, "add", "--", gitFilename); err != nil {
+		return err
+	}
+	if _, err := s.git(ctx, "diff", "--cached", "--quiet", "--", gitFilename); err == nil {
+		return nil
+	}
+	subject, body, _ := strings.Cut(message, "\n")
+	args := append(s.identity(ctx), "commit", "-q", "-m", subject)
+	if body != "" {
+		args = append(args, "-m", body)
+	}
+	_, err := s.git(ctx, append(args, "--", gitFilename)...)
+	return err
+}
+
+// identity returns options giving commits a stand-in author when git
+// has no user.name and user.email to use, so that a Save does not write
+// tasks.json and then fail to commit it.
+func (s *GitStore) identity(ctx context.Context) []string {
+	_, errAuthor := s.git(ctx, "var", "GIT_AUTHOR_IDENT")
+	_, errCommitter := s.git(ctx, "var", "GIT_COMMITTER_IDENT")
+	if errAuthor == nil && errCommitter == nil {
+		return nil
+	}
+	return []string{"-c", "user.name=tsk", "-c", "user.email=tsk@localhost"}
+}
+
+func (s *GitStore) hasCommits(ctx context.Context) bool {
+	if _, err := os.Stat(filepath.Join(s.Dir, ".git")); err != nil {
+		return false
+	}
+	_, err := s.git(ctx, "rev-parse", "-q", "--verify", "HEAD")
+	return err == nil
+}
+
+func (s *GitStore) count(ctx context.Context, rev string) (int, error) {
+	out, err := s.git(ctx, "rev-list", "--count", rev)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(out)
+}
+
+// git runs a git command in the repository and returns its trimmed
+// standard output. Failures carry git's own error message.
+func (s *GitStore) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.Dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		name := args[0]
+		for i := 0; i+2 < len(args) && args[i] == "-c"; i += 2 {
+			name = args[i+2]
+		}
+		return "", fmt.Errorf("git %s: %s", name, msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// describeChange summarises the difference between two task lists as a
+// commit message: a subject line naming the first change, such as
+// "done 3: buy milk", and a body listing every change if there are more.
+// Encrypted titles are left out: "done 3".
+func describeChange(before, after []Task) string {
+	old, cur := byID(before), byID(after)
+
+	var ids []int
+	for id := range old {
+		ids = append(ids, id)
+	}
+	for id := range cur {
+		if _, ok := old[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var changes []string
+	for _, id := range ids {
+		o, inOld := old[id]
+		c, inCur := cur[id]
+		var verb string
+		switch {
+		case !inOld:
+			verb = "add"
+		case !inCur:
+			verb, c = "rm", o
+		case sameTask(o, c):
+			continue
+		case !o.Done && c.Done:
+			verb = "done"
+		case o.Done && !c.Done:
+			verb = "reopen"
+		case !slices.Equal(o.Tags, c.Tags) && sameTask(o, withTags(c, o.Tags)):
+			verb = "tag"
+		default:
+			verb = "edit"
+		}
+		if Sealed(c.Title) {
+			// an encrypted title would only put ciphertext in the log
+			changes = append(changes, fmt.Sprintf("%s %d", verb, id))
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s %d: %s", verb, id, c.Title))
+	}
+
+	switch len(changes) {
+	case 0:
+		return "update tasks"
+	case 1:
+		return changes[0]
+	}
+	return fmt.Sprintf("%s (and %d more)\n%s", changes[0], len(changes)-1, strings.Join(changes, "\n"))
+}
+
+// withTags returns a copy of t with the given tags.
+func withTags(t Task, tags []string) Task {
+	t.Tags = tags
+	return t
+}
@@ -0,0 +1,286 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// compile-time check: GitStore implements Store
+var _ Store = (*GitStore)(nil)
+
+// gitTest skips the test if git is not installed and isolates it from
+// the user's git configuration.
+func gitTest(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "tsk")
+	t.Setenv("GIT_AUTHOR_EMAIL", "tsk@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "tsk")
+	t.Setenv("GIT_COMMITTER_EMAIL", "tsk@example.com")
+}
+
+func subjects(t *testing.T, s *GitStore) []string {
+	t.Helper()
+	commits, err := s.Log(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("log: %v", err)
+	}
+	var out []string
+	for _, c := range commits {
+		out = append(out, c.Subject)
+	}
+	return out
+}
+
+func update(t *testing.T, s Store, fn func([]Task) []Task) {
+	t.Helper()
+	err := s.Update(context.Background(), func(tasks []Task) ([]Task, error) {
+		return fn(tasks), nil
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+}
+
+func TestGitSaveCommits(t *testing.T) {
+	gitTest(t)
+	ctx := context.Background()
+	s := NewGitStore(filepath.Join(t.TempDir(), "tasks"), "")
+
+	if got := subjects(t, s); len(got) != 0 {
+		t.Fatalf("log before first save = %v, want empty", got)
+	}
+
+	update(t, s, func(tasks []Task) []Task { return Add(tasks, "buy milk", PriorityNone) })
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 1)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task { return tasks }) // no change, no commit
+
+	want := []string{"done 1: buy milk", "add 1: buy milk"}
+	got := subjects(t, s)
+	if len(got) != len(want) {
+		t.Fatalf("log = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("commit %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	tasks, err := NewGitStore(s.Dir, "").Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || !tasks[0].Done {
+		t.Errorf("reloaded tasks = %+v, want one done task", tasks)
+	}
+
+	commits, err := s.Log(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || time.Since(commits[0].Time) > time.Minute {
+		t.Errorf("Log(1) = %+v, want the latest commit", commits)
+	}
+}
+
+func TestGitSync(t *testing.T) {
+	gitTest(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init --bare: %v: %s", err, out)
+	}
+
+	conflicts := filepath.Join(dir, "conflicts.json")
+	newStore := func(name string) *GitStore {
+		s := NewGitStore(filepath.Join(dir, name), remote)
+		s.ConflictsPath = conflicts
+		return s
+	}
+	sync := func(s *GitStore) {
+		t.Helper()
+		if err := s.Sync(ctx); err != nil {
+			t.Fatalf("sync %s: %v", filepath.Base(s.Dir), err)
+		}
+	}
+	load := func(s *GitStore) []Task {
+		t.Helper()
+		tasks, err := s.Load(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tasks
+	}
+
+	a, b := newStore("a"), newStore("b")
+	update(t, a, func(tasks []Task) []Task {
+		tasks = Add(tasks, "one", PriorityNone)
+		return Add(tasks, "two", PriorityNone)
+	})
+	sync(a)
+
+	// b starts empty and picks up a's history
+	sync(b)
+	if got := summary(load(b)); got != "1:one 2:two" {
+		t.Fatalf("b after first sync = %s", got)
+	}
+
+	st, err := a.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Ahead != 0 || st.Behind != 0 {
+		t.Errorf("status after sync = %+v, want even", st)
+	}
+
+	// both edit different tasks: the second sync has to merge
+	update(t, a, func(tasks []Task) []Task {
+		Edit(tasks, 1, "one (a)")
+		return tasks
+	})
+	update(t, b, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 2)
+		return tasks
+	})
+	sync(a)
+	if st, err := b.Status(ctx); err != nil || st.Ahead != 1 || st.Behind != 1 {
+		t.Errorf("diverged status = %+v, %v; want 1 ahead, 1 behind", st, err)
+	}
+	sync(b)
+	sync(a)
+	for _, s := range []*GitStore{a, b} {
+		tasks := load(s)
+		if got := summary(tasks); got != "1:one (a) 2:two" || !tasks[1].Done {
+			t.Errorf("%s after merge = %s (task 2 done: %v)", filepath.Base(s.Dir), got, tasks[1].Done)
+		}
+	}
+
+	// both add a task: same ID on each side, the merge renumbers ours
+	update(t, a, func(tasks []Task) []Task { return Add(tasks, "from a", PriorityNone) })
+	update(t, b, func(tasks []Task) []Task { return Add(tasks, "from b", PriorityNone) })
+	sync(a)
+	sync(b)
+	if got := summary(load(b)); got != "1:one (a) 2:two 3:from a 4:from b" {
+		t.Errorf("b after concurrent adds = %s", got)
+	}
+
+	// both edit the same task: ours wins and the conflict is recorded
+	sync(a)
+	update(t, a, func(tasks []Task) []Task {
+		Edit(tasks, 1, "one (a again)")
+		return tasks
+	})
+	update(t, b, func(tasks []Task) []Task {
+		Edit(tasks, 1, "one (b)")
+		return tasks
+	})
+	sync(a)
+	sync(b)
+	if got := load(b)[0].Title; got != "one (b)" {
+		t.Errorf("conflicting title = %q, want ours", got)
+	}
+	recorded, err := LoadConflicts(conflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 || recorded[0].ID != 1 {
+		t.Errorf("conflicts = %+v, want task 1", recorded)
+	}
+}
+
+func TestGitSyncWithoutRemote(t *testing.T) {
+	gitTest(t)
+	s := NewGitStore(filepath.Join(t.TempDir(), "tasks"), "")
+	if err := s.Sync(context.Background()); err == nil {
+		t.Error("expected an error without a remote")
+	}
+}
+
+func TestGitSyncWaitsForLock(t *testing.T) {
+	gitTest(t)
+	s := NewGitStore(filepath.Join(t.TempDir(), "tasks"), "")
+	if err := s.init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := lockFile(context.Background(), s.file.Path+".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Sync(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("sync while locked: err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestGitSaveWithoutIdentity(t *testing.T) {
+	gitTest(t)
+	global := filepath.Join(t.TempDir(), "gitconfig")
+	if err := os.WriteFile(global, []byte("[user]\n\tuseConfigOnly = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", global)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL", "EMAIL"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	s := NewGitStore(filepath.Join(t.TempDir(), "tasks"), "")
+	update(t, s, func(tasks []Task) []Task { return Add(tasks, "buy milk", PriorityNone) })
+	if got := subjects(t, s); len(got) != 1 || got[0] != "add 1: buy milk" {
+		t.Errorf("log = %q, want the add committed", got)
+	}
+}
+
+func TestDescribeChange(t *testing.T) {
+	base := []Task{
+		{ID: 1, Title: "buy milk"},
+		{ID: 2, Title: "call mum", Tags: []string{"home"}},
+		{ID: 3, Title: "write report", Done: true},
+	}
+	with := func(fn func(tasks []Task) []Task) []Task {
+		return fn(Clone(base))
+	}
+
+	tests := []struct {
+		name  string
+		after []Task
+		want  string
+	}{
+		{"unchanged", base, "update tasks"},
+		{"add", with(func(ts []Task) []Task { return append(ts, Task{ID: 4, Title: "new"}) }), "add 4: new"},
+		{"rm", with(func(ts []Task) []Task { return ts[1:] }), "rm 1: buy milk"},
+		{"done", with(func(ts []Task) []Task { ts[0].Done = true; return ts }), "done 1: buy milk"},
+		{"reopen", with(func(ts []Task) []Task { ts[2].Done = false; return ts }), "reopen 3: write report"},
+		{"tag", with(func(ts []Task) []Task { ts[1].Tags = nil; return ts }), "tag 2: call mum"},
+		{"edit", with(func(ts []Task) []Task { ts[1].Title = "call dad"; return ts }), "edit 2: call dad"},
+		{"encrypted", with(func(ts []Task) []Task { ts[0].Title = sealedPrefix + "c2VjcmV0"; ts[0].Done = true; return ts }), "done 1"},
+		{"several", with(func(ts []Task) []Task {
+			ts[0].Done = true
+			return append(ts[:2], Task{ID: 4, Title: "new"})
+		}), "done 1: buy milk (and 2 more)\ndone 1: buy milk\nrm 3: write report\nadd 4: new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeChange(base, tt.after); got != tt.want {
+				t.Errorf("describeChange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
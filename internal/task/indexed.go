@@ -0,0 +1,773 @@
+package task
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// indexedMagic starts every IndexedStore file.
+const indexedMagic = "TSKIDX1\n"
+
+// compactMinSize is the file size below which IndexedStore never
+// bothers to compact.
+const compactMinSize = 1 << 20
+
+const (
+	recordPut    byte = 1
+	recordDelete byte = 2
+)
+
+// indexEntry locates the latest record for one task and holds the
+// fields the index is keyed on.
+type indexEntry struct {
+	off    int64  // offset of the record payload
+	n      int    // payload length
+	crc    uint32 // payload checksum, to spot unchanged tasks on Save
+	done   bool
+	tags   []string
+	parent int
+	deps   []int
+}
+
+// IndexedStore persists tasks in a compact binary file of checksummed
+// records and keeps an in-memory index by ID, tag and status. Saving
+// appends records only for the tasks that changed, and removals as
+// tombstones; the file is compacted once most of it is superseded.
+//
+// The index is built on first use by reading each record's ID, status,
+// tags, parent and dependencies; the rest of a task is decoded only when
+// it is asked for, so Get, Tagged, WithStatus and Related avoid decoding
+// the whole list. Load returns tasks in ID order.
+//
+// An IndexedStore is not safe for concurrent use; separate processes
+// are kept apart by the lock in Update and the revision check in Save.
+type IndexedStore struct {
+	Path string
+
+	byID     map[int]*indexEntry
+	byTag    map[string]map[int]bool // nil until first needed
+	byStatus [2]map[int]bool         // indexed by done; built with byTag
+	byParent map[int]map[int]bool    // subtasks of each task; built with byTag
+	byDep    map[int]map[int]bool    // dependents of each task; built with byTag
+
+	info   os.FileInfo // the file the index was built from
+	size   int64       // file size seen by the index
+	end    int64       // offset just past the last good record
+	dead   int64       // bytes in superseded records
+	built  bool
+	loaded bool
+}
+
+// NewIndexedStore returns an IndexedStore that reads/writes the given path.
+func NewIndexedStore(path string) *IndexedStore {
+	return &IndexedStore{Path: path}
+}
+
+// Load decodes every task, in ID order. Returns an empty slice if the
+// file does not exist.
+func (s *IndexedStore) Load(ctx context.Context) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	s.loaded = true
+	if len(s.byID) == 0 {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", s.Path, err)
+	}
+	ids := slices.Sorted(maps.Keys(s.byID))
+	tasks := make([]Task, 0, len(ids))
+	for _, id := range ids {
+		t, err := s.decodeAt(data, s.byID[id])
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// Save writes a record for every task that differs from the stored one
+// and a tombstone for every stored task missing from tasks. If the file
+// has changed since the last Load, Save returns a *ConflictError.
+func (s *IndexedStore) Save(ctx context.Context, tasks []Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := s.checkRevision(); err != nil {
+		return err
+	}
+
+	var batch []byte
+	keep := make(map[int]bool, len(tasks))
+	for _, t := range tasks {
+		keep[t.ID] = true
+		payload := encodeRecord(t)
+		if e := s.byID[t.ID]; e != nil && e.n == len(payload) && e.crc == crc32.ChecksumIEEE(payload) {
+			continue
+		}
+		batch = appendRecord(batch, payload)
+	}
+	for id := range s.byID {
+		if !keep[id] {
+			batch = appendRecord(batch, encodeDelete(id))
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := s.append(batch); err != nil {
+		return fmt.Errorf("write %s: %w", s.Path, err)
+	}
+	return s.maybeCompact()
+}
+
+// Update runs a load-modify-save cycle under the same lock as FileStore.
+func (s *IndexedStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	return lockedUpdate(ctx, s.Path+".lock", s, fn)
+}
+
+// Get decodes the task with the given ID without loading the others.
+func (s *IndexedStore) Get(ctx context.Context, id int) (Task, error) {
+	if err := ctx.Err(); err != nil {
+		return Task{}, err
+	}
+	if err := s.refresh(); err != nil {
+		return Task{}, err
+	}
+	e := s.byID[id]
+	if e == nil {
+		return Task{}, fmt.Errorf("task %d: not found", id)
+	}
+	tasks, err := s.read([]*indexEntry{e})
+	if err != nil {
+		return Task{}, err
+	}
+	return tasks[0], nil
+}
+
+// Tagged returns the tasks with the given tag, in ID order.
+func (s *IndexedStore) Tagged(ctx context.Context, tag string) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	s.secondary()
+	return s.readIDs(s.byTag[tag])
+}
+
+// WithStatus returns the done or pending tasks, in ID order.
+func (s *IndexedStore) WithStatus(ctx context.Context, done bool) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	s.secondary()
+	return s.readIDs(s.byStatus[b2i(done)])
+}
+
+// Related returns tasks together with their parents, subtasks,
+// dependencies and dependents, in ID order. That is every task Progress,
+// Dependents, OpenDependencies, Blocked and Ready look at for the given
+// ones, so they can be shown without a Load. Tasks already in the list
+// are not decoded again.
+func (s *IndexedStore) Related(ctx context.Context, tasks []Task) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	s.secondary()
+
+	have := make(map[int]bool, len(tasks))
+	for _, t := range tasks {
+		have[t.ID] = true
+	}
+	more := make(map[int]bool)
+	add := func(id int) {
+		if !have[id] && s.byID[id] != nil {
+			more[id] = true
+		}
+	}
+	for _, t := range tasks {
+		add(t.Parent)
+		for _, id := range t.DependsOn {
+			add(id)
+		}
+		for id := range s.byParent[t.ID] {
+			add(id)
+		}
+		for id := range s.byDep[t.ID] {
+			add(id)
+		}
+	}
+
+	extra, err := s.readIDs(more)
+	if err != nil {
+		return nil, err
+	}
+	all := append(slices.Clone(tasks), extra...)
+	slices.SortFunc(all, func(a, b Task) int { return a.ID - b.ID })
+	return all, nil
+}
+
+// Compact rewrites the file with only the latest record for each task.
+func (s *IndexedStore) Compact() error {
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read %s: %w", s.Path, err)
+	}
+
+	out := []byte(indexedMagic)
+	for _, id := range slices.Sorted(maps.Keys(s.byID)) {
+		e := s.byID[id]
+		out = appendRecord(out, data[e.off:e.off+int64(e.n)])
+	}
+	if err := writeFileAtomic(s.Path, out, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", s.Path, err)
+	}
+
+	s.built = false
+	return s.refresh()
+}
+
+// checkRevision makes sure the index matches the file before a write:
+// it is built if needed, and if it was built for a Load, the file must
+// not have changed since.
+func (s *IndexedStore) checkRevision() error {
+	if !s.loaded {
+		return s.refresh()
+	}
+	info, err := os.Stat(s.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("stat %s: %w", s.Path, err)
+	}
+	if !s.sameFile(info) || (info != nil && info.Size() != s.size) {
+		current := ""
+		if info != nil {
+			current = strconv.FormatInt(info.Size(), 10)
+		}
+		return &ConflictError{Loaded: strconv.FormatInt(s.size, 10), Current: current}
+	}
+	return nil
+}
+
+// refresh brings the index up to date with the file: records appended
+// since it was built are indexed, and a replaced file is indexed anew.
+func (s *IndexedStore) refresh() error {
+	info, err := os.Stat(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.reset(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat %s: %w", s.Path, err)
+	}
+
+	if !s.built || !s.sameFile(info) || info.Size() < s.size {
+		s.reset(info)
+	} else if info.Size() == s.size {
+		return nil
+	}
+	return s.scan(info)
+}
+
+func (s *IndexedStore) sameFile(info os.FileInfo) bool {
+	if info == nil || s.info == nil {
+		return info == nil && s.info == nil && s.size == 0
+	}
+	return os.SameFile(info, s.info)
+}
+
+func (s *IndexedStore) reset(info os.FileInfo) {
+	s.byID = make(map[int]*indexEntry)
+	s.byTag, s.byStatus, s.byParent, s.byDep = nil, [2]map[int]bool{}, nil, nil
+	s.info, s.size, s.end, s.dead, s.built = info, 0, 0, 0, true
+}
+
+// scan indexes the records from s.end to the end of the file. It stops
+// at the first incomplete or corrupt record, which a crash mid-append
+// can leave behind; the next append overwrites it.
+func (s *IndexedStore) scan(info os.FileInfo) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	if s.end == 0 {
+		magic := make([]byte, len(indexedMagic))
+		if _, err := io.ReadFull(f, magic); err != nil || string(magic) != indexedMagic {
+			return fmt.Errorf("%s: not a tsk indexed store", s.Path)
+		}
+		s.end = int64(len(indexedMagic))
+	} else if _, err := f.Seek(s.end, io.SeekStart); err != nil {
+		return fmt.Errorf("seek %s: %w", s.Path, err)
+	}
+
+	r := bufio.NewReaderSize(f, 1<<16)
+	var payload []byte
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			break
+		}
+		var crc [4]byte
+		if _, err := io.ReadFull(r, crc[:]); err != nil {
+			break
+		}
+		if n > uint64(info.Size()) {
+			break
+		}
+		payload = slices.Grow(payload[:0], int(n))[:n]
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+		sum := binary.LittleEndian.Uint32(crc[:])
+		if crc32.ChecksumIEEE(payload) != sum {
+			break
+		}
+
+		off := s.end + int64(uvarintLen(n)) + 4
+		if err := s.index(payload, off, sum); err != nil {
+			return fmt.Errorf("%s: record at byte %d: %w", s.Path, s.end, err)
+		}
+		s.end = off + int64(n)
+	}
+
+	s.info, s.size = info, info.Size()
+	return nil
+}
+
+// index applies one record to the index.
+func (s *IndexedStore) index(payload []byte, off int64, crc uint32) error {
+	d := decoder{b: payload}
+	kind := d.byte()
+	id := int(d.varint())
+
+	if old := s.byID[id]; old != nil {
+		s.dead += int64(old.n)
+		if s.byTag != nil {
+			delete(s.byStatus[b2i(old.done)], id)
+			for _, tag := range old.tags {
+				delete(s.byTag[tag], id)
+			}
+			delete(s.byParent[old.parent], id)
+			for _, dep := range old.deps {
+				delete(s.byDep[dep], id)
+			}
+		}
+		delete(s.byID, id)
+	}
+
+	switch kind {
+	case recordDelete:
+		s.dead += int64(len(payload))
+	case recordPut:
+		e := &indexEntry{off: off, n: len(payload), crc: crc, done: d.bool()}
+		for range d.count() {
+			e.tags = append(e.tags, d.string())
+		}
+		// skip from the title to the notes to reach the parent
+		d.skipString()
+		d.skipString()
+		d.skipTime()
+		d.skipOptTime()
+		d.skipOptTime()
+		d.skipString()
+		d.varint()
+		d.skipString()
+		e.parent = int(d.varint())
+		for range d.count() {
+			e.deps = append(e.deps, int(d.varint()))
+		}
+		if d.err != nil {
+			return d.err
+		}
+		s.byID[id] = e
+		if s.byTag != nil {
+			s.addSecondary(id, e)
+		}
+	default:
+		return fmt.Errorf("unknown record kind %d", kind)
+	}
+	return d.err
+}
+
+// secondary builds the tag, status, parent and dependency indexes on
+// first use; from then on index keeps them up to date.
+func (s *IndexedStore) secondary() {
+	if s.byTag != nil {
+		return
+	}
+	s.byTag = make(map[string]map[int]bool)
+	s.byStatus = [2]map[int]bool{make(map[int]bool), make(map[int]bool)}
+	s.byParent = make(map[int]map[int]bool)
+	s.byDep = make(map[int]map[int]bool)
+	for id, e := range s.byID {
+		s.addSecondary(id, e)
+	}
+}
+
+func (s *IndexedStore) addSecondary(id int, e *indexEntry) {
+	s.byStatus[b2i(e.done)][id] = true
+	for _, tag := range e.tags {
+		addTo(s.byTag, tag, id)
+	}
+	if e.parent != 0 {
+		addTo(s.byParent, e.parent, id)
+	}
+	for _, dep := range e.deps {
+		addTo(s.byDep, dep, id)
+	}
+}
+
+func addTo[K comparable](m map[K]map[int]bool, key K, id int) {
+	if m[key] == nil {
+		m[key] = make(map[int]bool)
+	}
+	m[key][id] = true
+}
+
+// append writes encoded records at the end of the last good record and
+// indexes them.
+func (s *IndexedStore) append(batch []byte) error {
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	start := s.end
+	if start == 0 {
+		batch = append([]byte(indexedMagic), batch...)
+	}
+	if _, err := f.WriteAt(batch, start); err != nil {
+		return err
+	}
+	if err := f.Truncate(start + int64(len(batch))); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if start == 0 {
+		s.reset(info)
+	}
+	return s.scan(info)
+}
+
+func (s *IndexedStore) maybeCompact() error {
+	if s.size < compactMinSize || s.dead*2 < s.size {
+		return nil
+	}
+	return s.Compact()
+}
+
+// readIDs decodes the tasks with the given IDs, in ID order.
+func (s *IndexedStore) readIDs(ids map[int]bool) ([]Task, error) {
+	entries := make([]*indexEntry, 0, len(ids))
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		entries = append(entries, s.byID[id])
+	}
+	return s.read(entries)
+}
+
+// read decodes the given entries with one ReadAt each.
+func (s *IndexedStore) read(entries []*indexEntry) ([]Task, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", s.Path, err)
+	}
+	defer f.Close()
+
+	tasks := make([]Task, 0, len(entries))
+	var buf []byte
+	for _, e := range entries {
+		buf = slices.Grow(buf[:0], e.n)[:e.n]
+		if _, err := f.ReadAt(buf, e.off); err != nil {
+			return nil, fmt.Errorf("read %s: %w", s.Path, err)
+		}
+		t, err := decodeRecord(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%s: record at byte %d: %w", s.Path, e.off, err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+func (s *IndexedStore) decodeAt(data []byte, e *indexEntry) (Task, error) {
+	t, err := decodeRecord(data[e.off : e.off+int64(e.n)])
+	if err != nil {
+		return Task{}, fmt.Errorf("%s: record at byte %d: %w", s.Path, e.off, err)
+	}
+	return t, nil
+}
+
+// appendRecord frames a payload as length, checksum, payload.
+func appendRecord(b, payload []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	b = binary.LittleEndian.AppendUint32(b, crc32.ChecksumIEEE(payload))
+	return append(b, payload...)
+}
+
+func encodeDelete(id int) []byte {
+	b := []byte{recordDelete}
+	return binary.AppendVarint(b, int64(id))
+}
+
+// encodeRecord encodes a task as a put record. The ID, status and tags
+// come first so the index can be built without decoding the rest; the
+// parent and dependencies are reached by skipping over the fields
+// before them. Every Task field must be encoded here.
+func encodeRecord(t Task) []byte {
+	b := []byte{recordPut}
+	b = binary.AppendVarint(b, int64(t.ID))
+	b = appendBool(b, t.Done)
+	b = binary.AppendUvarint(b, uint64(len(t.Tags)))
+	for _, tag := range t.Tags {
+		b = appendString(b, tag)
+	}
+
+	b = appendString(b, t.Title)
+	b = appendString(b, string(t.Priority))
+	b = appendTime(b, t.CreatedAt)
+	b = appendOptTime(b, t.CompletedAt)
+	b = appendOptTime(b, t.Due)
+	b = appendString(b, t.Project)
+	b = binary.AppendVarint(b, int64(t.ProjectID))
+	b = appendString(b, t.Notes)
+	b = binary.AppendVarint(b, int64(t.Parent))
+	b = binary.AppendUvarint(b, uint64(len(t.DependsOn)))
+	for _, id := range t.DependsOn {
+		b = binary.AppendVarint(b, int64(id))
+	}
+	b = appendString(b, t.Recur)
+	b = binary.AppendUvarint(b, uint64(len(t.Intervals)))
+	for _, iv := range t.Intervals {
+		b = appendTime(b, iv.Start)
+		b = appendOptTime(b, iv.End)
+	}
+	return b
+}
+
+// decodeRecord decodes a put record written by encodeRecord.
+func decodeRecord(payload []byte) (Task, error) {
+	d := decoder{b: payload}
+	if kind := d.byte(); kind != recordPut {
+		return Task{}, fmt.Errorf("not a task record (kind %d)", kind)
+	}
+
+	var t Task
+	t.ID = int(d.varint())
+	t.Done = d.bool()
+	for range d.count() {
+		t.Tags = append(t.Tags, d.string())
+	}
+
+	t.Title = d.string()
+	t.Priority = Priority(d.string())
+	t.CreatedAt = d.time()
+	t.CompletedAt = d.optTime()
+	t.Due = d.optTime()
+	t.Project = d.string()
+	t.ProjectID = int(d.varint())
+	t.Notes = d.string()
+	t.Parent = int(d.varint())
+	for range d.count() {
+		t.DependsOn = append(t.DependsOn, int(d.varint()))
+	}
+	t.Recur = d.string()
+	for range d.count() {
+		t.Intervals = append(t.Intervals, Interval{Start: d.time(), End: d.optTime()})
+	}
+	return t, d.err
+}
+
+func appendBool(b []byte, v bool) []byte {
+	return append(b, byte(b2i(v)))
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendTime encodes t as seconds, nanoseconds and zone offset, which
+// is all its JSON form keeps.
+func appendTime(b []byte, t time.Time) []byte {
+	_, offset := t.Zone()
+	b = binary.AppendVarint(b, t.Unix())
+	b = binary.AppendUvarint(b, uint64(t.Nanosecond()))
+	return binary.AppendVarint(b, int64(offset))
+}
+
+func appendOptTime(b []byte, t *time.Time) []byte {
+	if t == nil {
+		return append(b, 0)
+	}
+	return appendTime(append(b, 1), *t)
+}
+
+// decoder reads the encoding written by the append functions. After the
+// first error every read returns the zero value and err is kept.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+var errShortRecord = errors.New("record too short")
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.b) == 0 {
+		d.fail()
+		return 0
+	}
+	v := d.b[0]
+	d.b = d.b[1:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	return d.byte() == 1
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// count reads a length, which cannot exceed the bytes left.
+func (d *decoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.b)) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.count()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+func (d *decoder) skipString() {
+	n := d.count()
+	if d.err == nil {
+		d.b = d.b[n:]
+	}
+}
+
+func (d *decoder) time() time.Time {
+	sec, nsec, offset := d.varint(), d.uvarint(), d.varint()
+	if d.err != nil {
+		return time.Time{}
+	}
+	t := time.Unix(sec, int64(nsec))
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", int(offset)))
+}
+
+func (d *decoder) optTime() *time.Time {
+	if !d.bool() {
+		return nil
+	}
+	t := d.time()
+	return &t
+}
+
+// skipTime and skipOptTime skip a time without building its zone.
+func (d *decoder) skipTime() {
+	d.varint()
+	d.uvarint()
+	d.varint()
+}
+
+func (d *decoder) skipOptTime() {
+	if d.bool() {
+		d.skipTime()
+	}
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errShortRecord
+	}
+}
+
+func uvarintLen(v uint64) int {
+	return len(binary.AppendUvarint(nil, v))
+}
+
+func b2i(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,442 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// compile-time check: IndexedStore implements Store
+var _ Store = (*IndexedStore)(nil)
+
+func fullTask() Task {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 6, time.FixedZone("", 3600))
+	completed := created.Add(time.Hour).UTC()
+	due := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	return Task{
+		ID:          42,
+		Title:       "write report",
+		Done:        true,
+		Priority:    PriorityHigh,
+		CreatedAt:   created,
+		CompletedAt: &completed,
+		Due:         &due,
+		Tags:        []string{"work", "q1"},
+		Project:     "acme",
+		ProjectID:   4,
+		Notes:       "2026-01-02 called vendor",
+		Parent:      7,
+		DependsOn:   []int{3, 5},
+		Recur:       "1w",
+		Intervals: []Interval{
+			{Start: created, End: &completed},
+			{Start: completed},
+		},
+	}
+}
+
+func TestIndexedRecordRoundTrip(t *testing.T) {
+	want := fullTask()
+
+	// every field is set, so a field missing from the encoding shows up
+	v := reflect.ValueOf(want)
+	for i := range v.NumField() {
+		if v.Field(i).IsZero() {
+			t.Fatalf("fullTask leaves %s unset", v.Type().Field(i).Name)
+		}
+	}
+
+	got, err := decodeRecord(encodeRecord(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameTask(got, want) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := decodeRecord(encodeRecord(want)[:20]); err == nil {
+		t.Error("truncated record decoded without error")
+	}
+
+	// the index reads its fields without decoding the record
+	s := NewIndexedStore("")
+	s.reset(nil)
+	if err := s.index(encodeRecord(want), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	e := s.byID[want.ID]
+	if !e.done || !slices.Equal(e.tags, want.Tags) || e.parent != want.Parent || !slices.Equal(e.deps, want.DependsOn) {
+		t.Errorf("index entry = %+v, want the task's status, tags, parent and dependencies", e)
+	}
+}
+
+func TestIndexedStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s := NewIndexedStore(path)
+
+	update(t, s, func(tasks []Task) []Task {
+		tasks = Add(tasks, "buy milk", PriorityNone)
+		tasks = Add(tasks, "call mum", PriorityNone)
+		tasks = Add(tasks, "write report", PriorityNone)
+		Tag(tasks, 2, []string{"home"}, nil)
+		Tag(tasks, 3, []string{"home", "work"}, nil)
+		return tasks
+	})
+	update(t, s, func(tasks []Task) []Task {
+		tasks, _, _ = Done(tasks, 3)
+		tasks, _ = Remove(tasks, 1)
+		return tasks
+	})
+
+	fresh := NewIndexedStore(path)
+	tasks, err := fresh.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(tasks); got != "2:call mum 3:write report" {
+		t.Errorf("Load = %s", got)
+	}
+
+	got, err := NewIndexedStore(path).Get(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "write report" || !got.Done || len(got.Tags) != 2 {
+		t.Errorf("Get(3) = %+v", got)
+	}
+	if _, err := fresh.Get(ctx, 1); err == nil {
+		t.Error("Get(1) found a removed task")
+	}
+
+	tests := []struct {
+		name string
+		fn   func() ([]Task, error)
+		want string
+	}{
+		{"tag home", func() ([]Task, error) { return fresh.Tagged(ctx, "home") }, "2:call mum 3:write report"},
+		{"tag work", func() ([]Task, error) { return fresh.Tagged(ctx, "work") }, "3:write report"},
+		{"tag none", func() ([]Task, error) { return fresh.Tagged(ctx, "nope") }, ""},
+		{"pending", func() ([]Task, error) { return fresh.WithStatus(ctx, false) }, "2:call mum"},
+		{"done", func() ([]Task, error) { return fresh.WithStatus(ctx, true) }, "3:write report"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := tt.fn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(tasks); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// once built, the tag and status indexes follow later saves
+	update(t, fresh, func(tasks []Task) []Task {
+		Tag(tasks, 2, nil, []string{"home"})
+		tasks, _, _ = Done(tasks, 2)
+		return tasks
+	})
+	if tasks, err := fresh.Tagged(ctx, "home"); err != nil || summary(tasks) != "3:write report" {
+		t.Errorf("Tagged(home) after untagging = %s, %v", summary(tasks), err)
+	}
+	if tasks, err := fresh.WithStatus(ctx, false); err != nil || len(tasks) != 0 {
+		t.Errorf("WithStatus(false) after completing = %s, %v", summary(tasks), err)
+	}
+}
+
+func TestIndexedStoreRelated(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s := NewIndexedStore(path)
+
+	update(t, s, func(tasks []Task) []Task {
+		for _, title := range []string{"launch", "design", "build", "test", "unrelated"} {
+			tasks = Add(tasks, title, PriorityNone)
+		}
+		SetParent(tasks, 2, 1)
+		SetParent(tasks, 3, 1)
+		Block(tasks, 3, 2)
+		Block(tasks, 4, 3)
+		return tasks
+	})
+
+	related := func(id int) string {
+		t.Helper()
+		got, err := s.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks, err := s.Related(ctx, []Task{got})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary(tasks)
+	}
+
+	tests := []struct {
+		id   int
+		want string
+	}{
+		{1, "1:launch 2:design 3:build"},
+		{2, "1:launch 2:design 3:build"},
+		{3, "1:launch 2:design 3:build 4:test"},
+		{5, "5:unrelated"},
+	}
+	for _, tt := range tests {
+		if got := related(tt.id); got != tt.want {
+			t.Errorf("Related(%d) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+
+	// the parent and dependency indexes follow later saves
+	update(t, s, func(tasks []Task) []Task {
+		Unblock(tasks, 4, 3)
+		SetParent(tasks, 5, 3)
+		return tasks
+	})
+	if got, want := related(3), "1:launch 2:design 3:build 5:unrelated"; got != want {
+		t.Errorf("Related(3) after changes = %q, want %q", got, want)
+	}
+}
+
+func TestIndexedStoreAppendsChangesOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s := NewIndexedStore(path)
+	update(t, s, func(tasks []Task) []Task { return benchTasks(1000) })
+	before := fileSize(t, path)
+
+	update(t, s, func(tasks []Task) []Task {
+		Edit(tasks, 500, "changed")
+		return tasks
+	})
+	after := fileSize(t, path)
+	if grown := after - before; grown <= 0 || grown > 200 {
+		t.Errorf("file grew by %d bytes for one edit, want one small record", grown)
+	}
+
+	update(t, s, func(tasks []Task) []Task { return tasks })
+	if fileSize(t, path) != after {
+		t.Error("saving unchanged tasks wrote to the file")
+	}
+}
+
+func TestIndexedStoreOtherWriters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	a, b := NewIndexedStore(path), NewIndexedStore(path)
+
+	tasks, err := a.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update(t, b, func(tasks []Task) []Task { return Add(tasks, "from b", PriorityNone) })
+
+	var conflict *ConflictError
+	if err := a.Save(ctx, Add(tasks, "from a", PriorityNone)); !errors.As(err, &conflict) {
+		t.Errorf("stale save: err = %v, want *ConflictError", err)
+	}
+
+	// the index picks up records appended by b, and a rewrite by b
+	if got, err := a.Get(ctx, 1); err != nil || got.Title != "from b" {
+		t.Errorf("Get(1) = %+v, %v; want b's task", got, err)
+	}
+	update(t, b, func(tasks []Task) []Task { return Add(tasks, "again", PriorityNone) })
+	if err := b.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := a.Get(ctx, 2); err != nil || got.Title != "again" {
+		t.Errorf("Get(2) after compaction = %+v, %v", got, err)
+	}
+}
+
+func TestIndexedStoreCompact(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	s := NewIndexedStore(path)
+
+	update(t, s, func([]Task) []Task { return benchTasks(100) })
+	for i := range 20 {
+		update(t, s, func(tasks []Task) []Task {
+			for j := range tasks {
+				tasks[j].Title = fmt.Sprintf("rev %d", i)
+			}
+			return tasks
+		})
+	}
+	before := fileSize(t, path)
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after := fileSize(t, path); after*10 > before {
+		t.Errorf("compaction left %d of %d bytes", after, before)
+	}
+
+	tasks, err := NewIndexedStore(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 100 || tasks[99].Title != "rev 19" {
+		t.Errorf("after compaction: %d tasks, last %+v", len(tasks), tasks[len(tasks)-1])
+	}
+}
+
+func TestIndexedStoreTornWrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	update(t, NewIndexedStore(path), func(tasks []Task) []Task { return Add(tasks, "a", PriorityNone) })
+
+	// a crash mid-append leaves half a record
+	record := appendRecord(nil, encodeRecord(Task{ID: 2, Title: "b"}))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(record[:len(record)/2])
+	f.Close()
+
+	update(t, NewIndexedStore(path), func(tasks []Task) []Task {
+		if len(tasks) != 1 {
+			t.Errorf("loaded %d tasks past a torn record, want 1", len(tasks))
+		}
+		return Add(tasks, "c", PriorityNone)
+	})
+	tasks, err := NewIndexedStore(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(tasks); got != "1:a 2:c" {
+		t.Errorf("tasks = %s", got)
+	}
+}
+
+func TestIndexedStoreRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	if err := os.WriteFile(path, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIndexedStore(path).Load(context.Background()); err == nil {
+		t.Error("loaded a JSON file as an indexed store")
+	}
+}
+
+// benchTasks returns n tasks with a mix of tags and statuses.
+func benchTasks(n int) []Task {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tasks := make([]Task, n)
+	for i := range tasks {
+		tasks[i] = Task{
+			ID:        i + 1,
+			Title:     fmt.Sprintf("imported task number %d from the old tracker", i+1),
+			Done:      i%3 == 0,
+			CreatedAt: created.Add(time.Duration(i) * time.Minute),
+			Tags:      []string{fmt.Sprintf("tag%d", i%50)},
+			Project:   fmt.Sprintf("project%d", i%10),
+		}
+	}
+	return tasks
+}
+
+var benchSizes = []int{1_000, 10_000, 100_000}
+
+// benchStores returns a FileStore and an IndexedStore holding n tasks.
+func benchStores(b *testing.B, n int) map[string]Store {
+	b.Helper()
+	ctx := context.Background()
+	dir := b.TempDir()
+	stores := map[string]Store{
+		"file":    NewFileStore(filepath.Join(dir, "tasks.json")),
+		"indexed": NewIndexedStore(filepath.Join(dir, "tasks.db")),
+	}
+	for _, s := range stores {
+		if err := s.Save(ctx, benchTasks(n)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return stores
+}
+
+// reopen returns a new store for the same file, so each benchmark
+// iteration starts cold, as every tsk command does.
+func reopen(s Store) Store {
+	switch s := s.(type) {
+	case *FileStore:
+		return NewFileStore(s.Path)
+	case *IndexedStore:
+		return NewIndexedStore(s.Path)
+	}
+	panic("unknown store")
+}
+
+func BenchmarkStoreLoad(b *testing.B) {
+	for _, n := range benchSizes {
+		stores := benchStores(b, n)
+		for _, name := range []string{"file", "indexed"} {
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				for range b.N {
+					if _, err := reopen(stores[name]).Load(context.Background()); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkStoreDone is a whole `tsk done` cycle: load, complete one
+// task, save.
+func BenchmarkStoreDone(b *testing.B) {
+	for _, n := range benchSizes {
+		stores := benchStores(b, n)
+		for _, name := range []string{"file", "indexed"} {
+			b.Run(fmt.Sprintf("%s/%d", name, n), func(b *testing.B) {
+				for i := range b.N {
+					id := 2 + 3*(i%(n/3)) // a pending task
+					err := reopen(stores[name]).Update(context.Background(), func(tasks []Task) ([]Task, error) {
+						t := Find(tasks, id)
+						t.Done = !t.Done
+						return tasks, nil
+					})
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkStoreGet looks up one task: FileStore has to load and scan
+// everything, IndexedStore decodes only that task.
+func BenchmarkStoreGet(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range benchSizes {
+		stores := benchStores(b, n)
+		b.Run(fmt.Sprintf("file/%d", n), func(b *testing.B) {
+			for range b.N {
+				tasks, err := reopen(stores["file"]).Load(ctx)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if Find(tasks, n/2) == nil {
+					b.Fatal("not found")
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("indexed/%d", n), func(b *testing.B) {
+			for range b.N {
+				s := reopen(stores["indexed"]).(*IndexedStore)
+				if _, err := s.Get(ctx, n/2); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
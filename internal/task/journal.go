@@ -0,0 +1,292 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// journalLimit caps how many operations the journal keeps.
+const journalLimit = 50
+
+// Operation is a single recorded mutation with the task list before and
+// after it was applied.
+type Operation struct {
+	Command string    `json:"command"`
+	Time    time.Time `json:"time"`
+	Before  []Task    `json:"before"`
+	After   []Task    `json:"after"`
+}
+
+// journalFile is the on-disk journal format. Ops before Cursor are
+// applied; ops from Cursor on have been undone and can be redone.
+type journalFile struct {
+	Ops    []Operation `json:"ops"`
+	Cursor int         `json:"cursor"`
+}
+
+// Journal records mutations so they can be undone and redone. It keeps
+// full snapshots, so it works the same for every Store backend.
+type Journal struct {
+	Path string
+
+	// Cipher, if set, encrypts the journal file as a whole, since it
+	// holds copies of the tasks. An unencrypted journal still loads.
+	Cipher *Cipher
+}
+
+// NewJournal returns a Journal that reads/writes the given path.
+func NewJournal(path string) *Journal {
+	return &Journal{Path: path}
+}
+
+func (j *Journal) load() (journalFile, error) {
+	var jf journalFile
+	data, err := os.ReadFile(j.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return jf, nil
+		}
+		return jf, fmt.Errorf("read %s: %w", j.Path, err)
+	}
+	if len(data) == 0 {
+		return jf, nil
+	}
+	text, err := j.Cipher.Open(string(data))
+	if err != nil {
+		return jf, fmt.Errorf("journal: %w", err)
+	}
+	if err := json.Unmarshal([]byte(text), &jf); err != nil {
+		return jf, fmt.Errorf("unmarshal journal: %w", err)
+	}
+	return jf, nil
+}
+
+func (j *Journal) save(jf journalFile) error {
+	data, err := json.Marshal(jf)
+	if err != nil {
+		return fmt.Errorf("marshal journal: %w", err)
+	}
+	text, err := j.Cipher.Seal(string(data))
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if err := writeFileAtomic(j.Path, []byte(text), 0600); err != nil {
+		return fmt.Errorf("write %s: %w", j.Path, err)
+	}
+	return nil
+}
+
+// SetCipher rewrites the journal encrypted with c, or unencrypted if c
+// is nil, and uses c from then on.
+func (j *Journal) SetCipher(c *Cipher) error {
+	jf, err := j.load()
+	if err != nil {
+		return err
+	}
+	j.Cipher = c
+	return j.save(jf)
+}
+
+// lock takes an exclusive lock on a sibling ".lock" file, so that two
+// tsk processes cannot both load the journal and lose each other's
+// entries when they save it.
+func (j *Journal) lock(ctx context.Context) (unlock func(), err error) {
+	return lockFile(ctx, j.Path+".lock")
+}
+
+// Record appends an operation. Anything that was undone and not yet
+// redone is discarded, and the oldest entries are dropped once the
+// journal grows past its limit. Saves that change nothing are ignored.
+func (j *Journal) Record(ctx context.Context, command string, before, after []Task) error {
+	if sameTasks(before, after) {
+		return nil
+	}
+
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	jf, err := j.load()
+	if err != nil {
+		return err
+	}
+
+	jf.Ops = append(jf.Ops[:jf.Cursor], Operation{
+		Command: command,
+		Time:    time.Now(),
+		Before:  before,
+		After:   after,
+	})
+	if len(jf.Ops) > journalLimit {
+		jf.Ops = slices.Clone(jf.Ops[len(jf.Ops)-journalLimit:])
+	}
+	jf.Cursor = len(jf.Ops)
+	return j.save(jf)
+}
+
+// Undo restores the task list from before the most recent applied
+// operation and returns that operation. It refuses if the stored tasks
+// no longer match what the operation left behind.
+func (j *Journal) Undo(ctx context.Context, store Store) (Operation, error) {
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return Operation{}, err
+	}
+	defer unlock()
+
+	jf, err := j.load()
+	if err != nil {
+		return Operation{}, err
+	}
+	if jf.Cursor == 0 {
+		return Operation{}, fmt.Errorf("nothing to undo")
+	}
+
+	op := jf.Ops[jf.Cursor-1]
+	if err := j.restore(ctx, store, op.After, op.Before, op.Command); err != nil {
+		return Operation{}, err
+	}
+	jf.Cursor--
+	return op, j.save(jf)
+}
+
+// Redo reapplies the most recently undone operation and returns it.
+func (j *Journal) Redo(ctx context.Context, store Store) (Operation, error) {
+	unlock, err := j.lock(ctx)
+	if err != nil {
+		return Operation{}, err
+	}
+	defer unlock()
+
+	jf, err := j.load()
+	if err != nil {
+		return Operation{}, err
+	}
+	if jf.Cursor == len(jf.Ops) {
+		return Operation{}, fmt.Errorf("nothing to redo")
+	}
+
+	op := jf.Ops[jf.Cursor]
+	if err := j.restore(ctx, store, op.Before, op.After, op.Command); err != nil {
+		return Operation{}, err
+	}
+	jf.Cursor++
+	return op, j.save(jf)
+}
+
+// restore replaces want with target in the store, checking first that
+// the store still holds want.
+func (j *Journal) restore(ctx context.Context, store Store, want, target []Task, command string) error {
+	return store.Update(ctx, func(current []Task) ([]Task, error) {
+		if !sameTasks(current, want) {
+			return nil, fmt.Errorf("tasks changed since %q was recorded; refusing to overwrite", command)
+		}
+		return target, nil
+	})
+}
+
+// Recent returns up to n of the newest operations, oldest first, along
+// with how many of them are currently applied (the rest can be redone).
+func (j *Journal) Recent(n int) (ops []Operation, applied int, err error) {
+	jf, err := j.load()
+	if err != nil {
+		return nil, 0, err
+	}
+	start := max(len(jf.Ops)-n, 0)
+	return jf.Ops[start:], max(jf.Cursor-start, 0), nil
+}
+
+// sameTasks reports whether two task lists serialize identically.
+func sameTasks(a, b []Task) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// Clone returns a deep copy of tasks, so later in-place edits do not
+// affect the copy.
+func Clone(tasks []Task) []Task {
+	if tasks == nil {
+		return nil
+	}
+	out := make([]Task, len(tasks))
+	for i, t := range tasks {
+		t.Tags = slices.Clone(t.Tags)
+		t.DependsOn = slices.Clone(t.DependsOn)
+		t.Intervals = slices.Clone(t.Intervals)
+		out[i] = t
+	}
+	return out
+}
+
+// JournaledStore wraps a Store and records every Save in a Journal,
+// using the tasks seen by the last Load as the "before" snapshot.
+type JournaledStore struct {
+	Store
+	Journal *Journal
+	Command string // label recorded with each operation, e.g. "done 3"
+
+	loaded []Task
+}
+
+// NewJournaledStore returns a Store that records saves made by command.
+func NewJournaledStore(store Store, journal *Journal, command string) *JournaledStore {
+	return &JournaledStore{Store: store, Journal: journal, Command: command}
+}
+
+// Load reads tasks from the wrapped store and remembers a snapshot.
+func (s *JournaledStore) Load(ctx context.Context) ([]Task, error) {
+	tasks, err := s.Store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.loaded = Clone(tasks)
+	return tasks, nil
+}
+
+// Update runs fn through the wrapped store's Update and records the
+// change once it has been saved.
+func (s *JournaledStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	var before, after []Task
+	err := s.Store.Update(ctx, func(tasks []Task) ([]Task, error) {
+		before = Clone(tasks)
+		tasks, err := fn(tasks)
+		if err != nil {
+			return nil, err
+		}
+		after = Clone(tasks)
+		return tasks, nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.Journal.Record(ctx, s.Command, before, after); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	s.loaded = after
+	return nil
+}
+
+// Save writes tasks to the wrapped store, then records the change.
+func (s *JournaledStore) Save(ctx context.Context, tasks []Task) error {
+	if err := s.Store.Save(ctx, tasks); err != nil {
+		return err
+	}
+	after := Clone(tasks)
+	if err := s.Journal.Record(ctx, s.Command, s.loaded, after); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	s.loaded = after
+	return nil
+}
@@ -0,0 +1,221 @@
+package task
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// compile-time check: JournaledStore implements Store
+var _ Store = (*JournaledStore)(nil)
+
+func tempJournal(t *testing.T) *Journal {
+	t.Helper()
+	return NewJournal(filepath.Join(t.TempDir(), "journal.json"))
+}
+
+// run performs one load-mutate-save cycle through a journaled store.
+func run(t *testing.T, store Store, journal *Journal, command string, mutate func([]Task) []Task) {
+	t.Helper()
+	js := NewJournaledStore(store, journal, command)
+	tasks, err := js.Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if err := js.Save(context.Background(), mutate(tasks)); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func titles(t *testing.T, store Store) string {
+	t.Helper()
+	tasks, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	var out []string
+	for _, tk := range tasks {
+		s := tk.Title
+		if tk.Done {
+			s += "*"
+		}
+		out = append(out, s)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestUndoRedo(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	run(t, store, journal, "add a", func(ts []Task) []Task { return Add(ts, "a", "") })
+	run(t, store, journal, "add b", func(ts []Task) []Task { return Add(ts, "b", "") })
+	run(t, store, journal, "done 1", func(ts []Task) []Task {
+		ts, _, err := Done(ts, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	})
+
+	steps := []struct {
+		name    string
+		do      func(context.Context, Store) (Operation, error)
+		command string
+		want    string
+	}{
+		{"undo done", journal.Undo, "done 1", "a,b"},
+		{"undo add", journal.Undo, "add b", "a"},
+		{"redo add", journal.Redo, "add b", "a,b"},
+		{"redo done", journal.Redo, "done 1", "a*,b"},
+		{"undo again", journal.Undo, "done 1", "a,b"},
+	}
+	for _, s := range steps {
+		op, err := s.do(context.Background(), store)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if op.Command != s.command {
+			t.Errorf("%s: command = %q, want %q", s.name, op.Command, s.command)
+		}
+		if got := titles(t, store); got != s.want {
+			t.Errorf("%s: tasks = %q, want %q", s.name, got, s.want)
+		}
+	}
+}
+
+func TestUndoNothing(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	if _, err := journal.Undo(context.Background(), store); err == nil {
+		t.Error("undo on empty journal: expected error")
+	}
+	if _, err := journal.Redo(context.Background(), store); err == nil {
+		t.Error("redo on empty journal: expected error")
+	}
+
+	run(t, store, journal, "add a", func(ts []Task) []Task { return Add(ts, "a", "") })
+	if _, err := journal.Undo(context.Background(), store); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if _, err := journal.Undo(context.Background(), store); err == nil {
+		t.Error("undo past start: expected error")
+	}
+	if got := titles(t, store); got != "" {
+		t.Errorf("tasks = %q, want none", got)
+	}
+}
+
+func TestRecordDiscardsRedo(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	run(t, store, journal, "add a", func(ts []Task) []Task { return Add(ts, "a", "") })
+	run(t, store, journal, "add b", func(ts []Task) []Task { return Add(ts, "b", "") })
+	if _, err := journal.Undo(context.Background(), store); err != nil {
+		t.Fatal(err)
+	}
+	run(t, store, journal, "add c", func(ts []Task) []Task { return Add(ts, "c", "") })
+
+	if _, err := journal.Redo(context.Background(), store); err == nil {
+		t.Error("redo after new change: expected error")
+	}
+	ops, applied, err := journal.Recent(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || applied != 2 || ops[1].Command != "add c" {
+		t.Errorf("ops = %d (applied %d), want [add a, add c]", len(ops), applied)
+	}
+}
+
+func TestRecordSkipsNoop(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	run(t, store, journal, "add a", func(ts []Task) []Task { return Add(ts, "a", "") })
+	run(t, store, journal, "tag 1", func(ts []Task) []Task { return ts })
+
+	ops, _, err := journal.Recent(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 {
+		t.Errorf("got %d ops, want 1", len(ops))
+	}
+}
+
+func TestRecordConcurrent(t *testing.T) {
+	journal := tempJournal(t)
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// each goroutine has its own Journal, like separate processes
+			j := NewJournal(journal.Path)
+			after := []Task{{ID: i + 1, Title: "x"}}
+			if err := j.Record(context.Background(), "add", nil, after); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ops, _, err := journal.Recent(n * 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != n {
+		t.Errorf("got %d ops, want %d", len(ops), n)
+	}
+}
+
+func TestRecordLimit(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	for range journalLimit + 5 {
+		run(t, store, journal, "add", func(ts []Task) []Task { return Add(ts, "x", "") })
+	}
+	ops, applied, err := journal.Recent(journalLimit * 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != journalLimit || applied != journalLimit {
+		t.Errorf("got %d ops (applied %d), want %d", len(ops), applied, journalLimit)
+	}
+}
+
+func TestUndoRefusesDivergedStore(t *testing.T) {
+	store := tempStore(t)
+	journal := tempJournal(t)
+
+	run(t, store, journal, "add a", func(ts []Task) []Task { return Add(ts, "a", "") })
+	// change made outside the journal, e.g. from another machine
+	if err := store.Save(context.Background(), []Task{{ID: 1, Title: "other"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := journal.Undo(context.Background(), store); err == nil {
+		t.Fatal("expected error for diverged store")
+	}
+	if got := titles(t, store); got != "other" {
+		t.Errorf("tasks = %q, want untouched", got)
+	}
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	orig := []Task{{ID: 1, Tags: []string{"a"}, DependsOn: []int{2}}}
+	c := Clone(orig)
+	orig[0].Tags[0] = "changed"
+	orig[0].DependsOn[0] = 9
+	if c[0].Tags[0] != "a" || c[0].DependsOn[0] != 2 {
+		t.Errorf("clone shares slices with original: %+v", c[0])
+	}
+}
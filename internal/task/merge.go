@@ -0,0 +1,302 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Conflict is a task that was changed on both sides of a merge in
+// different ways. A nil side means the task was removed there.
+type Conflict struct {
+	ID     int   `json:"id"`
+	Base   *Task `json:"base,omitempty"`
+	Ours   *Task `json:"ours,omitempty"`
+	Theirs *Task `json:"theirs,omitempty"`
+}
+
+// FieldDiff is one field that differs between the two sides of a
+// conflict, with JSON-formatted values ("" when absent).
+type FieldDiff struct {
+	Field  string
+	Ours   string
+	Theirs string
+}
+
+// Merge combines two task lists that both started from base, matching
+// tasks by ID. A task changed on only one side takes that side's
+// version. A task changed on both sides is reported as a conflict and
+// keeps our version, unless one side removed it, in which case the
+// surviving version is kept so nothing is lost. Tasks added on both
+// sides under the same ID keep their version; ours is given a new ID,
+// and so is the per-project ID of any of ours that was also used
+// remotely.
+func Merge(base, ours, theirs []Task) ([]Task, []Conflict) {
+	baseByID := byID(base)
+	oursByID := byID(ours)
+	theirsByID := byID(theirs)
+
+	ids := make(map[int]bool)
+	for _, m := range []map[int]Task{baseByID, oursByID, theirsByID} {
+		for id := range m {
+			ids[id] = true
+		}
+	}
+
+	var merged []Task
+	var conflicts []Conflict
+	var collided []Task // our additions whose ID was also added remotely
+	fromOurs := make(map[int]bool)
+	added := make(map[int]bool) // our additions, by their merged ID
+
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		b, inBase := baseByID[id]
+		o, inOurs := oursByID[id]
+		t, inTheirs := theirsByID[id]
+
+		switch {
+		case !inBase:
+			// added on one or both sides
+			switch {
+			case inOurs && inTheirs && !sameTask(o, t):
+				merged = append(merged, t)
+				collided = append(collided, o)
+			case inTheirs:
+				merged = append(merged, t)
+			default:
+				merged = append(merged, o)
+				fromOurs[id] = true
+				added[id] = true
+			}
+
+		case inOurs && inTheirs:
+			switch {
+			case sameTask(o, b) || sameTask(o, t):
+				merged = append(merged, t)
+			case sameTask(t, b):
+				merged = append(merged, o)
+				fromOurs[id] = true
+			default:
+				merged = append(merged, o)
+				fromOurs[id] = true
+				conflicts = append(conflicts, Conflict{ID: id, Base: &b, Ours: &o, Theirs: &t})
+			}
+
+		case inOurs: // removed remotely
+			if !sameTask(o, b) {
+				merged = append(merged, o)
+				fromOurs[id] = true
+				conflicts = append(conflicts, Conflict{ID: id, Base: &b, Ours: &o})
+			}
+
+		case inTheirs: // removed locally
+			if !sameTask(t, b) {
+				merged = append(merged, t)
+				conflicts = append(conflicts, Conflict{ID: id, Base: &b, Theirs: &t})
+			}
+		}
+	}
+
+	if len(collided) == 0 {
+		renumberProjectIDs(merged, added)
+		return merged, conflicts
+	}
+
+	// renumber our colliding additions after everything else, and point
+	// our own references at their new IDs
+	remap := make(map[int]int)
+	next := nextID(merged)
+	for _, o := range collided {
+		remap[o.ID] = next
+		o.ID = next
+		merged = append(merged, o)
+		fromOurs[next] = true
+		added[next] = true
+		next++
+	}
+	for i := range merged {
+		if !fromOurs[merged[i].ID] {
+			continue
+		}
+		t := &merged[i]
+		if to, ok := remap[t.Parent]; ok {
+			t.Parent = to
+		}
+		deps := slices.Clone(t.DependsOn)
+		for j, d := range deps {
+			if to, ok := remap[d]; ok {
+				deps[j] = to
+			}
+		}
+		t.DependsOn = deps
+	}
+	renumberProjectIDs(merged, added)
+	return merged, conflicts
+}
+
+// renumberProjectIDs gives each of our additions whose per-project ID
+// was also taken remotely the next free one in its project.
+func renumberProjectIDs(merged []Task, added map[int]bool) {
+	taken := make(map[string]map[int]bool)
+	for _, t := range merged {
+		if !added[t.ID] && t.ProjectID != 0 {
+			if taken[t.Project] == nil {
+				taken[t.Project] = make(map[int]bool)
+			}
+			taken[t.Project][t.ProjectID] = true
+		}
+	}
+	for i := range merged {
+		t := &merged[i]
+		if added[t.ID] && taken[t.Project][t.ProjectID] {
+			t.ProjectID = nextProjectID(merged, t.Project)
+		}
+	}
+}
+
+// Resolve applies one side of a conflict to tasks: the chosen version
+// replaces the task, or removes it (with its subtasks, as rm does) if
+// that side had removed it.
+func Resolve(tasks []Task, c Conflict, useOurs bool) []Task {
+	chosen := c.Theirs
+	if useOurs {
+		chosen = c.Ours
+	}
+
+	if chosen == nil {
+		if out, err := Remove(tasks, c.ID); err == nil {
+			return out
+		}
+		return tasks
+	}
+	out := slices.DeleteFunc(tasks, func(t Task) bool { return t.ID == c.ID })
+	out = append(out, *chosen)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Fields lists the fields that differ between the two sides.
+func (c Conflict) Fields() []FieldDiff {
+	return diffFields(c.Ours, c.Theirs)
+}
+
+// diffFields lists the fields that differ between two versions of a task.
+func diffFields(a, b *Task) []FieldDiff {
+	ours, theirs := fieldMap(a), fieldMap(b)
+	keys := make(map[string]bool)
+	for k := range ours {
+		keys[k] = true
+	}
+	for k := range theirs {
+		keys[k] = true
+	}
+
+	var diffs []FieldDiff
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		if ours[k] != theirs[k] {
+			diffs = append(diffs, FieldDiff{Field: k, Ours: ours[k], Theirs: theirs[k]})
+		}
+	}
+	return diffs
+}
+
+// Title returns the task title from whichever side still has it.
+func (c Conflict) Title() string {
+	for _, t := range []*Task{c.Ours, c.Theirs, c.Base} {
+		if t != nil {
+			return t.Title
+		}
+	}
+	return ""
+}
+
+func fieldMap(t *Task) map[string]string {
+	if t == nil {
+		return nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	out := make(map[string]string, len(raw))
+	for k, v := range raw {
+		out[k] = string(v)
+	}
+	return out
+}
+
+func byID(tasks []Task) map[int]Task {
+	m := make(map[int]Task, len(tasks))
+	for _, t := range tasks {
+		m[t.ID] = t
+	}
+	return m
+}
+
+func sameTask(a, b Task) bool {
+	return sameTasks([]Task{a}, []Task{b})
+}
+
+// LoadConflicts reads unresolved conflicts from path.
+// Returns an empty slice if the file does not exist.
+func LoadConflicts(path string) ([]Conflict, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var conflicts []Conflict
+	if err := json.Unmarshal(data, &conflicts); err != nil {
+		return nil, fmt.Errorf("unmarshal conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+// SaveConflicts writes conflicts to path, removing the file when there
+// are none left.
+func SaveConflicts(path string, conflicts []Conflict) error {
+	if len(conflicts) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(conflicts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal conflicts: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// addConflicts merges new conflicts into those stored at path; a newer
+// conflict for the same task replaces the older one.
+func addConflicts(path string, conflicts []Conflict) error {
+	existing, err := LoadConflicts(path)
+	if err != nil {
+		return err
+	}
+	for _, c := range conflicts {
+		existing = slices.DeleteFunc(existing, func(e Conflict) bool { return e.ID == c.ID })
+		existing = append(existing, c)
+	}
+	sort.Slice(existing, func(i, j int) bool { return existing[i].ID < existing[j].ID })
+	return SaveConflicts(path, existing)
+}
@@ -0,0 +1,218 @@
+package task
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// summary renders tasks as "id:title" pairs for compact comparisons.
+func summary(tasks []Task) string {
+	parts := make([]string, len(tasks))
+	for i, t := range tasks {
+		parts[i] = fmt.Sprintf("%d:%s", t.ID, t.Title)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestMerge(t *testing.T) {
+	base := []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}}
+
+	tests := []struct {
+		name          string
+		ours          []Task
+		theirs        []Task
+		want          string
+		wantConflicts []int
+	}{
+		{
+			name:   "no changes",
+			ours:   base,
+			theirs: base,
+			want:   "1:a 2:b 3:c",
+		},
+		{
+			name:   "different tasks edited",
+			ours:   []Task{{ID: 1, Title: "a2"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}},
+			theirs: []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b2"}, {ID: 3, Title: "c"}},
+			want:   "1:a2 2:b2 3:c",
+		},
+		{
+			name:   "same edit on both sides",
+			ours:   []Task{{ID: 1, Title: "a2"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}},
+			theirs: []Task{{ID: 1, Title: "a2"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}},
+			want:   "1:a2 2:b 3:c",
+		},
+		{
+			name:          "same task edited differently",
+			ours:          []Task{{ID: 1, Title: "ours"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}},
+			theirs:        []Task{{ID: 1, Title: "theirs"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c"}},
+			want:          "1:ours 2:b 3:c",
+			wantConflicts: []int{1},
+		},
+		{
+			name:   "removed on one side, untouched on the other",
+			ours:   []Task{{ID: 1, Title: "a"}, {ID: 3, Title: "c"}},
+			theirs: []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}},
+			want:   "1:a",
+		},
+		{
+			name:          "removed locally, edited remotely",
+			ours:          []Task{{ID: 1, Title: "a"}, {ID: 3, Title: "c"}},
+			theirs:        []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b2"}, {ID: 3, Title: "c"}},
+			want:          "1:a 2:b2 3:c",
+			wantConflicts: []int{2},
+		},
+		{
+			name:          "edited locally, removed remotely",
+			ours:          []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b2"}, {ID: 3, Title: "c"}},
+			theirs:        []Task{{ID: 1, Title: "a"}, {ID: 3, Title: "c"}},
+			want:          "1:a 2:b2 3:c",
+			wantConflicts: []int{2},
+		},
+		{
+			name:   "added on different sides",
+			ours:   append(Clone(base), Task{ID: 4, Title: "mine"}),
+			theirs: append(Clone(base), Task{ID: 5, Title: "theirs"}),
+			want:   "1:a 2:b 3:c 4:mine 5:theirs",
+		},
+		{
+			name:   "same ID added on both sides",
+			ours:   append(Clone(base), Task{ID: 4, Title: "mine"}),
+			theirs: append(Clone(base), Task{ID: 4, Title: "theirs"}),
+			want:   "1:a 2:b 3:c 4:theirs 5:mine",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := Merge(base, tt.ours, tt.theirs)
+			if got := summary(merged); got != tt.want {
+				t.Errorf("merged = %q, want %q", got, tt.want)
+			}
+			var ids []int
+			for _, c := range conflicts {
+				ids = append(ids, c.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", ids, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestMergeRenumbersReferences(t *testing.T) {
+	base := []Task{{ID: 1, Title: "a"}}
+	ours := []Task{
+		{ID: 1, Title: "a", DependsOn: []int{3}},
+		{ID: 2, Title: "mine"},
+		{ID: 3, Title: "mine child", Parent: 2},
+	}
+	theirs := []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "theirs"}}
+
+	merged, conflicts := Merge(base, ours, theirs)
+	if len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+	if got, want := summary(merged), "1:a 2:theirs 3:mine child 4:mine"; got != want {
+		t.Fatalf("merged = %q, want %q", got, want)
+	}
+	if child := Find(merged, 3); child.Parent != 4 {
+		t.Errorf("child parent = %d, want 4 (renumbered)", child.Parent)
+	}
+	if a := Find(merged, 1); len(a.DependsOn) != 1 || a.DependsOn[0] != 3 {
+		t.Errorf("task 1 depends on %v, want [3]", a.DependsOn)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ours := Task{ID: 2, Title: "ours"}
+	theirs := Task{ID: 2, Title: "theirs"}
+	// what Merge leaves in place: our edit, or the surviving version
+	withKept := func() []Task { return []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "kept"}, {ID: 3, Title: "c"}} }
+
+	tests := []struct {
+		name    string
+		tasks   []Task
+		c       Conflict
+		useOurs bool
+		want    string
+	}{
+		{"take ours", withKept(), Conflict{ID: 2, Ours: &ours, Theirs: &theirs}, true, "1:a 2:ours 3:c"},
+		{"take theirs", withKept(), Conflict{ID: 2, Ours: &ours, Theirs: &theirs}, false, "1:a 2:theirs 3:c"},
+		{"take our removal", withKept(), Conflict{ID: 2, Theirs: &theirs}, true, "1:a 3:c"},
+		{"take their edit over removal", withKept(), Conflict{ID: 2, Theirs: &theirs}, false, "1:a 2:theirs 3:c"},
+		{"restore when missing", []Task{{ID: 1, Title: "a"}, {ID: 3, Title: "c"}}, Conflict{ID: 2, Ours: &ours}, true, "1:a 2:ours 3:c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resolve(tt.tasks, tt.c, tt.useOurs)
+			if summary(got) != tt.want {
+				t.Errorf("got %q, want %q", summary(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictFields(t *testing.T) {
+	c := Conflict{
+		ID:     1,
+		Ours:   &Task{ID: 1, Title: "ours", Done: true},
+		Theirs: &Task{ID: 1, Title: "theirs", Tags: []string{"x"}},
+	}
+	var got []string
+	for _, d := range c.Fields() {
+		got = append(got, d.Field)
+	}
+	if want := "done,tags,title"; strings.Join(got, ",") != want {
+		t.Errorf("fields = %v, want %s", got, want)
+	}
+}
+
+func TestConflictsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conflicts.json")
+	a := &Task{ID: 1, Title: "a"}
+
+	if err := addConflicts(path, []Conflict{{ID: 2, Ours: a}, {ID: 1, Ours: a}}); err != nil {
+		t.Fatal(err)
+	}
+	// a newer conflict for task 2 replaces the old one
+	if err := addConflicts(path, []Conflict{{ID: 2, Theirs: a}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadConflicts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 || got[1].Ours != nil {
+		t.Fatalf("conflicts = %+v, want [1, 2 (replaced)]", got)
+	}
+
+	if err := SaveConflicts(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadConflicts(path); err != nil || len(got) != 0 {
+		t.Errorf("after clearing: %v, %v", got, err)
+	}
+}
+
+func TestMergeRenumbersProjectIDs(t *testing.T) {
+	base := []Task{{ID: 1, Title: "a", Project: "work", ProjectID: 1}}
+	ours := append(Clone(base),
+		Task{ID: 2, Title: "mine", Project: "work", ProjectID: 2},
+		Task{ID: 3, Title: "mine too", Project: "home", ProjectID: 1})
+	theirs := append(Clone(base),
+		Task{ID: 4, Title: "theirs", Project: "work", ProjectID: 2})
+
+	merged, _ := Merge(base, ours, theirs)
+	var refs []string
+	for _, t := range merged {
+		refs = append(refs, fmt.Sprintf("%d:%s", t.ID, Ref(t)))
+	}
+	if got, want := strings.Join(refs, " "), "1:work#1 2:work#3 3:home#1 4:work#2"; got != want {
+		t.Errorf("refs = %q, want %q", got, want)
+	}
+}
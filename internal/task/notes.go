@@ -0,0 +1,34 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetNotes replaces the notes of the task with the given ID.
+// Trailing whitespace is trimmed. Returns an error if the ID is not found.
+func SetNotes(tasks []Task, id int, notes string) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	t.Notes = strings.TrimRight(notes, " \t\r\n")
+	return nil
+}
+
+// AppendNote adds a timestamped line to the notes of the task with the
+// given ID. Returns an error if the ID is not found.
+func AppendNote(tasks []Task, id int, line string, now time.Time) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	entry := fmt.Sprintf("[%s] %s", now.Format("2006-01-02 15:04"), strings.TrimSpace(line))
+	if t.Notes == "" {
+		t.Notes = entry
+	} else {
+		t.Notes += "\n" + entry
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetNotes(t *testing.T) {
+	tasks := []Task{{ID: 1, Title: "a", Notes: "old"}}
+
+	if err := SetNotes(tasks, 1, "line one\nline two\n\n"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if want := "line one\nline two"; tasks[0].Notes != want {
+		t.Errorf("Notes = %q, want %q", tasks[0].Notes, want)
+	}
+
+	if err := SetNotes(tasks, 1, "  \n"); err != nil {
+		t.Fatalf("clear: %v", err)
+	}
+	if tasks[0].Notes != "" {
+		t.Errorf("Notes = %q, want empty", tasks[0].Notes)
+	}
+
+	if err := SetNotes(tasks, 99, "x"); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
+
+func TestAppendNote(t *testing.T) {
+	now := time.Date(2026, 3, 11, 9, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		notes string
+		line  string
+		want  string
+	}{
+		{"empty notes", "", "called vendor", "[2026-03-11 09:05] called vendor"},
+		{"existing notes", "first", "second", "first\n[2026-03-11 09:05] second"},
+		{"trims line", "", "  padded  ", "[2026-03-11 09:05] padded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []Task{{ID: 1, Title: "a", Notes: tt.notes}}
+			if err := AppendNote(tasks, 1, tt.line, now); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			if tasks[0].Notes != tt.want {
+				t.Errorf("Notes = %q, want %q", tasks[0].Notes, tt.want)
+			}
+		})
+	}
+
+	if err := AppendNote(nil, 1, "x", now); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
@@ -0,0 +1,182 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// gistCache is the local copy of a gist kept for offline use.
+type gistCache struct {
+	GistID  string    `json:"gist_id"`
+	Rev     string    `json:"rev"`     // gist revision of Base
+	Base    []Task    `json:"base"`    // tasks as last seen on the gist
+	Tasks   []Task    `json:"tasks"`   // local tasks; differ from Base while Pending
+	Pending bool      `json:"pending"` // Tasks have not been pushed yet
+	Synced  time.Time `json:"synced"`  // last successful contact with the gist
+}
+
+// SyncStatus describes how the local cache relates to the gist.
+type SyncStatus struct {
+	GistID  string
+	Synced  time.Time // zero if never synced
+	Pending bool      // local changes are queued
+	Ahead   int       // tasks changed locally since the last sync
+	Behind  int       // tasks changed on the gist since the last sync
+	Online  bool      // false if the gist could not be reached; Behind is then unknown
+}
+
+// Sync pushes any changes queued while offline and refreshes the cache.
+func (s *GistStore) Sync(ctx context.Context) error {
+	if _, err := s.Load(ctx); err != nil {
+		return err
+	}
+	if s.offline != nil {
+		return s.offline
+	}
+	return nil
+}
+
+// Status reports queued local changes and, if the gist is reachable,
+// how many tasks changed there since the last sync.
+func (s *GistStore) Status(ctx context.Context) (SyncStatus, error) {
+	cache, err := s.readCache()
+	if err != nil {
+		return SyncStatus{}, err
+	}
+
+	st := SyncStatus{
+		GistID:  s.GistID,
+		Synced:  cache.Synced,
+		Pending: cache.Pending,
+		Ahead:   countChanged(cache.Base, cache.Tasks),
+	}
+	if s.GistID == "" {
+		st.Online = true
+		return st, nil
+	}
+
+	gist, err := s.fetch(ctx)
+	if err != nil {
+		if isNetworkError(ctx, err) {
+			return st, nil
+		}
+		return SyncStatus{}, err
+	}
+	st.Online = true
+	if gist.revision() != cache.Rev {
+		theirs, err := gist.tasks()
+		if err != nil {
+			return SyncStatus{}, err
+		}
+		st.Behind = countChanged(cache.Base, theirs)
+	}
+	return st, nil
+}
+
+// goOffline switches to the cached tasks after a network error.
+func (s *GistStore) goOffline(cache gistCache, err error) []Task {
+	s.offline = err
+	s.rev, s.base, s.loaded = cache.Rev, cache.Base, true
+	fmt.Fprintf(os.Stderr, "gist: offline, using tasks cached %s\n", cache.Synced.Local().Format("2006-01-02 15:04"))
+	return cache.Tasks
+}
+
+// queue records tasks in the cache to be pushed later.
+func (s *GistStore) queue(tasks []Task) error {
+	if err := s.writeCache(tasks, true); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "gist: offline, change saved locally — run tsk sync when back online")
+	return nil
+}
+
+// readCache returns the cache for this gist, or an empty cache if there
+// is none or it belongs to a different gist.
+func (s *GistStore) readCache() (gistCache, error) {
+	var cache gistCache
+	if s.CachePath == "" {
+		return cache, nil
+	}
+
+	data, err := os.ReadFile(s.CachePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cache, nil
+		}
+		return cache, fmt.Errorf("read %s: %w", s.CachePath, err)
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return cache, fmt.Errorf("unmarshal gist cache: %w", err)
+	}
+	if cache.GistID != s.GistID {
+		return gistCache{}, nil
+	}
+	return cache, nil
+}
+
+// writeCache stores the current revision and base along with tasks.
+// Pending caches keep the time of the last successful sync.
+func (s *GistStore) writeCache(tasks []Task, pending bool) error {
+	if s.CachePath == "" {
+		return nil
+	}
+
+	synced := time.Now()
+	if pending {
+		old, err := s.readCache()
+		if err != nil {
+			return err
+		}
+		synced = old.Synced
+	}
+
+	data, err := json.Marshal(gistCache{
+		GistID:  s.GistID,
+		Rev:     s.rev,
+		Base:    s.base,
+		Tasks:   tasks,
+		Pending: pending,
+		Synced:  synced,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal gist cache: %w", err)
+	}
+	if err := writeFileAtomic(s.CachePath, data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", s.CachePath, err)
+	}
+	return nil
+}
+
+// isNetworkError reports whether err came from failing to reach the
+// server, as opposed to an error response or ctx being cancelled or
+// running out of time. A client timeout still counts as a network error.
+func isNetworkError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// countChanged counts the task IDs that were added, removed or changed
+// between a and b.
+func countChanged(a, b []Task) int {
+	am, bm := byID(a), byID(b)
+	n := 0
+	for id, t := range am {
+		if u, ok := bm[id]; !ok || !sameTask(t, u) {
+			n++
+		}
+	}
+	for id := range bm {
+		if _, ok := am[id]; !ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,196 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProjectSummary holds per-project task counts.
+type ProjectSummary struct {
+	Name    string
+	Pending int
+	Done    int
+}
+
+// ValidProject checks whether name can be used as a project name.
+// Names must be non-empty and contain no whitespace.
+func ValidProject(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n")
+}
+
+// InProject matches tasks belonging to the named project.
+// An empty name matches tasks without a project.
+func InProject(name string) Filter {
+	return func(t Task) bool {
+		return t.Project == name
+	}
+}
+
+// Unarchived matches tasks outside the archived projects.
+func Unarchived(archived []string) Filter {
+	return func(t Task) bool {
+		return !slices.Contains(archived, t.Project)
+	}
+}
+
+// Projects summarizes task counts per project, sorted by name.
+// Tasks without a project are reported under the empty name.
+func Projects(tasks []Task) []ProjectSummary {
+	byName := make(map[string]*ProjectSummary)
+	var names []string
+	for _, t := range tasks {
+		p, ok := byName[t.Project]
+		if !ok {
+			p = &ProjectSummary{Name: t.Project}
+			byName[t.Project] = p
+			names = append(names, t.Project)
+		}
+		if t.Done {
+			p.Done++
+		} else {
+			p.Pending++
+		}
+	}
+
+	sort.Strings(names)
+	out := make([]ProjectSummary, 0, len(names))
+	for _, n := range names {
+		out = append(out, *byName[n])
+	}
+	return out
+}
+
+// Ref returns the per-project reference of t, such as work#3, or "" if
+// t has no project or no per-project ID.
+func Ref(t Task) string {
+	if t.Project == "" || t.ProjectID == 0 {
+		return ""
+	}
+	return t.Project + "#" + strconv.Itoa(t.ProjectID)
+}
+
+// ParseRef splits a per-project reference such as work#3 into its
+// project and per-project ID.
+func ParseRef(s string) (project string, id int, ok bool) {
+	i := strings.LastIndexByte(s, '#')
+	if i < 0 {
+		return "", 0, false
+	}
+	id, err := strconv.Atoi(s[i+1:])
+	if err != nil || id <= 0 || !ValidProject(s[:i]) {
+		return "", 0, false
+	}
+	return s[:i], id, true
+}
+
+// FindRef returns a pointer to the task with the given per-project ID,
+// or nil if not found.
+func FindRef(tasks []Task, project string, id int) *Task {
+	for i := range tasks {
+		if tasks[i].Project == project && tasks[i].ProjectID == id {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// nextProjectID returns the next per-project ID in project, or 0 for
+// tasks without a project. Each project counts from 1.
+func nextProjectID(tasks []Task, project string) int {
+	if project == "" {
+		return 0
+	}
+	max := 0
+	for _, t := range tasks {
+		if t.Project == project && t.ProjectID > max {
+			max = t.ProjectID
+		}
+	}
+	return max + 1
+}
+
+// SetProject moves the task with the given ID to project, giving it the
+// next per-project ID there. An empty project removes it from its
+// project. Returns an error if the ID is not found or the name is
+// invalid.
+func SetProject(tasks []Task, id int, project string) error {
+	if project != "" && !ValidProject(project) {
+		return fmt.Errorf("invalid project name: %q", project)
+	}
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	if t.Project == project {
+		return nil
+	}
+	t.Project, t.ProjectID = project, nextProjectID(tasks, project)
+	return nil
+}
+
+// RenameProject moves every task in project from to project to. If to
+// already has tasks, the moved ones are numbered after them.
+// Returns the number of tasks moved, or an error if none were found.
+func RenameProject(tasks []Task, from, to string) (int, error) {
+	if !ValidProject(to) {
+		return 0, fmt.Errorf("invalid project name: %q", to)
+	}
+	if from == to {
+		n := len(List(tasks, InProject(from)))
+		if n == 0 {
+			return 0, fmt.Errorf("project %s: not found", from)
+		}
+		return n, nil
+	}
+
+	next := nextProjectID(tasks, to)
+	renumber := next > 1 // to has tasks of its own
+	var moved []*Task
+	for i := range tasks {
+		if tasks[i].Project == from {
+			moved = append(moved, &tasks[i])
+		}
+	}
+	if len(moved) == 0 {
+		return 0, fmt.Errorf("project %s: not found", from)
+	}
+	// number the moved tasks after those already in to, in their old order
+	slices.SortStableFunc(moved, func(a, b *Task) int { return a.ProjectID - b.ProjectID })
+	for _, t := range moved {
+		t.Project = to
+		if renumber {
+			t.ProjectID = next
+			next++
+		}
+	}
+	return len(moved), nil
+}
+
+// ArchiveProject adds name to the archived projects, which are kept
+// sorted, hiding its tasks from the default list view — including ones
+// added later. Returns an error if the project has no tasks or is
+// already archived.
+func ArchiveProject(archived []string, tasks []Task, name string) ([]string, error) {
+	if slices.Contains(archived, name) {
+		return nil, fmt.Errorf("project %s: already archived", name)
+	}
+	if !slices.ContainsFunc(tasks, func(t Task) bool { return t.Project == name }) {
+		return nil, fmt.Errorf("project %s: not found", name)
+	}
+	archived = append(slices.Clone(archived), name)
+	slices.Sort(archived)
+	return archived, nil
+}
+
+// UnarchiveProject removes name from the archived projects. Returns an
+// error if it is not archived.
+func UnarchiveProject(archived []string, name string) ([]string, error) {
+	i := slices.Index(archived, name)
+	if i < 0 {
+		return nil, fmt.Errorf("project %s: not archived", name)
+	}
+	return slices.Delete(slices.Clone(archived), i, i+1), nil
+}
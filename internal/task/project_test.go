@@ -0,0 +1,232 @@
+package task
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProjects(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "a", Project: "work"},
+		{ID: 2, Title: "b", Project: "work", Done: true},
+		{ID: 3, Title: "c", Project: "home"},
+		{ID: 4, Title: "d"},
+		{ID: 5, Title: "e", Project: "home"},
+	}
+
+	got := Projects(tasks)
+	want := []ProjectSummary{
+		{Name: "", Pending: 1},
+		{Name: "home", Pending: 2},
+		{Name: "work", Pending: 1, Done: 1},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("projects[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRenameProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantN   int
+		wantErr bool
+	}{
+		{"rename existing", "work", "job", 2, false},
+		{"missing project", "nope", "job", 0, true},
+		{"invalid new name", "work", "two words", 0, true},
+		{"empty new name", "work", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []Task{
+				{ID: 1, Title: "a", Project: "work"},
+				{ID: 2, Title: "b", Project: "home"},
+				{ID: 3, Title: "c", Project: "work"},
+			}
+			n, err := RenameProject(tasks, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if n != tt.wantN {
+				t.Errorf("n = %d, want %d", n, tt.wantN)
+			}
+			if err != nil {
+				return
+			}
+			if got := len(List(tasks, InProject(tt.to))); got != tt.wantN {
+				t.Errorf("tasks in %s = %d, want %d", tt.to, got, tt.wantN)
+			}
+			if tasks[1].Project != "home" {
+				t.Errorf("unrelated task moved to %q", tasks[1].Project)
+			}
+		})
+	}
+}
+
+func TestArchiveProject(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "a", Project: "work"},
+		{ID: 2, Title: "b", Project: "home"},
+		{ID: 3, Title: "c", Project: "old"},
+	}
+
+	archived, err := ArchiveProject([]string{"old"}, tasks, "home")
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	if want := []string{"home", "old"}; !slices.Equal(archived, want) {
+		t.Errorf("archived = %q, want %q", archived, want)
+	}
+
+	// tasks added to an archived project stay hidden
+	tasks = append(tasks, Task{ID: 4, Title: "d", Project: "home"})
+	if got := summary(List(tasks, Unarchived(archived))); got != "1:a" {
+		t.Errorf("active = %s, want only task 1", got)
+	}
+
+	tests := []struct {
+		name string
+		arch func() ([]string, error)
+		want string
+	}{
+		{"archive missing project", func() ([]string, error) { return ArchiveProject(archived, tasks, "nope") }, "project nope: not found"},
+		{"archive twice", func() ([]string, error) { return ArchiveProject(archived, tasks, "home") }, "project home: already archived"},
+		{"unarchive active project", func() ([]string, error) { return UnarchiveProject(archived, "work") }, "project work: not archived"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.arch(); err == nil || err.Error() != tt.want {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	archived, err = UnarchiveProject(archived, "home")
+	if err != nil {
+		t.Fatalf("unarchive: %v", err)
+	}
+	if want := []string{"old"}; !slices.Equal(archived, want) {
+		t.Errorf("archived after unarchive = %q, want %q", archived, want)
+	}
+	if got := summary(List(tasks, Unarchived(archived))); got != "1:a 2:b 4:d" {
+		t.Errorf("active after unarchive = %s", got)
+	}
+}
+
+func TestProjectIDs(t *testing.T) {
+	var tasks []Task
+	for _, p := range []string{"work", "home", "work", ""} {
+		tasks = Add(tasks, "t", PriorityNone)
+		if err := SetProject(tasks, len(tasks), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	refs := func() string {
+		var out []string
+		for _, t := range tasks {
+			out = append(out, Ref(t))
+		}
+		return strings.Join(out, ",")
+	}
+	if got, want := refs(), "work#1,home#1,work#2,"; got != want {
+		t.Fatalf("refs = %q, want %q", got, want)
+	}
+
+	// moving a task numbers it in its new project; staying put keeps it
+	if err := SetProject(tasks, 2, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetProject(tasks, 3, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := refs(), "work#1,work#3,work#2,"; got != want {
+		t.Errorf("refs after move = %q, want %q", got, want)
+	}
+	if err := SetProject(tasks, 9, "work"); err == nil {
+		t.Error("SetProject on a missing task succeeded")
+	}
+
+	if p := FindRef(tasks, "work", 3); p == nil || p.ID != 2 {
+		t.Errorf("FindRef(work#3) = %+v, want task 2", p)
+	}
+	if p := FindRef(tasks, "home", 1); p != nil {
+		t.Errorf("FindRef(home#1) = %+v, want none", p)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		in      string
+		project string
+		id      int
+		ok      bool
+	}{
+		{"work#3", "work", 3, true},
+		{"release-1.4#12", "release-1.4", 12, true},
+		{"c#sharp#2", "c#sharp", 2, true},
+		{"3", "", 0, false},
+		{"work#", "", 0, false},
+		{"#3", "", 0, false},
+		{"work#0", "", 0, false},
+		{"work#x", "", 0, false},
+	}
+	for _, tt := range tests {
+		project, id, ok := ParseRef(tt.in)
+		if project != tt.project || id != tt.id || ok != tt.ok {
+			t.Errorf("ParseRef(%q) = %q, %d, %v; want %q, %d, %v", tt.in, project, id, ok, tt.project, tt.id, tt.ok)
+		}
+	}
+}
+
+func TestRenameProjectRenumbers(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "a", Project: "job", ProjectID: 1},
+		{ID: 2, Title: "b", Project: "work", ProjectID: 2},
+		{ID: 3, Title: "c", Project: "work", ProjectID: 1},
+	}
+	if _, err := RenameProject(tasks, "work", "job"); err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, t := range tasks {
+		refs = append(refs, Ref(t))
+	}
+	if got, want := strings.Join(refs, ","), "job#1,job#3,job#2"; got != want {
+		t.Errorf("refs = %q, want %q", got, want)
+	}
+
+	// a new name keeps the numbers
+	if _, err := RenameProject(tasks, "job", "work"); err != nil {
+		t.Fatal(err)
+	}
+	if Ref(tasks[1]) != "work#3" {
+		t.Errorf("ref after rename = %q, want work#3", Ref(tasks[1]))
+	}
+}
+
+func TestValidProject(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"work", true},
+		{"release-1.4", true},
+		{"", false},
+		{"two words", false},
+		{"tab\there", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidProject(tt.name); got != tt.want {
+			t.Errorf("ValidProject(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ValidRecur checks whether rule is a recognized recurrence rule.
+// Rules are an interval ("3d", "1w", "2m"), a weekday ("monday", "mon"),
+// "month-end", or one of the aliases daily, weekly and monthly.
+func ValidRecur(rule string) bool {
+	_, err := NextDue(rule, time.Now())
+	return err == nil
+}
+
+// normalizeRecur maps aliases onto their interval form.
+func normalizeRecur(rule string) string {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	switch rule {
+	case "daily":
+		return "1d"
+	case "weekly":
+		return "1w"
+	case "monthly":
+		return "1m"
+	}
+	return rule
+}
+
+// NextDue returns the first due date of a recurring task strictly after
+// from, at midnight local time.
+func NextDue(rule string, from time.Time) (time.Time, error) {
+	rule = normalizeRecur(rule)
+	day := startOfDay(from)
+
+	if rule == "month-end" {
+		end := monthEnd(day)
+		if !end.After(day) {
+			end = monthEnd(end.AddDate(0, 0, 1))
+		}
+		return end, nil
+	}
+
+	if wd, ok := parseWeekday(rule); ok {
+		diff := (int(wd) - int(day.Weekday()) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return day.AddDate(0, 0, diff), nil
+	}
+
+	if n, unit, ok := parseOffset(rule); ok && n > 0 {
+		switch unit {
+		case 'd':
+			return day.AddDate(0, 0, n), nil
+		case 'w':
+			return day.AddDate(0, 0, 7*n), nil
+		case 'm':
+			return addMonths(day, n), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid recurrence: %s (use 3d/1w/2m, a weekday, month-end, daily, weekly or monthly)", rule)
+}
+
+// FirstDue returns the first due date on or after now for a new
+// recurring task that was added without an explicit due date.
+func FirstDue(rule string, now time.Time) (time.Time, error) {
+	rule = normalizeRecur(rule)
+	if _, _, ok := parseOffset(rule); ok {
+		if _, err := NextDue(rule, now); err != nil {
+			return time.Time{}, err
+		}
+		return startOfDay(now), nil
+	}
+	return NextDue(rule, startOfDay(now).AddDate(0, 0, -1))
+}
+
+// monthEnd returns the last day of t's month.
+func monthEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location())
+}
+
+// addMonths adds n months to t, clamping to the end of shorter months
+// so that Jan 31 + 1m is the last day of February.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, t.Location())
+	end := monthEnd(first)
+	if t.Day() > end.Day() {
+		return end
+	}
+	return first.AddDate(0, 0, t.Day()-1)
+}
+
+// nextOccurrence appends the next task in t's series to tasks.
+// The new task keeps the title, priority, tags and project of t, and its
+// due date moves forward from t's (or from now if t had none).
+func nextOccurrence(tasks []Task, t Task, now time.Time) ([]Task, error) {
+	from := now
+	if t.Due != nil {
+		from = *t.Due
+	}
+	due, err := NextDue(t.Recur, from)
+	if err != nil {
+		return tasks, err
+	}
+
+	next := Task{
+		ID:        nextID(tasks),
+		Title:     t.Title,
+		Priority:  t.Priority,
+		CreatedAt: now,
+		Due:       &due,
+		Tags:      append([]string(nil), t.Tags...),
+		Project:   t.Project,
+		ProjectID: nextProjectID(tasks, t.Project),
+		Parent:    t.Parent,
+		Recur:     t.Recur,
+	}
+	return append(tasks, next), nil
+}
+
+// StopRecur ends the series of the task with the given ID, so completing
+// it no longer creates a new occurrence.
+// Returns an error if the ID is not found or the task does not recur.
+func StopRecur(tasks []Task, id int) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	if t.Recur == "" {
+		return fmt.Errorf("task %d: does not recur", id)
+	}
+	t.Recur = ""
+	return nil
+}
+
+// FilterRecurring matches pending tasks that belong to a recurring series.
+func FilterRecurring(t Task) bool { return !t.Done && t.Recur != "" }
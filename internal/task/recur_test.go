@@ -0,0 +1,153 @@
+package task
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNextDue(t *testing.T) {
+	tests := []struct {
+		rule    string
+		from    time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{"1d", date(2026, 3, 11), date(2026, 3, 12), false},
+		{"daily", date(2026, 3, 11), date(2026, 3, 12), false},
+		{"1w", date(2026, 3, 11), date(2026, 3, 18), false},
+		{"weekly", date(2026, 3, 11), date(2026, 3, 18), false},
+		{"2w", date(2026, 3, 11), date(2026, 3, 25), false},
+		{"1m", date(2026, 1, 31), date(2026, 2, 28), false},
+		{"monthly", date(2026, 3, 15), date(2026, 4, 15), false},
+		{"monday", date(2026, 3, 11), date(2026, 3, 16), false},
+		{"mon", date(2026, 3, 16), date(2026, 3, 23), false},
+		{"month-end", date(2026, 1, 31), date(2026, 2, 28), false},
+		{"month-end", date(2026, 2, 10), date(2026, 2, 28), false},
+		{"month-end", date(2026, 12, 31), date(2027, 1, 31), false},
+		{"0d", date(2026, 3, 11), time.Time{}, true},
+		{"fortnightly", date(2026, 3, 11), time.Time{}, true},
+		{"", date(2026, 3, 11), time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.from.Format("2006-01-02"), func(t *testing.T) {
+			got, err := NextDue(tt.rule, tt.from)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err == nil && !got.Equal(tt.want) {
+				t.Errorf("NextDue(%q, %s) = %s, want %s", tt.rule, tt.from.Format("2006-01-02"),
+					got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestFirstDue(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 11, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule string
+		want time.Time
+	}{
+		{"1w", date(2026, 3, 11)},
+		{"wednesday", date(2026, 3, 11)},
+		{"friday", date(2026, 3, 13)},
+		{"month-end", date(2026, 3, 31)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := FirstDue(tt.rule, now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("FirstDue = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+
+	if _, err := FirstDue("bogus", now); err == nil {
+		t.Error("expected error for invalid rule")
+	}
+}
+
+func TestDoneRecurring(t *testing.T) {
+	due := date(2026, 3, 9)
+	tasks := []Task{
+		{ID: 1, Title: "rotate on-call", Recur: "1w", Due: &due, Tags: []string{"ops"}, Project: "work", ProjectID: 1, Priority: PriorityHigh},
+		{ID: 2, Title: "one-off"},
+	}
+
+	tasks, c, err := Done(tasks, 1)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("len = %d, want 3", len(tasks))
+	}
+	if !slices.Equal(c.Spawned, []int{3}) {
+		t.Errorf("spawned = %v, want [3]", c.Spawned)
+	}
+
+	next := tasks[2]
+	if next.ID != 3 || next.Done || next.Title != "rotate on-call" {
+		t.Errorf("next = %+v, want pending copy with ID 3", next)
+	}
+	if next.Due == nil || !next.Due.Equal(date(2026, 3, 16)) {
+		t.Errorf("next.Due = %v, want 2026-03-16", next.Due)
+	}
+	if next.Recur != "1w" || next.Project != "work" || next.Priority != PriorityHigh || !slices.Equal(next.Tags, []string{"ops"}) {
+		t.Errorf("next did not keep series fields: %+v", next)
+	}
+	if next.ProjectID != 2 {
+		t.Errorf("next.ProjectID = %d, want the next in the project, 2", next.ProjectID)
+	}
+
+	// tags are copied, not shared
+	next.Tags[0] = "changed"
+	if tasks[0].Tags[0] != "ops" {
+		t.Error("next occurrence shares tag slice with the completed task")
+	}
+
+	// non-recurring tasks don't spawn
+	tasks, c, err = Done(tasks, 2)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if len(tasks) != 3 || len(c.Spawned) != 0 {
+		t.Errorf("len = %d, spawned = %v; want 3 and none", len(tasks), c.Spawned)
+	}
+}
+
+func TestStopRecur(t *testing.T) {
+	tasks := []Task{{ID: 1, Recur: "1w"}, {ID: 2}}
+
+	if err := StopRecur(tasks, 1); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if tasks[0].Recur != "" {
+		t.Errorf("Recur = %q, want empty", tasks[0].Recur)
+	}
+	if err := StopRecur(tasks, 2); err == nil {
+		t.Error("expected error for non-recurring task")
+	}
+	if err := StopRecur(tasks, 99); err == nil {
+		t.Error("expected error for missing task")
+	}
+
+	tasks, _, err := Done(tasks, 1)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("len = %d, want 2 (stopped series should not regenerate)", len(tasks))
+	}
+}
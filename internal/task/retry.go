@@ -0,0 +1,130 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultGistTimeout is the per-request time limit for the Gist API.
+const DefaultGistTimeout = 30 * time.Second
+
+// Retry policy for the Gist API. Variables so tests can shorten them.
+var (
+	gistMaxRetries = 3
+	gistRetryBase  = 500 * time.Millisecond
+	gistMaxBackoff = 8 * time.Second
+	gistMaxWait    = time.Minute // longest server-requested wait we honor
+	gistSleep      = sleepContext
+)
+
+// do sends req, retrying transient failures: 5xx responses, rate limits,
+// timeouts and dropped connections. Waits follow Retry-After or
+// X-RateLimit-Reset when the server sends them, and exponential backoff
+// with jitter otherwise. The last response or error is returned as-is.
+func (s *GistStore) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := s.client.Do(req)
+		wait, retry := retryDelay(req, resp, err, attempt)
+		if !retry || attempt >= gistMaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := gistSleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleepContext waits for d, or returns early if ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryDelay decides whether a request should be retried and how long
+// to wait first.
+func retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if err != nil {
+		// a POST that timed out may still have created the gist
+		if req.Method == http.MethodPost || !transient(err) {
+			return 0, false
+		}
+		return backoff(attempt), true
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0":
+		// rate limited: nothing was processed, so any method can retry
+	case resp.StatusCode >= 500 && req.Method != http.MethodPost:
+	default:
+		return 0, false
+	}
+
+	if wait, ok := serverDelay(resp.Header, time.Now()); ok {
+		return wait, wait <= gistMaxWait
+	}
+	return backoff(attempt), true
+}
+
+// transient reports whether a request error is worth retrying.
+func transient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// serverDelay reads the wait requested by Retry-After (seconds or an
+// HTTP date) or, failing that, X-RateLimit-Reset (a Unix time).
+func serverDelay(h http.Header, now time.Time) (time.Duration, bool) {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(max(secs, 0)) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0), true
+		}
+	}
+	if v := h.Get("X-RateLimit-Reset"); v != "" {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return max(time.Unix(unix, 0).Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+// backoff returns the exponential delay for attempt with equal jitter:
+// half the delay is fixed and half is random.
+func backoff(attempt int) time.Duration {
+	d := min(gistRetryBase<<attempt, gistMaxBackoff)
+	half := d / 2
+	return half + rand.N(half+1)
+}
@@ -1,23 +1,40 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 )
 
 // Store abstracts task persistence, enabling pluggable backends.
 type Store interface {
-	Load() ([]Task, error)
-	Save([]Task) error
+	Load(ctx context.Context) ([]Task, error)
+	Save(ctx context.Context, tasks []Task) error
+	// Update loads the tasks, passes them to fn and saves the result as
+	// one transaction. Nothing is saved if fn returns an error.
+	Update(ctx context.Context, fn func([]Task) ([]Task, error)) error
+}
+
+// ConflictError is returned when a Save would overwrite changes that
+// were made after the tasks were loaded.
+type ConflictError struct {
+	Loaded  string // revision seen by Load
+	Current string // revision found when saving
+}
+
+func (e *ConflictError) Error() string {
+	return "tasks were changed elsewhere since they were loaded; run the command again"
 }
 
 // FileStore persists tasks as JSON in a local file.
 type FileStore struct {
 	Path string
+
+	rev    string // revision seen by the last Load or Save
+	loaded bool
 }
 
 // NewFileStore returns a FileStore that reads/writes the given path.
@@ -25,9 +42,21 @@ func NewFileStore(path string) *FileStore {
 	return &FileStore{Path: path}
 }
 
-// Load reads tasks from the JSON file.
+// Load reads tasks from the JSON file and records its revision.
 // Returns an empty slice if the file does not exist.
-func (s *FileStore) Load() ([]Task, error) {
+func (s *FileStore) Load(ctx context.Context) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// stat before reading: if the file changes in between, the next Save
+	// sees a spurious conflict rather than silently losing the change
+	rev, err := fileRevision(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	s.rev, s.loaded = rev, true
+
 	data, err := os.ReadFile(s.Path)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -47,25 +76,86 @@ func (s *FileStore) Load() ([]Task, error) {
 	return tasks, nil
 }
 
-// Save writes tasks to the JSON file.
-func (s *FileStore) Save(tasks []Task) error {
+// Save writes tasks to the JSON file. The file is replaced atomically,
+// so a crash mid-write leaves the previous contents intact. If the file
+// has changed since the last Load, Save returns a *ConflictError.
+func (s *FileStore) Save(ctx context.Context, tasks []Task) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	data, err := json.MarshalIndent(tasks, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal tasks: %w", err)
 	}
-	if err := os.WriteFile(s.Path, data, 0644); err != nil {
+
+	if s.loaded {
+		current, err := fileRevision(s.Path)
+		if err != nil {
+			return err
+		}
+		if current != s.rev {
+			return &ConflictError{Loaded: s.rev, Current: current}
+		}
+	}
+
+	if err := writeFileAtomic(s.Path, data, 0644); err != nil {
 		return fmt.Errorf("write %s: %w", s.Path, err)
 	}
+
+	rev, err := fileRevision(s.Path)
+	if err != nil {
+		return err
+	}
+	s.rev = rev
 	return nil
 }
 
-// DefaultPath returns the default storage path (~/.tasks.json).
-func DefaultPath() (string, error) {
-	home, err := os.UserHomeDir()
+// Revision returns the file revision seen by the last Load or Save.
+func (s *FileStore) Revision() string {
+	return s.rev
+}
+
+// fileRevision identifies the current contents of path by modification
+// time and size. A missing file has the empty revision.
+func fileRevision(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("stat %s: %w", path, err)
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+// Update runs a load-modify-save cycle while holding an exclusive lock
+// on a sibling ".lock" file, so concurrent tsk processes cannot lose
+// each other's changes. Writers that bypass the lock (a text editor,
+// a sync tool) are still caught by the revision check in Save.
+func (s *FileStore) Update(ctx context.Context, fn func([]Task) ([]Task, error)) error {
+	return lockedUpdate(ctx, s.Path+".lock", s, fn)
+}
+
+// lockedUpdate implements Update for store: it holds an exclusive lock
+// on lockPath while it loads the tasks, passes them to fn and saves the
+// result.
+func lockedUpdate(ctx context.Context, lockPath string, store Store, fn func([]Task) ([]Task, error)) error {
+	unlock, err := lockFile(ctx, lockPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tasks, err := store.Load(ctx)
 	if err != nil {
-		return "", fmt.Errorf("user home dir: %w", err)
+		return err
 	}
-	return filepath.Join(home, ".tasks.json"), nil
+	tasks, err = fn(tasks)
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, tasks)
 }
 
 // nextID returns the next auto-incrementing ID.
@@ -91,18 +181,53 @@ func Add(tasks []Task, title string, priority Priority) []Task {
 	return append(tasks, t)
 }
 
-// Done marks the task with the given ID as done.
-// Returns an error if the ID is not found.
-func Done(tasks []Task, id int) error {
-	for i := range tasks {
-		if tasks[i].ID == id {
-			tasks[i].Done = true
-			now := time.Now()
-			tasks[i].CompletedAt = &now
-			return nil
+// Done marks the task with the given ID as done and returns the updated
+// slice. Completing a recurring task appends its next occurrence.
+// Returns an error if the ID is not found, or ErrOpenSubtasks if any of
+// its subtasks are still pending (see DoneRecursive).
+func Done(tasks []Task, id int) ([]Task, Completion, error) {
+	if Find(tasks, id) == nil {
+		return tasks, Completion{}, fmt.Errorf("task %d: not found", id)
+	}
+	if hasOpenDescendants(tasks, id) {
+		return tasks, Completion{}, fmt.Errorf("task %d: %w", id, ErrOpenSubtasks)
+	}
+	return complete(tasks, []int{id})
+}
+
+// complete marks the given tasks done, stopping any running time
+// tracking, then reports what was unblocked and creates the next
+// occurrence of any recurring ones.
+func complete(tasks []Task, ids []int) ([]Task, Completion, error) {
+	var c Completion
+	now := time.Now()
+	for _, id := range ids {
+		t := Find(tasks, id)
+		if t.Done {
+			continue
+		}
+		t.Done = true
+		t.CompletedAt = &now
+		if t.Running() {
+			t.Intervals[len(t.Intervals)-1].End = &now
 		}
+		c.Completed = append(c.Completed, id)
 	}
-	return fmt.Errorf("task %d: not found", id)
+	c.Unblocked = unblockedBy(tasks, c.Completed)
+
+	for _, id := range c.Completed {
+		t := *Find(tasks, id)
+		if t.Recur == "" {
+			continue
+		}
+		var err error
+		tasks, err = nextOccurrence(tasks, t, now)
+		if err != nil {
+			return tasks, c, fmt.Errorf("task %d: %w", id, err)
+		}
+		c.Spawned = append(c.Spawned, tasks[len(tasks)-1].ID)
+	}
+	return tasks, c, nil
 }
 
 // Find returns a pointer to the task with the given ID, or nil if not found.
@@ -127,52 +252,108 @@ func Edit(tasks []Task, id int, title string) error {
 	return fmt.Errorf("task %d: not found", id)
 }
 
-// Remove deletes the task with the given ID and returns the updated slice.
+// Remove deletes the task with the given ID, along with all of its
+// subtasks, and returns the updated slice. Dependencies on removed
+// tasks are dropped from the remaining ones.
 // Returns an error if the ID is not found.
 func Remove(tasks []Task, id int) ([]Task, error) {
-	for i, t := range tasks {
-		if t.ID == id {
-			return append(tasks[:i], tasks[i+1:]...), nil
+	if Find(tasks, id) == nil {
+		return tasks, fmt.Errorf("task %d: not found", id)
+	}
+
+	drop := map[int]bool{id: true}
+	for _, d := range Descendants(tasks, id) {
+		drop[d] = true
+	}
+
+	out := tasks[:0]
+	for _, t := range tasks {
+		if !drop[t.ID] {
+			out = append(out, t)
 		}
 	}
-	return tasks, fmt.Errorf("task %d: not found", id)
+	dropReferences(out)
+	return out, nil
 }
 
 // ClearDone removes all completed tasks and returns the count removed
-// along with the remaining tasks.
+// along with the remaining tasks. A done task that still has pending
+// subtasks is kept so the hierarchy stays intact.
 func ClearDone(tasks []Task) (removed int, remaining []Task) {
+	keep := make(map[int]bool)
+	for _, t := range tasks {
+		if t.Done {
+			continue
+		}
+		// keep pending tasks and every ancestor above them
+		for id := t.ID; id != 0 && !keep[id]; {
+			keep[id] = true
+			p := Find(tasks, id)
+			if p == nil {
+				break
+			}
+			id = p.Parent
+		}
+	}
+
 	var out []Task
 	for _, t := range tasks {
-		if !t.Done {
+		if keep[t.ID] {
 			out = append(out, t)
 		}
 	}
+	dropReferences(out)
 	return len(tasks) - len(out), out
 }
 
-// Filter controls which tasks List returns.
-type Filter int
+// Filter reports whether a task should be included by List.
+type Filter func(Task) bool
 
-const (
-	FilterAll     Filter = iota
-	FilterDone           // only completed tasks
-	FilterPending        // only incomplete tasks
-)
+// FilterAll matches every task.
+func FilterAll(Task) bool { return true }
+
+// FilterDone matches only completed tasks.
+func FilterDone(t Task) bool { return t.Done }
+
+// FilterPending matches only incomplete tasks.
+func FilterPending(t Task) bool { return !t.Done }
+
+// Overdue matches pending tasks whose due date is before the day of now.
+func Overdue(now time.Time) Filter {
+	return func(t Task) bool {
+		return !t.Done && t.Due != nil && DaysUntil(*t.Due, now) < 0
+	}
+}
 
-// List returns tasks matching the given filter.
-func List(tasks []Task, f Filter) []Task {
-	if f == FilterAll {
+// DueBefore matches tasks with a due date strictly before the given time.
+func DueBefore(before time.Time) Filter {
+	return func(t Task) bool {
+		return t.Due != nil && t.Due.Before(before)
+	}
+}
+
+// List returns tasks matching every given filter.
+// With no filters, all tasks are returned.
+func List(tasks []Task, filters ...Filter) []Task {
+	if len(filters) == 0 {
 		return tasks
 	}
 
 	var out []Task
 	for _, t := range tasks {
-		switch {
-		case f == FilterDone && t.Done:
-			out = append(out, t)
-		case f == FilterPending && !t.Done:
+		if matchAll(t, filters) {
 			out = append(out, t)
 		}
 	}
 	return out
 }
+
+// matchAll reports whether t satisfies all filters.
+func matchAll(t Task, filters []Filter) bool {
+	for _, f := range filters {
+		if f != nil && !f(t) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,11 @@
 package task
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -17,7 +20,7 @@ func tempStore(t *testing.T) *FileStore {
 
 func TestLoadNonExistent(t *testing.T) {
 	store := NewFileStore(filepath.Join(t.TempDir(), "nope.json"))
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -32,7 +35,7 @@ func TestLoadEmptyFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -51,11 +54,11 @@ func TestSaveAndLoad(t *testing.T) {
 		{ID: 2, Title: "write code", Done: true, CreatedAt: now, CompletedAt: &completed},
 	}
 
-	if err := store.Save(original); err != nil {
+	if err := store.Save(context.Background(), original); err != nil {
 		t.Fatalf("save: %v", err)
 	}
 
-	loaded, err := store.Load()
+	loaded, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
@@ -188,7 +191,7 @@ func TestDone(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := Done(tt.tasks, tt.id)
+			_, _, err := Done(tt.tasks, tt.id)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
 			}
@@ -403,11 +406,11 @@ func TestDoneCompletedAtRoundTrip(t *testing.T) {
 		{ID: 2, Title: "done", Done: true, CreatedAt: now, CompletedAt: &completed},
 	}
 
-	if err := store.Save(original); err != nil {
+	if err := store.Save(context.Background(), original); err != nil {
 		t.Fatalf("save: %v", err)
 	}
 
-	loaded, err := store.Load()
+	loaded, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
@@ -531,7 +534,7 @@ func TestRoundTrip(t *testing.T) {
 	store := tempStore(t)
 
 	// start empty
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("initial load: %v", err)
 	}
@@ -541,12 +544,12 @@ func TestRoundTrip(t *testing.T) {
 	tasks = Add(tasks, "write tests", PriorityHigh)
 	tasks = Add(tasks, "deploy", PriorityNone)
 
-	if err := store.Save(tasks); err != nil {
+	if err := store.Save(context.Background(), tasks); err != nil {
 		t.Fatalf("save after add: %v", err)
 	}
 
 	// reload and mark done
-	tasks, err = store.Load()
+	tasks, err = store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load after add: %v", err)
 	}
@@ -554,15 +557,15 @@ func TestRoundTrip(t *testing.T) {
 		t.Fatalf("expected 3 tasks, got %d", len(tasks))
 	}
 
-	if err := Done(tasks, 2); err != nil {
+	if _, _, err := Done(tasks, 2); err != nil {
 		t.Fatalf("done: %v", err)
 	}
-	if err := store.Save(tasks); err != nil {
+	if err := store.Save(context.Background(), tasks); err != nil {
 		t.Fatalf("save after done: %v", err)
 	}
 
 	// reload and remove
-	tasks, err = store.Load()
+	tasks, err = store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load after done: %v", err)
 	}
@@ -577,12 +580,12 @@ func TestRoundTrip(t *testing.T) {
 	if err != nil {
 		t.Fatalf("remove: %v", err)
 	}
-	if err := store.Save(tasks); err != nil {
+	if err := store.Save(context.Background(), tasks); err != nil {
 		t.Fatalf("save after remove: %v", err)
 	}
 
 	// final reload
-	tasks, err = store.Load()
+	tasks, err = store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("final load: %v", err)
 	}
@@ -596,6 +599,168 @@ func TestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestSaveLeavesNoTempFiles(t *testing.T) {
+	store := tempStore(t)
+	for i := range 3 {
+		if err := store.Save(context.Background(), []Task{{ID: i + 1, Title: "x"}}); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(store.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "tasks.json" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("dir contains %v, want only tasks.json", names)
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("perm = %o, want 644", perm)
+	}
+}
+
+func TestUpdateCreatesDir(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "share", "tsk", "tasks.json"))
+	err := store.Update(context.Background(), func(tasks []Task) ([]Task, error) {
+		return Add(tasks, "x", PriorityNone), nil
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := os.Stat(store.Path); err != nil {
+		t.Errorf("tasks not saved: %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	store := tempStore(t)
+	if err := store.Save(context.Background(), []Task{{ID: 1, Title: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.Update(context.Background(), func(tasks []Task) ([]Task, error) {
+		return Add(tasks, "b", PriorityNone), nil
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	failed := errors.New("boom")
+	err = store.Update(context.Background(), func(tasks []Task) ([]Task, error) {
+		Edit(tasks, 1, "changed")
+		return nil, failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("err = %v, want %v", err, failed)
+	}
+
+	tasks, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "a" || tasks[1].Title != "b" {
+		t.Errorf("tasks = %+v, want [a b] with failed update discarded", tasks)
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	const n = 20
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// separate stores, as separate tsk processes would have
+			store := NewFileStore(path)
+			err := store.Update(context.Background(), func(tasks []Task) ([]Task, error) {
+				return Add(tasks, "x", PriorityNone), nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	tasks, err := NewFileStore(path).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != n {
+		t.Errorf("got %d tasks, want %d (updates were lost)", len(tasks), n)
+	}
+}
+
+func TestUpdateCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	unlock, err := lockFile(context.Background(), path+".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = NewFileStore(path).Update(ctx, func(tasks []Task) ([]Task, error) {
+		t.Error("fn ran while the lock was held")
+		return tasks, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestSaveConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	a, b := NewFileStore(path), NewFileStore(path)
+	if err := a.Save(context.Background(), []Task{{ID: 1, Title: "a"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(context.Background(), []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "from b"}}); err != nil {
+		t.Fatalf("b save: %v", err)
+	}
+
+	err := a.Save(context.Background(), []Task{{ID: 1, Title: "stale"}})
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("err = %v, want *ConflictError", err)
+	}
+	if conflict.Loaded == conflict.Current {
+		t.Errorf("revisions should differ: %+v", conflict)
+	}
+
+	// b's own revision is current, so it can keep saving
+	if err := b.Save(context.Background(), []Task{{ID: 1, Title: "again"}}); err != nil {
+		t.Errorf("b second save: %v", err)
+	}
+
+	// reloading picks up the new revision
+	if _, err := a.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Save(context.Background(), []Task{{ID: 1, Title: "fresh"}}); err != nil {
+		t.Errorf("save after reload: %v", err)
+	}
+}
+
 func TestValidPriority(t *testing.T) {
 	tests := []struct {
 		input string
@@ -657,11 +822,11 @@ func TestPriorityJSONRoundTrip(t *testing.T) {
 		{ID: 3, Title: "low task", Priority: PriorityLow, CreatedAt: now},
 	}
 
-	if err := store.Save(original); err != nil {
+	if err := store.Save(context.Background(), original); err != nil {
 		t.Fatalf("save: %v", err)
 	}
 
-	loaded, err := store.Load()
+	loaded, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
@@ -686,7 +851,7 @@ func TestBackwardsCompatibility(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tasks, err := store.Load()
+	tasks, err := store.Load(context.Background())
 	if err != nil {
 		t.Fatalf("load: %v", err)
 	}
@@ -0,0 +1,97 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrOpenSubtasks is returned by Done when a task still has pending subtasks.
+var ErrOpenSubtasks = errors.New("has open subtasks")
+
+// SetParent makes the task with the given ID a subtask of parent.
+// A parent of 0 detaches it. Returns an error if either task is not
+// found or the change would create a cycle.
+func SetParent(tasks []Task, id, parent int) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	if parent == 0 {
+		t.Parent = 0
+		return nil
+	}
+	if Find(tasks, parent) == nil {
+		return fmt.Errorf("parent task %d: not found", parent)
+	}
+	for p := parent; p != 0; {
+		if p == id {
+			return fmt.Errorf("task %d: cannot be a subtask of %d (cycle)", id, parent)
+		}
+		pt := Find(tasks, p)
+		if pt == nil {
+			break
+		}
+		p = pt.Parent
+	}
+	t.Parent = parent
+	return nil
+}
+
+// Children returns the direct subtasks of the task with the given ID.
+func Children(tasks []Task, id int) []Task {
+	var out []Task
+	for _, t := range tasks {
+		if t.Parent == id {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Descendants returns the IDs of all subtasks below the given task,
+// depth first.
+func Descendants(tasks []Task, id int) []int {
+	var out []int
+	for _, c := range Children(tasks, id) {
+		out = append(out, c.ID)
+		out = append(out, Descendants(tasks, c.ID)...)
+	}
+	return out
+}
+
+// Progress returns how many direct subtasks of the given task are done,
+// out of the total.
+func Progress(tasks []Task, id int) (done, total int) {
+	for _, c := range Children(tasks, id) {
+		total++
+		if c.Done {
+			done++
+		}
+	}
+	return done, total
+}
+
+// hasOpenDescendants reports whether any subtask below id is pending.
+func hasOpenDescendants(tasks []Task, id int) bool {
+	for _, d := range Descendants(tasks, id) {
+		if t := Find(tasks, d); t != nil && !t.Done {
+			return true
+		}
+	}
+	return false
+}
+
+// DoneRecursive marks the task with the given ID and all of its pending
+// subtasks as done, subtasks first, and returns the updated slice.
+func DoneRecursive(tasks []Task, id int) ([]Task, Completion, error) {
+	if Find(tasks, id) == nil {
+		return tasks, Completion{}, fmt.Errorf("task %d: not found", id)
+	}
+
+	// reversed depth-first order puts every subtask before its parent
+	ids := Descendants(tasks, id)
+	slices.Reverse(ids)
+	ids = append(ids, id)
+	return complete(tasks, ids)
+}
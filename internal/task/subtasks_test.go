@@ -0,0 +1,157 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// tree builds:
+//
+//	1
+//	├── 2
+//	│   └── 4
+//	└── 3
+//	5
+func tree() []Task {
+	return []Task{
+		{ID: 1, Title: "release"},
+		{ID: 2, Title: "build", Parent: 1},
+		{ID: 3, Title: "announce", Parent: 1},
+		{ID: 4, Title: "compile", Parent: 2},
+		{ID: 5, Title: "unrelated"},
+	}
+}
+
+func TestSetParent(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      int
+		parent  int
+		wantErr bool
+	}{
+		{"attach", 5, 3, false},
+		{"detach", 2, 0, false},
+		{"self", 1, 1, true},
+		{"cycle through child", 1, 4, true},
+		{"missing parent", 5, 99, true},
+		{"missing task", 99, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := tree()
+			err := SetParent(tasks, tt.id, tt.parent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := Find(tasks, tt.id).Parent; got != tt.parent {
+				t.Errorf("Parent = %d, want %d", got, tt.parent)
+			}
+		})
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	got := Descendants(tree(), 1)
+	if want := []int{2, 4, 3}; !slices.Equal(got, want) {
+		t.Errorf("Descendants = %v, want %v", got, want)
+	}
+	if got := Descendants(tree(), 5); len(got) != 0 {
+		t.Errorf("Descendants of leaf = %v, want empty", got)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	tasks := tree()
+	tasks[2].Done = true
+
+	done, total := Progress(tasks, 1)
+	if done != 1 || total != 2 {
+		t.Errorf("Progress = %d/%d, want 1/2", done, total)
+	}
+}
+
+func TestDoneWithOpenSubtasks(t *testing.T) {
+	tasks := tree()
+
+	_, _, err := Done(tasks, 1)
+	if !errors.Is(err, ErrOpenSubtasks) {
+		t.Fatalf("err = %v, want ErrOpenSubtasks", err)
+	}
+	if tasks[0].Done {
+		t.Error("parent should not be marked done")
+	}
+
+	// leaf subtask completes normally
+	if _, _, err := Done(tasks, 4); err != nil {
+		t.Fatalf("done leaf: %v", err)
+	}
+	if _, _, err := Done(tasks, 2); err != nil {
+		t.Fatalf("done parent with finished subtasks: %v", err)
+	}
+}
+
+func TestDoneRecursive(t *testing.T) {
+	tasks := tree()
+	tasks[2].Done = true
+
+	tasks, c, err := DoneRecursive(tasks, 1)
+	if err != nil {
+		t.Fatalf("done recursive: %v", err)
+	}
+	if want := []int{4, 2, 1}; !slices.Equal(c.Completed, want) {
+		t.Errorf("completed = %v, want %v", c.Completed, want)
+	}
+	for _, id := range c.Completed {
+		if tk := Find(tasks, id); !tk.Done || tk.CompletedAt == nil {
+			t.Errorf("task %d should be done with CompletedAt set", id)
+		}
+	}
+	if tasks[4].Done {
+		t.Error("unrelated task should stay pending")
+	}
+
+	if _, _, err := DoneRecursive(tasks, 99); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
+
+func TestRemoveCascades(t *testing.T) {
+	tasks, err := Remove(tree(), 2)
+	if err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	var ids []int
+	for _, tk := range tasks {
+		ids = append(ids, tk.ID)
+	}
+	if want := []int{1, 3, 5}; !slices.Equal(ids, want) {
+		t.Errorf("remaining = %v, want %v", ids, want)
+	}
+}
+
+func TestClearDoneKeepsParentsOfPending(t *testing.T) {
+	tasks := tree()
+	// parent done but one grandchild still open
+	tasks[0].Done = true
+	tasks[1].Done = true
+	tasks[2].Done = true
+
+	removed, remaining := ClearDone(tasks)
+	if removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+
+	var ids []int
+	for _, tk := range remaining {
+		ids = append(ids, tk.ID)
+	}
+	if want := []int{1, 2, 4, 5}; !slices.Equal(ids, want) {
+		t.Errorf("remaining = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,82 @@
+package task
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ExtractTags pulls +tag tokens out of a title. It returns the title
+// with those tokens removed and the tags in order of appearance.
+func ExtractTags(title string) (string, []string) {
+	var words, tags []string
+	for _, w := range strings.Fields(title) {
+		if tag, ok := strings.CutPrefix(w, "+"); ok && tag != "" {
+			tags = addTag(tags, tag)
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), tags
+}
+
+// addTag appends tag unless it is already present.
+func addTag(tags []string, tag string) []string {
+	if slices.Contains(tags, tag) {
+		return tags
+	}
+	return append(tags, tag)
+}
+
+// Tag adds and removes tags on the task with the given ID.
+// Returns an error if the ID is not found.
+func Tag(tasks []Task, id int, add, remove []string) error {
+	t := Find(tasks, id)
+	if t == nil {
+		return fmt.Errorf("task %d: not found", id)
+	}
+	for _, tag := range add {
+		t.Tags = addTag(t.Tags, tag)
+	}
+	t.Tags = slices.DeleteFunc(t.Tags, func(tag string) bool {
+		return slices.Contains(remove, tag)
+	})
+	if len(t.Tags) == 0 {
+		t.Tags = nil
+	}
+	return nil
+}
+
+// HasTag matches tasks carrying the given tag.
+func HasTag(tag string) Filter {
+	return func(t Task) bool {
+		return slices.Contains(t.Tags, tag)
+	}
+}
+
+// And matches tasks that satisfy every filter.
+func And(filters ...Filter) Filter {
+	return func(t Task) bool {
+		return matchAll(t, filters)
+	}
+}
+
+// Or matches tasks that satisfy at least one filter.
+// With no filters, nothing matches.
+func Or(filters ...Filter) Filter {
+	return func(t Task) bool {
+		for _, f := range filters {
+			if f != nil && f(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a filter.
+func Not(f Filter) Filter {
+	return func(t Task) bool {
+		return !f(t)
+	}
+}
@@ -0,0 +1,100 @@
+package task
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractTags(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantTitle string
+		wantTags  []string
+	}{
+		{"buy milk", "buy milk", nil},
+		{"fix login +backend", "fix login", []string{"backend"}},
+		{"+oncall rotate pager +oncall +ops", "rotate pager", []string{"oncall", "ops"}},
+		{"c++ is fine", "c++ is fine", nil},
+		{"lone + sign", "lone + sign", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			title, tags := ExtractTags(tt.input)
+			if title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", title, tt.wantTitle)
+			}
+			if !slices.Equal(tags, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		add      []string
+		remove   []string
+		wantTags []string
+	}{
+		{"add to empty", nil, []string{"a", "b"}, nil, []string{"a", "b"}},
+		{"add duplicate", []string{"a"}, []string{"a"}, nil, []string{"a"}},
+		{"remove", []string{"a", "b", "c"}, nil, []string{"b"}, []string{"a", "c"}},
+		{"remove missing", []string{"a"}, nil, []string{"z"}, []string{"a"}},
+		{"remove all", []string{"a"}, nil, []string{"a"}, nil},
+		{"add and remove", []string{"a"}, []string{"b"}, []string{"a"}, []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := []Task{{ID: 1, Title: "x", Tags: tt.tags}}
+			if err := Tag(tasks, 1, tt.add, tt.remove); err != nil {
+				t.Fatalf("tag: %v", err)
+			}
+			if !slices.Equal(tasks[0].Tags, tt.wantTags) {
+				t.Errorf("tags = %v, want %v", tasks[0].Tags, tt.wantTags)
+			}
+		})
+	}
+
+	if err := Tag(nil, 1, []string{"a"}, nil); err == nil {
+		t.Error("expected error for missing task")
+	}
+}
+
+func TestListTagFilters(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "a", Tags: []string{"backend"}},
+		{ID: 2, Title: "b", Tags: []string{"backend", "oncall"}},
+		{ID: 3, Title: "c", Tags: []string{"docs"}, Done: true},
+		{ID: 4, Title: "d"},
+	}
+
+	tests := []struct {
+		name    string
+		filters []Filter
+		wantIDs []int
+	}{
+		{"has tag", []Filter{HasTag("backend")}, []int{1, 2}},
+		{"include and exclude", []Filter{HasTag("backend"), Not(HasTag("oncall"))}, []int{1}},
+		{"exclude only", []Filter{Not(HasTag("backend"))}, []int{3, 4}},
+		{"or", []Filter{Or(HasTag("oncall"), HasTag("docs"))}, []int{2, 3}},
+		{"and with status", []Filter{And(FilterPending, Not(HasTag("oncall")))}, []int{1, 4}},
+		{"empty or", []Filter{Or()}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := List(tasks, tt.filters...)
+			var ids []int
+			for _, tk := range got {
+				ids = append(ids, tk.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
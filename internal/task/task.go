@@ -37,4 +37,13 @@ type Task struct {
 	Priority    Priority   `json:"priority,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Due         *time.Time `json:"due,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	ProjectID   int        `json:"project_id,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Parent      int        `json:"parent,omitempty"`
+	DependsOn   []int      `json:"depends_on,omitempty"`
+	Recur       string     `json:"recur,omitempty"`
+	Intervals   []Interval `json:"intervals,omitempty"`
 }
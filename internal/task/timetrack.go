@@ -0,0 +1,173 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Interval is a span of time tracked against a task.
+// End is nil while the interval is still running.
+type Interval struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Running reports whether t has an open time-tracking interval.
+func (t Task) Running() bool {
+	n := len(t.Intervals)
+	return n > 0 && t.Intervals[n-1].End == nil
+}
+
+// Active returns the task currently being tracked, or nil if none is.
+func Active(tasks []Task) *Task {
+	for i := range tasks {
+		if tasks[i].Running() {
+			return &tasks[i]
+		}
+	}
+	return nil
+}
+
+// Start begins tracking time on the task with the given ID. Only one
+// task is tracked at a time, so any other running task is stopped first;
+// its ID is returned, or 0 if nothing was running.
+func Start(tasks []Task, id int, now time.Time) (stopped int, err error) {
+	t := Find(tasks, id)
+	if t == nil {
+		return 0, fmt.Errorf("task %d: not found", id)
+	}
+	if t.Done {
+		return 0, fmt.Errorf("task %d: already done", id)
+	}
+	if t.Running() {
+		return 0, fmt.Errorf("task %d: already being tracked", id)
+	}
+
+	if a := Active(tasks); a != nil {
+		stopped = a.ID
+		a.Intervals[len(a.Intervals)-1].End = &now
+	}
+	t.Intervals = append(t.Intervals, Interval{Start: now})
+	return stopped, nil
+}
+
+// Stop ends tracking on the active task and returns its ID along with
+// the length of the interval just closed.
+func Stop(tasks []Task, now time.Time) (int, time.Duration, error) {
+	a := Active(tasks)
+	if a == nil {
+		return 0, 0, fmt.Errorf("no task is being tracked")
+	}
+	last := &a.Intervals[len(a.Intervals)-1]
+	last.End = &now
+	return a.ID, now.Sub(last.Start), nil
+}
+
+// Tracked returns the total time logged against t. A running interval
+// counts up to now.
+func Tracked(t Task, now time.Time) time.Duration {
+	var total time.Duration
+	for _, iv := range t.Intervals {
+		end := now
+		if iv.End != nil {
+			end = *iv.End
+		}
+		total += end.Sub(iv.Start)
+	}
+	return total
+}
+
+// TimeEntry is the time spent on one task during one day.
+type TimeEntry struct {
+	Day      time.Time // midnight local time
+	TaskID   int
+	Title    string
+	Duration time.Duration
+}
+
+// Timesheet returns time tracked between since and now, split at
+// midnight and grouped by day and task. Entries are sorted by day, then
+// task ID. Running intervals count up to now.
+func Timesheet(tasks []Task, since, now time.Time) []TimeEntry {
+	type key struct {
+		day time.Time
+		id  int
+	}
+	totals := make(map[key]time.Duration)
+	titles := make(map[int]string)
+
+	for _, t := range tasks {
+		for _, iv := range t.Intervals {
+			start, end := iv.Start, now
+			if iv.End != nil {
+				end = *iv.End
+			}
+			if start.Before(since) {
+				start = since
+			}
+			for start.Before(end) {
+				day := startOfDay(start)
+				next := day.AddDate(0, 0, 1)
+				stop := end
+				if next.Before(stop) {
+					stop = next
+				}
+				totals[key{day, t.ID}] += stop.Sub(start)
+				titles[t.ID] = t.Title
+				start = stop
+			}
+		}
+	}
+
+	out := make([]TimeEntry, 0, len(totals))
+	for k, d := range totals {
+		out = append(out, TimeEntry{Day: k.day, TaskID: k.id, Title: titles[k.id], Duration: d})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Day.Equal(out[j].Day) {
+			return out[i].Day.Before(out[j].Day)
+		}
+		return out[i].TaskID < out[j].TaskID
+	})
+	return out
+}
+
+// ParseSince parses the start of a reporting period relative to now.
+// Accepted forms are YYYY-MM-DD, "today", "yesterday", a weekday name
+// (the most recent one, including today), or an offset like "7d" / "2w"
+// back from today. The result is midnight local time.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := startOfDay(now)
+
+	switch s {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+
+	if wd, ok := parseWeekday(s); ok {
+		diff := (int(today.Weekday()) - int(wd) + 7) % 7
+		return today.AddDate(0, 0, -diff), nil
+	}
+
+	if n, unit, ok := parseOffset(s); ok {
+		switch unit {
+		case 'd':
+			return today.AddDate(0, 0, -n), nil
+		case 'w':
+			return today.AddDate(0, 0, -7*n), nil
+		case 'm':
+			return today.AddDate(0, -n, 0), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date: %s (use YYYY-MM-DD, today, yesterday, a weekday or 7d/2w/1m)", s)
+}
@@ -0,0 +1,155 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartStop(t *testing.T) {
+	t0 := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "a"}, {ID: 2, Title: "b"}, {ID: 3, Title: "c", Done: true}}
+
+	stopped, err := Start(tasks, 1, t0)
+	if err != nil {
+		t.Fatalf("start 1: %v", err)
+	}
+	if stopped != 0 {
+		t.Errorf("stopped = %d, want 0", stopped)
+	}
+	if a := Active(tasks); a == nil || a.ID != 1 {
+		t.Fatalf("active = %v, want task 1", a)
+	}
+
+	if _, err := Start(tasks, 1, t0); err == nil {
+		t.Error("expected error starting an already running task")
+	}
+	if _, err := Start(tasks, 3, t0); err == nil {
+		t.Error("expected error starting a done task")
+	}
+	if _, err := Start(tasks, 99, t0); err == nil {
+		t.Error("expected error for missing task")
+	}
+
+	// switching tasks stops the previous one
+	stopped, err = Start(tasks, 2, t0.Add(30*time.Minute))
+	if err != nil {
+		t.Fatalf("start 2: %v", err)
+	}
+	if stopped != 1 {
+		t.Errorf("stopped = %d, want 1", stopped)
+	}
+	if got := Tracked(tasks[0], t0.Add(5*time.Hour)); got != 30*time.Minute {
+		t.Errorf("tracked on 1 = %v, want 30m", got)
+	}
+
+	id, d, err := Stop(tasks, t0.Add(45*time.Minute))
+	if err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if id != 2 || d != 15*time.Minute {
+		t.Errorf("Stop = (%d, %v), want (2, 15m)", id, d)
+	}
+	if Active(tasks) != nil {
+		t.Error("no task should be active after stop")
+	}
+
+	if _, _, err := Stop(tasks, t0); err == nil {
+		t.Error("expected error when nothing is running")
+	}
+}
+
+func TestTrackedRunning(t *testing.T) {
+	t0 := time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)
+	end := t0.Add(time.Hour)
+	tk := Task{ID: 1, Intervals: []Interval{
+		{Start: t0, End: &end},
+		{Start: t0.Add(2 * time.Hour)},
+	}}
+
+	if got := Tracked(tk, t0.Add(150*time.Minute)); got != 90*time.Minute {
+		t.Errorf("Tracked = %v, want 1h30m", got)
+	}
+}
+
+func TestDoneStopsTracking(t *testing.T) {
+	tasks := []Task{{ID: 1, Title: "a"}}
+	if _, err := Start(tasks, 1, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	tasks, _, err := Done(tasks, 1)
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if Active(tasks) != nil {
+		t.Error("completing a task should stop its tracking")
+	}
+}
+
+func TestTimesheet(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 3, day, hour, min, 0, 0, time.UTC)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	tasks := []Task{
+		{ID: 1, Title: "a", Intervals: []Interval{
+			{Start: at(9, 10, 0), End: ptr(at(9, 11, 0))}, // before since
+			{Start: at(10, 9, 0), End: ptr(at(10, 10, 30))},
+			{Start: at(10, 23, 0), End: ptr(at(11, 1, 0))}, // crosses midnight
+		}},
+		{ID: 2, Title: "b", Intervals: []Interval{
+			{Start: at(10, 14, 0), End: ptr(at(10, 14, 45))},
+			{Start: at(11, 8, 0)}, // running
+		}},
+	}
+
+	got := Timesheet(tasks, at(10, 0, 0), at(11, 9, 0))
+	want := []TimeEntry{
+		{Day: at(10, 0, 0), TaskID: 1, Title: "a", Duration: 2*time.Hour + 30*time.Minute},
+		{Day: at(10, 0, 0), TaskID: 2, Title: "b", Duration: 45 * time.Minute},
+		{Day: at(11, 0, 0), TaskID: 1, Title: "a", Duration: time.Hour},
+		{Day: at(11, 0, 0), TaskID: 2, Title: "b", Duration: time.Hour},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Day.Equal(want[i].Day) || got[i].TaskID != want[i].TaskID || got[i].Duration != want[i].Duration {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	// Wednesday
+	now := time.Date(2026, 3, 11, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"today", date(2026, 3, 11), false},
+		{"yesterday", date(2026, 3, 10), false},
+		{"monday", date(2026, 3, 9), false},
+		{"wednesday", date(2026, 3, 11), false},
+		{"thu", date(2026, 3, 5), false},
+		{"7d", date(2026, 3, 4), false},
+		{"2026-03-01", date(2026, 3, 1), false},
+		{"later", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSince(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if err == nil && !got.Equal(tt.want) {
+				t.Errorf("ParseSince(%q) = %s, want %s", tt.input, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}